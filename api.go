@@ -1,22 +1,43 @@
 package deltadefi
 
 import (
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/sidan-lab/rum"
 	"github.com/sidan-lab/rum/wallet"
+	"golang.org/x/crypto/blake2b"
 )
 
+// ErrOrderNotFound is returned when the API confirms that an order does not exist.
+var ErrOrderNotFound = errors.New("order not found")
+
 // LoadOperationKey loads and decrypts the operation key required for transaction signing.
 // This method must be called before performing any transaction operations like placing orders.
+// It is safe to call while other goroutines place or cancel orders; the new wallet is
+// installed atomically and only used by operations started afterwards.
+// When a Keystore is configured, the fetched key is cached locally and the cached copy is used
+// if the API cannot be reached.
 //
 // Parameters:
-//   - passcode: The operation passcode for decrypting the key
+//   - passcode: The operation passcode for decrypting the key. When empty, the passcode from
+//     ApiConfig (OperationPasscode or OperationPasscodeProvider) is used.
 //
 // Returns:
 //   - error: nil on success, error on failure
 func (d *DeltaDeFi) LoadOperationKey(passcode string) error {
-	res, err := d.Accounts.GetOperationKey()
+	if passcode == "" {
+		var err error
+		passcode, err = d.client.operationPasscode()
+		if err != nil {
+			return err
+		}
+	}
+
+	res, cached, err := d.fetchOperationKey()
 	if err != nil {
 		return err
 	}
@@ -32,10 +53,88 @@ func (d *DeltaDeFi) LoadOperationKey(passcode string) error {
 		return err
 	}
 
-	d.OperationWallet = operationWallet
+	// A cached entry is only bound to its hash by the keystore itself; check the key really matches it
+	if cached {
+		hash, err := operationKeyHash(operationWallet)
+		if err != nil {
+			return err
+		}
+		if !strings.EqualFold(hash, res.OperationKeyHash) {
+			return fmt.Errorf("cached operation key does not match its hash %s", res.OperationKeyHash)
+		}
+	}
+
+	d.setOperationWallet(operationWallet)
 	return nil
 }
 
+// fetchOperationKey retrieves the encrypted operation key from the API and keeps the keystore in sync.
+// If the API request fails and a keystore is configured, the cached key is returned instead and
+// cached is true.
+func (d *DeltaDeFi) fetchOperationKey() (res *GetOperationKeyResponse, cached bool, err error) {
+	res, err = d.Accounts.GetOperationKey()
+	if err == nil && (res.EncryptedOperationKey == "" || res.OperationKeyHash == "") {
+		err = fmt.Errorf("empty operation key response")
+	}
+
+	if d.keystore == nil {
+		return res, false, err
+	}
+
+	if err != nil {
+		entry, cacheErr := d.keystore.Load(d.keystoreID)
+		if cacheErr != nil {
+			return nil, false, fmt.Errorf("fetching operation key: %w (keystore: %v)", err, cacheErr)
+		}
+		return entry, true, nil
+	}
+
+	// Refresh the cached entry when it is missing or its hash no longer matches the server
+	cachedHash, hashErr := d.keystore.OperationKeyHash(d.keystoreID)
+	if hashErr != nil || cachedHash != res.OperationKeyHash {
+		if err := d.keystore.Save(d.keystoreID, res); err != nil {
+			return nil, false, fmt.Errorf("caching operation key: %w", err)
+		}
+	}
+	return res, false, nil
+}
+
+// operationKeyHash returns the Cardano key hash of a wallet: the hex-encoded blake2b-224 digest
+// of its public key, which is what the API reports as the operation key hash.
+func operationKeyHash(w *wallet.Wallet) (string, error) {
+	publicKeyHex, err := w.Signer().GetPublicKey()
+	if err != nil {
+		return "", err
+	}
+	publicKey, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key: %w", err)
+	}
+	hash, err := blake2b.New(28, nil)
+	if err != nil {
+		return "", err
+	}
+	hash.Write(publicKey)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SignTransaction signs a transaction built by one of the low-level Build* methods with the operation wallet.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - txHex: The unsigned transaction hex
+//
+// Returns:
+//   - string: The signed transaction hex
+//   - error: nil on success, error on failure
+func (d *DeltaDeFi) SignTransaction(txHex string) (string, error) {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
+		return "", fmt.Errorf("operation wallet is not loaded")
+	}
+	return d.signTransaction(operationWallet, txHex)
+}
+
 // PostOrder is a high-level method for placing an order.
 // It handles the complete order flow: building the transaction, signing it, and submitting it.
 // The operation wallet must be loaded before calling this method.
@@ -47,17 +146,52 @@ func (d *DeltaDeFi) LoadOperationKey(passcode string) error {
 //   - *SubmitPlaceOrderTransactionResponse: Order details and transaction info
 //   - error: nil on success, error on failure
 func (d *DeltaDeFi) PostOrder(data *BuildPlaceOrderTransactionRequest) (*SubmitPlaceOrderTransactionResponse, error) {
-	if d.OperationWallet == nil {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
 		return nil, fmt.Errorf("operation wallet is not loaded")
 	}
+	return d.placeOrder(operationWallet, data)
+}
+
+// placeOrder builds, signs and submits an order with the given operation wallet.
+func (d *DeltaDeFi) placeOrder(operationWallet *wallet.Wallet, data *BuildPlaceOrderTransactionRequest) (*SubmitPlaceOrderTransactionResponse, error) {
+	return d.placeOrderWithHook(operationWallet, data, nil)
+}
+
+// placeOrderWithHook places an order like placeOrder and calls built, if non-nil, with the prepared
+// request and the server OrderID once the order is built and before it is signed.
+// An error from built aborts the placement.
+func (d *DeltaDeFi) placeOrderWithHook(operationWallet *wallet.Wallet, data *BuildPlaceOrderTransactionRequest, built func(prepared *BuildPlaceOrderTransactionRequest, orderID string) error) (*SubmitPlaceOrderTransactionResponse, error) {
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunPostOrder(data))
+	}
+	if err := d.checkOrdersAllowed(); err != nil {
+		return nil, err
+	}
+	data, err := d.prepareOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	reservation, err := d.risk.reserve(data)
+	if err != nil {
+		return nil, err
+	}
+	defer reservation.release()
 
 	buildRes, err := d.Order.BuildPlaceOrderTransaction(data)
 	if err != nil {
 		return nil, err
 	}
+	if buildRes.OrderID == "" {
+		return nil, fmt.Errorf("order build returned no order ID")
+	}
+	if built != nil {
+		if err := built(data, buildRes.OrderID); err != nil {
+			return nil, err
+		}
+	}
 
-	fmt.Println("Built order, tx hex:", buildRes.TxHex)
-	signedTx, err := d.OperationWallet.Signer().SignTransaction(buildRes.TxHex)
+	signedTx, err := d.signTransaction(operationWallet, buildRes.TxHex)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +203,11 @@ func (d *DeltaDeFi) PostOrder(data *BuildPlaceOrderTransactionRequest) (*SubmitP
 	if err != nil {
 		return nil, err
 	}
+	// The submitted order is the built one, even if the response leaves its ID out
+	if submitRes.Order.OrderID == "" {
+		submitRes.Order.OrderID = buildRes.OrderID
+	}
+	reservation.commit()
 	return submitRes, nil
 }
 
@@ -83,16 +222,25 @@ func (d *DeltaDeFi) PostOrder(data *BuildPlaceOrderTransactionRequest) (*SubmitP
 //   - *SubmitCancelOrderTransactionResponse: Transaction hash of the cancellation
 //   - error: nil on success, error on failure
 func (d *DeltaDeFi) CancelOrder(orderId string) (*SubmitCancelOrderTransactionResponse, error) {
-	if d.OperationWallet == nil {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
 		return nil, fmt.Errorf("operation wallet is not loaded")
 	}
+	return d.cancelOrder(operationWallet, orderId)
+}
+
+// cancelOrder builds, signs and submits an order cancellation with the given operation wallet.
+func (d *DeltaDeFi) cancelOrder(operationWallet *wallet.Wallet, orderId string) (*SubmitCancelOrderTransactionResponse, error) {
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunCancelOrder(orderId))
+	}
 
 	buildRes, err := d.Order.BuildCancelOrderTransaction(orderId)
 	if err != nil {
 		return nil, err
 	}
 
-	signedTx, err := d.OperationWallet.Signer().SignTransaction(buildRes.TxHex)
+	signedTx, err := d.signTransaction(operationWallet, buildRes.TxHex)
 	if err != nil {
 		return nil, err
 	}
@@ -114,9 +262,13 @@ func (d *DeltaDeFi) CancelOrder(orderId string) (*SubmitCancelOrderTransactionRe
 //   - *SubmitCancelAllOrdersTransactionResponse: Details of all canceled orders
 //   - error: nil on success, error on failure
 func (d *DeltaDeFi) CancelAllOrders() (*SubmitCancelAllOrdersTransactionResponse, error) {
-	if d.OperationWallet == nil {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
 		return nil, fmt.Errorf("operation wallet is not loaded")
 	}
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunCancelAllOrders())
+	}
 
 	buildRes, err := d.Order.BuildCancelAllOrdersTransaction()
 	if err != nil {
@@ -125,7 +277,7 @@ func (d *DeltaDeFi) CancelAllOrders() (*SubmitCancelAllOrdersTransactionResponse
 
 	signedTxs := make([]string, 0, len(buildRes.TxHexes))
 	for _, txHex := range buildRes.TxHexes {
-		signedTx, err := d.OperationWallet.Signer().SignTransaction(txHex)
+		signedTx, err := d.signTransaction(operationWallet, txHex)
 		if err != nil {
 			return nil, err
 		}
@@ -141,3 +293,48 @@ func (d *DeltaDeFi) CancelAllOrders() (*SubmitCancelAllOrdersTransactionResponse
 	}
 	return submitRes, nil
 }
+
+// fetchOrder retrieves the current state of an order and rejects empty responses.
+// The error wraps ErrOrderNotFound only when the API answers 404 or returns an empty order;
+// any other failure leaves the existence of the order unknown.
+func (d *DeltaDeFi) fetchOrder(orderId string) (*OrderJSON, error) {
+	res, err := d.Accounts.GetOrderRecord(orderId)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("order %s: %w", orderId, ErrOrderNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.OrderJSON.OrderID == "" {
+		return nil, fmt.Errorf("order %s: %w", orderId, ErrOrderNotFound)
+	}
+	return &res.OrderJSON, nil
+}
+
+// openOrdersPageLimit is the page size used when fetching all open orders.
+const openOrdersPageLimit = 250
+
+// fetchOpenOrders retrieves all open orders, following pagination.
+// An empty symbol returns the open orders of every symbol.
+func (d *DeltaDeFi) fetchOpenOrders(symbol Symbol) ([]OrderJSON, error) {
+	var orders []OrderJSON
+	for page := 1; ; page++ {
+		res, err := d.Accounts.GetOrderRecords(&GetOrderRecordRequest{
+			Status: OrderRecordStatusOpenOrder,
+			Limit:  openOrdersPageLimit,
+			Page:   page,
+			Symbol: symbol,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, data := range res.Data {
+			orders = append(orders, data.Orders...)
+		}
+		if page >= res.TotalPage {
+			return orders, nil
+		}
+	}
+}
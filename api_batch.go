@@ -0,0 +1,161 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	rum "github.com/sidan-lab/rum/wallet"
+)
+
+// DefaultBatchConcurrency is the number of orders processed in parallel when no concurrency is configured.
+const DefaultBatchConcurrency = 4
+
+// ErrBatchAborted is reported for orders that were not placed because an all-or-nothing batch failed.
+var ErrBatchAborted = errors.New("batch aborted")
+
+// PostOrdersOptions configures batch order placement.
+type PostOrdersOptions struct {
+	// Concurrency bounds how many orders are built, signed and submitted at the same time (defaults to DefaultBatchConcurrency)
+	Concurrency int
+	// AllOrNothing cancels the orders already placed if any order of the batch fails. The
+	// cancellation cannot undo fills that happened before it.
+	AllOrNothing bool
+}
+
+// PostOrderResult is the outcome of a single order in a batch.
+type PostOrderResult struct {
+	// Request is the order request from the input
+	Request *BuildPlaceOrderTransactionRequest
+	// Response is the submission result, nil if the order was not placed
+	Response *SubmitPlaceOrderTransactionResponse
+	// Err is the error that prevented the order from being placed
+	Err error
+	// RolledBack reports whether the placed order was cancelled because the all-or-nothing batch failed
+	RolledBack bool
+	// RollbackErr is the error of the rollback cancellation, if it failed
+	RollbackErr error
+}
+
+// PostOrders places several orders, building, signing and submitting them with bounded concurrency.
+// The operation wallet must be loaded before calling this method.
+// With AllOrNothing, a failure stops the orders not yet started and cancels the placed ones. The
+// rollback only removes what still rests on the book: fills that happened before the cancellation
+// cannot be undone, so check ExecutedQty of the rolled back orders.
+//
+// Parameters:
+//   - data: The orders to place; nil entries are rejected before anything is placed
+//   - opts: Concurrency and all-or-nothing settings (optional)
+//
+// Returns:
+//   - []PostOrderResult: One result per input order, in input order
+//   - error: nil if every order was placed, otherwise an error summarizing the failures
+func (d *DeltaDeFi) PostOrders(data []*BuildPlaceOrderTransactionRequest, opts *PostOrdersOptions) ([]PostOrderResult, error) {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
+		return nil, fmt.Errorf("operation wallet is not loaded")
+	}
+	for i, request := range data {
+		if request == nil {
+			return nil, fmt.Errorf("order %d of the batch is nil", i)
+		}
+	}
+
+	concurrency := DefaultBatchConcurrency
+	allOrNothing := false
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		allOrNothing = opts.AllOrNothing
+	}
+
+	results := make([]PostOrderResult, len(data))
+	for i, request := range data {
+		results[i].Request = request
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		aborted bool
+	)
+	sem := make(chan struct{}, concurrency)
+
+	for i := range data {
+		sem <- struct{}{}
+
+		mu.Lock()
+		stop := aborted
+		mu.Unlock()
+		if stop {
+			<-sem
+			results[i].Err = ErrBatchAborted
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// placeOrder always returns the ID of a submitted order, so every placed order can be rolled back
+			res, err := d.placeOrder(operationWallet, data[i])
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				results[i].Err = err
+				if allOrNothing {
+					aborted = true
+				}
+				return
+			}
+			results[i].Response = res
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return results, nil
+	}
+
+	if allOrNothing {
+		d.rollbackOrders(operationWallet, results, concurrency)
+		return results, fmt.Errorf("%d of %d orders failed, batch rolled back", failed, len(results))
+	}
+	return results, fmt.Errorf("%d of %d orders failed", failed, len(results))
+}
+
+// rollbackOrders cancels every placed order of the batch.
+func (d *DeltaDeFi) rollbackOrders(operationWallet *rum.Wallet, results []PostOrderResult, concurrency int) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for i := range results {
+		if results[i].Response == nil {
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(result *PostOrderResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			_, err := d.cancelOrder(operationWallet, result.Response.Order.OrderID)
+			if err != nil {
+				result.RollbackErr = err
+				return
+			}
+			result.RolledBack = true
+		}(&results[i])
+	}
+	wg.Wait()
+}
@@ -0,0 +1,173 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRequests returns n limit orders with quantities 1 to n.
+func batchRequests(n int) []*BuildPlaceOrderTransactionRequest {
+	requests := make([]*BuildPlaceOrderTransactionRequest, n)
+	for i := range requests {
+		requests[i] = &BuildPlaceOrderTransactionRequest{
+			Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: float64(i + 1), Price: FloatPtr(0.4),
+		}
+	}
+	return requests
+}
+
+func TestPostOrders(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *PostOrdersOptions
+		// fail lists the quantities whose build is rejected
+		fail            map[float64]bool
+		hideSubmittedID bool
+		wantErr         bool
+		wantPlaced      []bool
+		wantRolledBack  []bool
+		wantAborted     []bool
+	}{
+		{
+			name:       "all placed",
+			wantPlaced: []bool{true, true, true, true},
+		},
+		{
+			name:       "partial failure keeps the other orders",
+			fail:       map[float64]bool{2: true},
+			wantErr:    true,
+			wantPlaced: []bool{true, false, true, true},
+		},
+		{
+			name:           "all or nothing rolls back and aborts",
+			opts:           &PostOrdersOptions{Concurrency: 1, AllOrNothing: true},
+			fail:           map[float64]bool{2: true},
+			wantErr:        true,
+			wantPlaced:     []bool{true, false, false, false},
+			wantRolledBack: []bool{true, false, false, false},
+			wantAborted:    []bool{false, false, true, true},
+		},
+		{
+			name:            "orders submitted without an ID in the response are rolled back",
+			opts:            &PostOrdersOptions{Concurrency: 1, AllOrNothing: true},
+			fail:            map[float64]bool{2: true},
+			hideSubmittedID: true,
+			wantErr:         true,
+			wantPlaced:      []bool{true, false, false, false},
+			wantRolledBack:  []bool{true, false, false, false},
+			wantAborted:     []bool{false, false, true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.hideSubmittedID = tt.hideSubmittedID
+			exchange.beforePlace = func(req *BuildPlaceOrderTransactionRequest) error {
+				if tt.fail[req.Quantity] {
+					return errors.New("rejected")
+				}
+				return nil
+			}
+			requests := batchRequests(len(tt.wantPlaced))
+
+			results, err := d.PostOrders(requests, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PostOrders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(results) != len(requests) {
+				t.Fatalf("got %d results, want %d", len(results), len(requests))
+			}
+			for i, result := range results {
+				if result.Request != requests[i] {
+					t.Errorf("result %d is for another request", i)
+				}
+				placed := result.Response != nil
+				if placed != tt.wantPlaced[i] {
+					t.Errorf("result %d placed = %v, want %v (err %v)", i, placed, tt.wantPlaced[i], result.Err)
+					continue
+				}
+				if placed {
+					order := exchange.order(result.Response.Order.OrderID)
+					if order.OrigQty != strconv.Itoa(i+1) {
+						t.Errorf("result %d is order %s of quantity %s", i, result.Response.Order.OrderID, order.OrigQty)
+					}
+				}
+				rolledBack := tt.wantRolledBack != nil && tt.wantRolledBack[i]
+				if result.RolledBack != rolledBack || result.RollbackErr != nil {
+					t.Errorf("result %d RolledBack = %v (%v), want %v", i, result.RolledBack, result.RollbackErr, rolledBack)
+				}
+				if rolledBack && exchange.order(result.Response.Order.OrderID).Status != "cancelled" {
+					t.Errorf("result %d was not cancelled on the exchange", i)
+				}
+				aborted := tt.wantAborted != nil && tt.wantAborted[i]
+				if errors.Is(result.Err, ErrBatchAborted) != aborted {
+					t.Errorf("result %d Err = %v, want aborted %v", i, result.Err, aborted)
+				}
+			}
+		})
+	}
+}
+
+func TestPostOrdersRejectsNilEntries(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	requests := batchRequests(3)
+	requests[2] = nil
+
+	if _, err := d.PostOrders(requests, nil); err == nil {
+		t.Fatal("PostOrders() accepted a nil order")
+	}
+	if n := exchange.count(http.MethodPost, "/order/build"); n != 0 {
+		t.Errorf("built %d orders before rejecting the batch", n)
+	}
+}
+
+// concurrencyTransport records the largest number of order builds in flight at once.
+type concurrencyTransport struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (c *concurrencyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !strings.HasSuffix(req.URL.Path, "/order/build") {
+		return c.next.RoundTrip(req)
+	}
+	c.mu.Lock()
+	c.inFlight++
+	if c.inFlight > c.max {
+		c.max = c.inFlight
+	}
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	return c.next.RoundTrip(req)
+}
+
+func TestPostOrdersBoundsConcurrency(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run(strconv.Itoa(concurrency), func(t *testing.T) {
+			d, _ := newMockClient(t, ApiConfig{})
+			transport := &concurrencyTransport{next: http.DefaultTransport}
+			d.client.HTTPClient.Transport = transport
+
+			if _, err := d.PostOrders(batchRequests(9), &PostOrdersOptions{Concurrency: concurrency}); err != nil {
+				t.Fatal(err)
+			}
+			if transport.max != concurrency {
+				t.Errorf("%d builds in flight, want %d", transport.max, concurrency)
+			}
+		})
+	}
+}
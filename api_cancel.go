@@ -0,0 +1,138 @@
+package deltadefi
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CancelOrdersOptions configures selective cancellation.
+type CancelOrdersOptions struct {
+	// Concurrency bounds how many cancellations are built, signed and submitted at the same time (defaults to DefaultBatchConcurrency)
+	Concurrency int
+	// All must be set to cancel every open order with a nil or empty filter
+	All bool
+}
+
+// CancelOrderResult is the outcome of a single cancellation in CancelOrders.
+type CancelOrderResult struct {
+	// Order is the open order selected by the filter
+	Order OrderJSON
+	// ClientOrderID is the client order ID the order was placed with, if any
+	ClientOrderID string
+	// Response is the cancellation result, nil if the cancellation failed
+	Response *SubmitCancelOrderTransactionResponse
+	// Err is the error that prevented the cancellation
+	Err error
+}
+
+// CancelOrders cancels the open orders matching the filter, across all symbols unless the filter
+// names one. Cancellations are built, signed and submitted with bounded concurrency, so one
+// strategy can clear its own orders without touching those of others on the same account.
+// Client tags are resolved through the client order store. A nil or empty filter selects every
+// open order and is rejected unless opts.All is set.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - filter: The selection criteria
+//   - opts: Concurrency settings and the opt-in to cancel every order (optional)
+//
+// Returns:
+//   - []CancelOrderResult: One result per selected order, sorted by creation time
+//   - error: nil if every selected order was cancelled, otherwise an error summarizing the failures
+func (d *DeltaDeFi) CancelOrders(filter *OrderFilter, opts *CancelOrdersOptions) ([]CancelOrderResult, error) {
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
+		return nil, fmt.Errorf("operation wallet is not loaded")
+	}
+	if filter.isEmpty() && (opts == nil || !opts.All) {
+		return nil, fmt.Errorf("empty filter selects every open order; set CancelOrdersOptions.All to cancel them all")
+	}
+
+	concurrency := DefaultBatchConcurrency
+	if opts != nil && opts.Concurrency > 0 {
+		concurrency = opts.Concurrency
+	}
+
+	var symbol Symbol
+	if filter != nil {
+		symbol = filter.Symbol
+	}
+	open, err := d.fetchOpenOrders(symbol)
+	if err != nil {
+		return nil, err
+	}
+	clientOrderIDs, err := d.ClientOrderIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var results []CancelOrderResult
+	for _, order := range open {
+		if filter.matches(&order, clientOrderIDs, now) {
+			results = append(results, CancelOrderResult{
+				Order:         order,
+				ClientOrderID: clientOrderIDs[order.OrderID],
+			})
+		}
+	}
+	sortCancelOrderResults(results)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i := range results {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(result *CancelOrderResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result.Response, result.Err = d.cancelOrder(operationWallet, result.Order.OrderID)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d cancellations failed", failed, len(results))
+	}
+	return results, nil
+}
+
+// ClientOrderIDs returns the recorded client order IDs keyed by server order ID, for use with
+// OrderFilter.Matches.
+//
+// Returns:
+//   - map[string]string: Client order IDs keyed by server order ID
+//   - error: nil on success, error if the client order store cannot be read
+func (d *DeltaDeFi) ClientOrderIDs() (map[string]string, error) {
+	records, err := d.clientOrders.List()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(records))
+	for _, record := range records {
+		if record.OrderID != "" {
+			ids[record.OrderID] = record.ClientOrderID
+		}
+	}
+	return ids, nil
+}
+
+// sortCancelOrderResults orders results by creation time, then order ID.
+func sortCancelOrderResults(results []CancelOrderResult) {
+	sort.Slice(results, func(i, j int) bool {
+		a, b := &results[i].Order, &results[j].Order
+		if a.CreatedTime != b.CreatedTime {
+			return a.CreatedTime < b.CreatedTime
+		}
+		return a.OrderID < b.OrderID
+	})
+}
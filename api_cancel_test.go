@@ -0,0 +1,78 @@
+package deltadefi
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestCancelOrders(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  *OrderFilter
+		opts    *CancelOrdersOptions
+		want    []string
+		wantErr bool
+	}{
+		{name: "nil filter rejected", wantErr: true},
+		{name: "empty filter rejected", filter: &OrderFilter{}, opts: &CancelOrdersOptions{Concurrency: 2}, wantErr: true},
+		{name: "all opted in", opts: &CancelOrdersOptions{All: true}, want: []string{"a", "b", "c"}},
+		{name: "side", filter: &OrderFilter{Side: OrderSideSell}, want: []string{"c"}},
+		{name: "client tag", filter: &OrderFilter{ClientTag: "grid-"}, want: []string{"b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.addOrder(OrderJSON{OrderID: "a", Status: "open", Symbol: ADAUSDM, Side: OrderSideBuy, OrigQty: "10", ExecutedQty: "0"})
+			exchange.addOrder(OrderJSON{OrderID: "b", Status: "open", Symbol: ADAUSDM, Side: OrderSideBuy, OrigQty: "10", ExecutedQty: "0"})
+			exchange.addOrder(OrderJSON{OrderID: "c", Status: "open", Symbol: ADAUSDM, Side: OrderSideSell, OrigQty: "10", ExecutedQty: "0"})
+			if err := d.clientOrders.Put(&ClientOrderRecord{ClientOrderID: "grid-1", OrderID: "b"}); err != nil {
+				t.Fatal(err)
+			}
+
+			results, err := d.CancelOrders(tt.filter, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CancelOrders() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var cancelled []string
+			for _, id := range []string{"a", "b", "c"} {
+				if exchange.order(id).Status == "cancelled" {
+					cancelled = append(cancelled, id)
+				}
+			}
+			if len(cancelled) != len(tt.want) || len(results) != len(tt.want) {
+				t.Fatalf("cancelled %v with %d results, want %v", cancelled, len(results), tt.want)
+			}
+			sort.Strings(cancelled)
+			for i := range cancelled {
+				if cancelled[i] != tt.want[i] {
+					t.Errorf("cancelled %v, want %v", cancelled, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderFilterMatches(t *testing.T) {
+	order := OrderJSON{OrderID: "b", Symbol: ADAUSDM, Side: OrderSideBuy, Price: 0.5}
+	clientOrderIDs := map[string]string{"b": "grid-1"}
+	tests := []struct {
+		name           string
+		filter         *OrderFilter
+		clientOrderIDs map[string]string
+		want           bool
+	}{
+		{name: "nil filter", want: true},
+		{name: "price range", filter: &OrderFilter{MinPrice: FloatPtr(0.4), MaxPrice: FloatPtr(0.5)}, want: true},
+		{name: "other side", filter: &OrderFilter{Side: OrderSideSell}},
+		{name: "client tag", filter: &OrderFilter{ClientTag: "grid-"}, clientOrderIDs: clientOrderIDs, want: true},
+		{name: "other client tag", filter: &OrderFilter{ClientTag: "dca-"}, clientOrderIDs: clientOrderIDs},
+		{name: "client tag without IDs", filter: &OrderFilter{ClientTag: "grid-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(&order, tt.clientOrderIDs); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
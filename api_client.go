@@ -9,6 +9,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	rum "github.com/sidan-lab/rum/wallet"
@@ -16,6 +18,11 @@ import (
 
 // DeltaDeFi is the main client for interacting with the DeltaDeFi API.
 // It provides access to all API endpoints through specialized client instances.
+//
+// A DeltaDeFi client is safe for concurrent use by multiple goroutines. Wallets are
+// accessed through GetOperationWallet/GetMasterWallet and replaced atomically; in-flight
+// operations keep signing with the wallet they started with. The API key can be swapped
+// with SetApiKey while requests are running; each request uses either the old or the new key.
 type DeltaDeFi struct {
 	// Accounts provides access to account management operations
 	Accounts *AccountsClient
@@ -23,12 +30,40 @@ type DeltaDeFi struct {
 	Market *MarketClient
 	// Order provides access to order management operations
 	Order *OrderClient
-	// MasterWallet holds the master wallet instance
+	// MasterWallet holds the master wallet instance (guarded by walletMu).
+	//
+	// Deprecated: use GetMasterWallet and SetMasterWallet. Accessing the field directly is not
+	// safe while other goroutines use the client; it will be unexported in the next release.
 	MasterWallet *rum.Wallet
-	// OperationWallet holds the operation wallet instance for transaction signing
+	// OperationWallet holds the operation wallet instance for transaction signing (guarded by walletMu).
+	//
+	// Deprecated: use GetOperationWallet and LoadOperationKey. Accessing the field directly is not
+	// safe while other goroutines use the client; it will be unexported in the next release.
 	OperationWallet *rum.Wallet
+	// walletMu guards MasterWallet and OperationWallet
+	walletMu sync.RWMutex
+	// signMu serializes calls into the native transaction signer
+	signMu sync.Mutex
+	// rotateMu serializes API key rotations
+	rotateMu sync.Mutex
 	// client is the underlying HTTP client
 	client *Client
+	// keystore caches the encrypted operation key (optional)
+	keystore *Keystore
+	// keystoreID is the keystore entry of this account
+	keystoreID string
+	// clientOrders records client order ID mappings
+	clientOrders ClientOrderStore
+	// clientOrderLocks serializes placement of orders with the same client order ID
+	clientOrderLocks clientOrderLocks
+	// deadMansSwitch is the active dead man's switch, if any
+	deadMansSwitch atomic.Pointer[DeadMansSwitch]
+	// risk applies the pre-trade risk checks
+	risk *RiskEngine
+	// dryRun makes transactional methods build but never sign or submit transactions
+	dryRun bool
+	// autoSlippage calibrates slippage bounds for market orders without one (optional)
+	autoSlippage *SlippageCalibration
 }
 
 // NewDeltaDeFi creates a new DeltaDeFi client instance.
@@ -41,23 +76,119 @@ type DeltaDeFi struct {
 //   - *DeltaDeFi: A new client instance ready for API operations
 func NewDeltaDeFi(cfg ApiConfig) *DeltaDeFi {
 	client := newClient(cfg)
-	return &DeltaDeFi{
-		Accounts:        newAccountsClient(client),
-		Market:          newMarketClient(client),
-		Order:           newOrderClient(client),
-		MasterWallet:    nil,
-		OperationWallet: nil,
-		client:          client,
+	keystoreID := cfg.KeystoreID
+	if keystoreID == "" {
+		keystoreID = DefaultKeystoreID
+	}
+	clientOrders := cfg.ClientOrderStore
+	if clientOrders == nil {
+		clientOrders = NewMemoryClientOrderStore()
+	}
+	d := &DeltaDeFi{
+		Accounts:     newAccountsClient(client),
+		Market:       newMarketClient(client),
+		Order:        newOrderClient(client),
+		client:       client,
+		keystore:     cfg.Keystore,
+		keystoreID:   keystoreID,
+		clientOrders: clientOrders,
+		dryRun:       cfg.DryRun,
+		autoSlippage: cfg.AutoSlippage,
 	}
+	d.risk = newRiskEngine(d, cfg.RiskLimits)
+	return d
+}
+
+// GetMasterWallet returns the master wallet, or nil if none has been set.
+func (d *DeltaDeFi) GetMasterWallet() *rum.Wallet {
+	d.walletMu.RLock()
+	defer d.walletMu.RUnlock()
+	return d.MasterWallet
+}
+
+// SetMasterWallet replaces the master wallet.
+func (d *DeltaDeFi) SetMasterWallet(wallet *rum.Wallet) {
+	d.walletMu.Lock()
+	defer d.walletMu.Unlock()
+	d.MasterWallet = wallet
+}
+
+// GetOperationWallet returns the operation wallet used for transaction signing,
+// or nil if LoadOperationKey has not been called yet.
+func (d *DeltaDeFi) GetOperationWallet() *rum.Wallet {
+	d.walletMu.RLock()
+	defer d.walletMu.RUnlock()
+	return d.OperationWallet
+}
+
+// setOperationWallet replaces the operation wallet.
+func (d *DeltaDeFi) setOperationWallet(wallet *rum.Wallet) {
+	d.walletMu.Lock()
+	defer d.walletMu.Unlock()
+	d.OperationWallet = wallet
+}
+
+// signTransaction signs the transaction with the given wallet.
+// Calls into the native signer are serialized.
+func (d *DeltaDeFi) signTransaction(wallet *rum.Wallet, txHex string) (string, error) {
+	d.signMu.Lock()
+	defer d.signMu.Unlock()
+	return wallet.Signer().SignTransaction(txHex)
+}
+
+// SetApiKey atomically replaces the API key used by all endpoint clients.
+// Requests already in flight complete with the key they were sent with.
+//
+// Parameters:
+//   - apiKey: The new API key
+func (d *DeltaDeFi) SetApiKey(apiKey string) {
+	d.client.setApiKey(apiKey)
+}
+
+// SetOperationPasscode replaces the operation passcode used by LoadOperationKey when it is
+// called without a passcode.
+//
+// Parameters:
+//   - passcode: The new operation passcode
+func (d *DeltaDeFi) SetOperationPasscode(passcode string) {
+	d.client.setOperationPasscode(passcode)
+}
+
+// APIError is returned when the API responds with a non-2xx status code.
+type APIError struct {
+	// StatusCode is the HTTP status code of the response
+	StatusCode int
+	// Body is the raw response body
+	Body string
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s, status code: %d", e.Body, e.StatusCode)
+}
+
+// checkStatus returns an *APIError unless the response status code is 2xx.
+func checkStatus(resp *http.Response, bodyBytes []byte) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
+	}
+	return nil
 }
 
 // Client represents the underlying HTTP client for API communication.
+// It is safe for concurrent use.
 type Client struct {
-	// ApiKey is the API authentication key
+	// ApiKey is the API authentication key configured at construction.
+	//
+	// Deprecated: use DeltaDeFi.SetApiKey. The field is only read when the API key is first
+	// resolved; later changes have no effect. It will be removed in the next release.
 	ApiKey string
 	// NetworkId identifies the network (0=dev/staging, 1=mainnet)
 	NetworkId uint8
-	// OperationPasscode is used for decrypting operation keys
+	// OperationPasscode is the operation passcode configured at construction.
+	//
+	// Deprecated: use DeltaDeFi.SetOperationPasscode. The field is only read when the passcode is
+	// first resolved; later changes have no effect. It will be removed in the next release.
 	OperationPasscode string
 	// HTTPClient is the HTTP client instance
 	HTTPClient *http.Client
@@ -65,6 +196,17 @@ type Client struct {
 	BaseURL string
 	// WsURL is the WebSocket base URL
 	WsURL string
+
+	// apiKeyValue is the API authentication key, swapped atomically
+	apiKeyValue atomic.Pointer[string]
+	// apiKeyProvider resolves the API key lazily when it was not configured directly
+	apiKeyProvider SecretProvider
+	// operationPasscodeProvider resolves the operation passcode lazily when it was not configured directly
+	operationPasscodeProvider SecretProvider
+	// passcode is the resolved operation passcode (guarded by secretMu)
+	passcode string
+	// secretMu guards lazy resolution of the API key and the operation passcode
+	secretMu sync.Mutex
 }
 
 // newClient creates a new HTTP client instance based on the provided configuration.
@@ -89,20 +231,111 @@ func newClient(cfg ApiConfig) *Client {
 		baseURL = cfg.ProvidedBaseUrl
 	}
 
-	return &Client{
+	client := &Client{
 		ApiKey:            cfg.ApiKey,
 		NetworkId:         networkId,
 		OperationPasscode: cfg.OperationPasscode,
 		HTTPClient: &http.Client{
 			Timeout: 5 * time.Minute,
 		},
-		BaseURL: baseURL,
-		WsURL:   wsURL,
+		BaseURL:                   baseURL,
+		WsURL:                     wsURL,
+		apiKeyProvider:            cfg.ApiKeyProvider,
+		operationPasscodeProvider: cfg.OperationPasscodeProvider,
+	}
+	return client
+}
+
+// apiKey returns the API key, resolving it from the configured provider on first use.
+func (c *Client) apiKey() (string, error) {
+	if apiKey := c.apiKeyValue.Load(); apiKey != nil {
+		return *apiKey, nil
+	}
+
+	c.secretMu.Lock()
+	defer c.secretMu.Unlock()
+
+	// Another goroutine may have resolved the key while we were waiting
+	if apiKey := c.apiKeyValue.Load(); apiKey != nil {
+		return *apiKey, nil
+	}
+
+	apiKey, err := resolveSecret(c.ApiKey, c.apiKeyProvider, "API key")
+	if err != nil {
+		return "", err
+	}
+	c.apiKeyValue.Store(&apiKey)
+	return apiKey, nil
+}
+
+// setApiKey atomically replaces the API key.
+func (c *Client) setApiKey(apiKey string) {
+	c.apiKeyValue.Store(&apiKey)
+}
+
+// operationPasscode returns the operation passcode, resolving it from the configured provider on first use.
+func (c *Client) operationPasscode() (string, error) {
+	c.secretMu.Lock()
+	defer c.secretMu.Unlock()
+
+	if c.passcode != "" {
+		return c.passcode, nil
+	}
+	passcode, err := resolveSecret(c.OperationPasscode, c.operationPasscodeProvider, "operation passcode")
+	if err != nil {
+		return "", err
 	}
+	c.passcode = passcode
+	return passcode, nil
+}
+
+// setOperationPasscode replaces the operation passcode.
+func (c *Client) setOperationPasscode(passcode string) {
+	c.secretMu.Lock()
+	defer c.secretMu.Unlock()
+	c.passcode = passcode
+}
+
+// setHeaders adds the content type and authentication headers to the request.
+func (c *Client) setHeaders(req *http.Request) error {
+	apiKey, err := c.apiKey()
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("X-API-KEY", apiKey)
+	return nil
+}
+
+// validateApiKey performs a lightweight authenticated request using the given API key
+// instead of the installed one, and returns an error unless the API accepts it.
+func (c *Client) validateApiKey(apiKey string) error {
+	req, err := http.NewRequest("GET", c.BaseURL+"/accounts/balance", nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("X-API-KEY", apiKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	return checkStatus(resp, bodyBytes)
 }
 
 // get performs a GET request to the specified URL path.
 // It automatically adds authentication headers and returns the response body.
+// Non-2xx responses are returned as *APIError.
 func (c *Client) get(url string) ([]byte, error) {
 	req, err := http.NewRequest("GET", c.BaseURL+url, nil)
 	if err != nil {
@@ -112,8 +345,9 @@ func (c *Client) get(url string) ([]byte, error) {
 		return nil, fmt.Errorf("empty request")
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("X-API-KEY", c.ApiKey)
+	if err := c.setHeaders(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -126,6 +360,10 @@ func (c *Client) get(url string) ([]byte, error) {
 		return nil, err
 	}
 
+	if err := checkStatus(resp, bodyBytes); err != nil {
+		return nil, err
+	}
+
 	return bodyBytes, nil
 }
 
@@ -145,8 +383,9 @@ func (c *Client) getWithParams(path string, params map[string]string) ([]byte, e
 	req.URL.RawQuery = q.Encode()
 
 	// Add headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("X-API-KEY", c.ApiKey)
+	if err := c.setHeaders(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -161,8 +400,8 @@ func (c *Client) getWithParams(path string, params map[string]string) ([]byte, e
 	}
 
 	// Check if the response status code is not 2xx
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: %s, status code: %d", string(bodyBytes), resp.StatusCode)
+	if err := checkStatus(resp, bodyBytes); err != nil {
+		return nil, err
 	}
 
 	return bodyBytes, nil
@@ -170,6 +409,7 @@ func (c *Client) getWithParams(path string, params map[string]string) ([]byte, e
 
 // post performs a POST request with JSON body.
 // It automatically adds authentication headers and marshals the request body.
+// Non-2xx responses are returned as *APIError.
 func (c *Client) post(url string, body interface{}) ([]byte, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -180,8 +420,9 @@ func (c *Client) post(url string, body interface{}) ([]byte, error) {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("X-API-KEY", c.ApiKey)
+	if err := c.setHeaders(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -194,11 +435,16 @@ func (c *Client) post(url string, body interface{}) ([]byte, error) {
 		return nil, err
 	}
 
+	if err := checkStatus(resp, bodyBytes); err != nil {
+		return nil, err
+	}
+
 	return bodyBytes, nil
 }
 
 // delete performs a DELETE request with JSON body.
 // It automatically adds authentication headers and marshals the request body.
+// Non-2xx responses are returned as *APIError.
 func (c *Client) delete(url string, body interface{}) ([]byte, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
@@ -209,8 +455,9 @@ func (c *Client) delete(url string, body interface{}) ([]byte, error) {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Add("X-API-KEY", c.ApiKey)
+	if err := c.setHeaders(req); err != nil {
+		return nil, err
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -223,5 +470,9 @@ func (c *Client) delete(url string, body interface{}) ([]byte, error) {
 		return nil, err
 	}
 
+	if err := checkStatus(resp, bodyBytes); err != nil {
+		return nil, err
+	}
+
 	return bodyBytes, nil
 }
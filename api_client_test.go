@@ -0,0 +1,440 @@
+package deltadefi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sidan-lab/rum"
+	"github.com/sidan-lab/rum/wallet"
+)
+
+// testTransaction returns a minimal unsigned Conway transaction accepted by the native signer.
+// Its body is unique for n, so the mock exchange can tell which build a signed transaction belongs to.
+func testTransaction(n int) (txHex, bodyHex string) {
+	index := fmt.Sprintf("19%04x", n&0xffff)
+	bodyHex = "a30081825820" + strings.Repeat("00", 32) + index + "0180020a"
+	return "84" + bodyHex + "a0f5f6", bodyHex
+}
+
+// mockExchange is an in-memory stand-in for the order and account endpoints.
+// Placed orders rest as open until a hook or the test changes them.
+type mockExchange struct {
+	t *testing.T
+
+	encryptedKey string
+	keyHash      string
+
+	placed    atomic.Int64
+	cancelled atomic.Int64
+	nextID    atomic.Int64
+
+	mu      sync.Mutex
+	apiKeys map[string]int
+	orders  map[string]*OrderJSON
+	// builds maps the body of each built transaction to its order ID
+	builds map[string]string
+	// requests counts requests by method and path
+	requests map[string]int
+	// depth is the order book returned for every symbol
+	depth GetMarketDepthResponse
+	// price is the market price returned for every symbol
+	price float64
+	// balances are the account balances
+	balances []AssetBalance
+	// candles are returned for every symbol and interval
+	candles GetAggregatedPriceResponse
+	// fills is the trading history, newest first
+	fills []OrderFillingRecordJSON
+
+	// beforeCancel runs before an order is cancelled; returning an error fails the cancellation
+	beforeCancel func(order *OrderJSON) error
+	// beforePlace runs when an order is built; returning an error fails the build
+	beforePlace func(req *BuildPlaceOrderTransactionRequest) error
+	// lookup overrides the status code of single-order lookups, e.g. to simulate outages
+	lookup func(orderID string) int
+	// authorize overrides the status code of every request by API key
+	authorize func(apiKey string) int
+	// hideSubmittedID leaves the order ID out of order submission responses
+	hideSubmittedID bool
+}
+
+func newMockExchange(t *testing.T) (*mockExchange, *httptest.Server) {
+	t.Helper()
+	rootKey := newTestRootKey(t)
+	w, err := wallet.NewRootKeyWallet(rootKey, wallet.NewDerivationIndices())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := operationKeyHash(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := rum.EncryptWithCipher(rootKey, "passcode", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &mockExchange{
+		t:            t,
+		encryptedKey: encrypted,
+		keyHash:      hash,
+		apiKeys:      map[string]int{},
+		orders:       map[string]*OrderJSON{},
+		builds:       map[string]string{},
+		requests:     map[string]int{},
+	}
+	server := httptest.NewServer(m)
+	t.Cleanup(server.Close)
+	return m, server
+}
+
+// newMockClient returns a client connected to a new mock exchange with the operation key loaded.
+func newMockClient(t *testing.T, cfg ApiConfig) (*DeltaDeFi, *mockExchange) {
+	t.Helper()
+	exchange, server := newMockExchange(t)
+	cfg.ProvidedBaseUrl = server.URL
+	if cfg.ApiKey == "" {
+		cfg.ApiKey = "key"
+	}
+	d := NewDeltaDeFi(cfg)
+	if err := d.LoadOperationKey("passcode"); err != nil {
+		t.Fatal(err)
+	}
+	return d, exchange
+}
+
+// addOrder stores an order on the exchange.
+func (m *mockExchange) addOrder(order OrderJSON) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orders[order.OrderID] = &order
+}
+
+// order returns a copy of an order on the exchange.
+func (m *mockExchange) order(id string) OrderJSON {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if order, ok := m.orders[id]; ok {
+		return *order
+	}
+	return OrderJSON{}
+}
+
+// update modifies an order on the exchange.
+func (m *mockExchange) update(id string, fn func(order *OrderJSON)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(m.orders[id])
+}
+
+// count returns the number of requests received for the method and path.
+func (m *mockExchange) count(method, path string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[method+" "+path]
+}
+
+func (m *mockExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiKeys[r.Header.Get("X-API-KEY")]++
+	m.requests[r.Method+" "+r.URL.Path]++
+	if m.authorize != nil {
+		if status := m.authorize(r.Header.Get("X-API-KEY")); status != http.StatusOK {
+			http.Error(w, "unauthorized", status)
+			return
+		}
+	}
+
+	var body map[string]interface{}
+	if r.Body != nil {
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/accounts/operation-key":
+		m.reply(w, GetOperationKeyResponse{EncryptedOperationKey: m.encryptedKey, OperationKeyHash: m.keyHash})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/accounts/order/"):
+		id := strings.TrimPrefix(r.URL.Path, "/accounts/order/")
+		if m.lookup != nil {
+			if status := m.lookup(id); status != http.StatusOK {
+				http.Error(w, "unavailable", status)
+				return
+			}
+		}
+		order, ok := m.orders[id]
+		if !ok {
+			http.Error(w, "order not found", http.StatusNotFound)
+			return
+		}
+		m.reply(w, GetOrderRecordResponse{OrderJSON: *order})
+	case r.Method == http.MethodGet && r.URL.Path == "/accounts/order-records" &&
+		r.URL.Query().Get("status") == string(OrderRecordStatusTradingHistory):
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*limit, len(m.fills))
+		end := min(start+limit, len(m.fills))
+		m.reply(w, GetOrderRecordsResponse{
+			Data:       []OrderRecordsData{{OrderFillingRecords: m.fills[start:end]}},
+			TotalCount: len(m.fills),
+			TotalPage:  (len(m.fills) + limit - 1) / limit,
+		})
+	case r.Method == http.MethodGet && r.URL.Path == "/accounts/order-records":
+		var open []OrderJSON
+		for _, order := range m.orders {
+			if order.OrderStatus() == OrderStatusOpen {
+				open = append(open, *order)
+			}
+		}
+		m.reply(w, GetOrderRecordsResponse{Data: []OrderRecordsData{{Orders: open}}, TotalCount: len(open), TotalPage: 1})
+	case r.Method == http.MethodGet && r.URL.Path == "/market/depth":
+		m.reply(w, m.depth)
+	case r.Method == http.MethodGet && r.URL.Path == "/market/market-price":
+		m.reply(w, GetMarketPriceResponse{Price: m.price})
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/market/graph/"):
+		m.reply(w, m.candles)
+	case r.Method == http.MethodGet && r.URL.Path == "/accounts/new-api-key":
+		m.reply(w, CreateNewAPIKeyResponse{APIKey: fmt.Sprintf("key-%d", m.nextID.Add(1))})
+	case r.Method == http.MethodGet && r.URL.Path == "/accounts/balance":
+		m.reply(w, GetAccountBalanceResponse(m.balances))
+	case r.Method == http.MethodPost && r.URL.Path == "/order/build":
+		var req BuildPlaceOrderTransactionRequest
+		raw, _ := json.Marshal(body)
+		json.Unmarshal(raw, &req)
+		if m.beforePlace != nil {
+			if err := m.beforePlace(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		n := int(m.nextID.Add(1))
+		id := fmt.Sprintf("order-%d", n)
+		order := &OrderJSON{OrderID: id, Status: string(OrderStatusBuilding), Symbol: req.Symbol, Side: req.Side, Type: req.Type,
+			OrigQty: strconv.FormatFloat(req.Quantity, 'f', -1, 64), ExecutedQty: "0"}
+		if req.Price != nil {
+			order.Price = *req.Price
+		}
+		m.orders[id] = order
+		txHex, bodyHex := testTransaction(n)
+		m.builds[bodyHex] = id
+		m.reply(w, BuildPlaceOrderTransactionResponse{OrderID: id, TxHex: txHex})
+	case r.Method == http.MethodPost && r.URL.Path == "/order/submit":
+		m.checkSigned(body)
+		id := fmt.Sprint(body["order_id"])
+		order, ok := m.orders[id]
+		if !ok {
+			http.Error(w, "order not found", http.StatusNotFound)
+			return
+		}
+		order.Status = string(OrderStatusOpen)
+		m.placed.Add(1)
+		res := SubmitPlaceOrderTransactionResponse{Order: *order}
+		if m.hideSubmittedID {
+			res.Order.OrderID = ""
+		}
+		m.reply(w, res)
+	case r.Method == http.MethodDelete && r.URL.Path == "/order/cancel-all/build":
+		txHex, _ := testTransaction(int(m.nextID.Add(1)))
+		m.reply(w, BuildCancelAllOrdersTransactionResponse{TxHexes: []string{txHex}})
+	case r.Method == http.MethodDelete && r.URL.Path == "/order/cancel-all/submit":
+		var cancelled []string
+		for id, order := range m.orders {
+			if order.OrderStatus() == OrderStatusOpen {
+				order.Status = string(OrderStatusCancelled)
+				m.cancelled.Add(1)
+				cancelled = append(cancelled, id)
+			}
+		}
+		m.reply(w, SubmitCancelAllOrdersTransactionResponse{CancelledOrderIds: cancelled})
+	case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/build"):
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/order/"), "/build")
+		if _, ok := m.orders[id]; !ok {
+			http.Error(w, "order not found", http.StatusNotFound)
+			return
+		}
+		txHex, bodyHex := testTransaction(int(m.nextID.Add(1)))
+		m.builds[bodyHex] = id
+		m.reply(w, BuildCancelOrderTransactionResponse{TxHex: txHex})
+	case r.Method == http.MethodDelete && r.URL.Path == "/order/submit":
+		m.checkSigned(body)
+		signed, _ := body["signed_tx"].(string)
+		var order *OrderJSON
+		for bodyHex, id := range m.builds {
+			if strings.HasPrefix(signed, "84"+bodyHex) {
+				order = m.orders[id]
+			}
+		}
+		if order == nil {
+			http.Error(w, "unknown transaction", http.StatusBadRequest)
+			return
+		}
+		if m.beforeCancel != nil {
+			if err := m.beforeCancel(order); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if order.OrderStatus().IsTerminal() {
+			http.Error(w, "order is not open", http.StatusBadRequest)
+			return
+		}
+		order.Status = string(OrderStatusCancelled)
+		m.cancelled.Add(1)
+		m.reply(w, SubmitCancelOrderTransactionResponse{TxHash: "hash"})
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func (m *mockExchange) checkSigned(body map[string]interface{}) {
+	signed, _ := body["signed_tx"].(string)
+	if !strings.Contains(signed, "a10081825820") {
+		m.t.Errorf("transaction submitted without a witness: %q", signed)
+	}
+}
+
+func (m *mockExchange) reply(w http.ResponseWriter, v interface{}) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		m.t.Error(err)
+	}
+}
+
+func TestConcurrentOrderFlow(t *testing.T) {
+	tests := []struct {
+		name       string
+		goroutines int
+		iterations int
+		rotate     bool
+	}{
+		{name: "single goroutine", goroutines: 1, iterations: 4},
+		{name: "parallel orders and key reloads", goroutines: 8, iterations: 4},
+		{name: "parallel with api key rotation", goroutines: 8, iterations: 4, rotate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exchange, server := newMockExchange(t)
+			d := NewDeltaDeFi(ApiConfig{ProvidedBaseUrl: server.URL, ApiKey: "key-0", OperationPasscode: "passcode"})
+			if err := d.LoadOperationKey(""); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			for g := 0; g < tt.goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					for i := 0; i < tt.iterations; i++ {
+						res, err := d.PostOrder(&BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5)})
+						if err != nil {
+							t.Error(err)
+							return
+						}
+						if _, err := d.CancelOrder(res.Order.OrderID); err != nil {
+							t.Error(err)
+							return
+						}
+						switch {
+						case i%2 == 0:
+							if err := d.LoadOperationKey(""); err != nil {
+								t.Error(err)
+							}
+						case tt.rotate:
+							d.SetApiKey(fmt.Sprintf("key-%d", g+1))
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			want := int64(tt.goroutines * tt.iterations)
+			if got := exchange.placed.Load(); got != want {
+				t.Errorf("placed %d orders, want %d", got, want)
+			}
+			if got := exchange.cancelled.Load(); got != want {
+				t.Errorf("cancelled %d orders, want %d", got, want)
+			}
+			for apiKey := range exchange.apiKeys {
+				if !strings.HasPrefix(apiKey, "key-") {
+					t.Errorf("request sent with API key %q", apiKey)
+				}
+			}
+		})
+	}
+}
+
+func TestDeprecatedFields(t *testing.T) {
+	_, server := newMockExchange(t)
+	operationWallet, err := wallet.NewMnemonicWallet(testMnemonic, wallet.NewDerivationIndices())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		setup func(d *DeltaDeFi)
+		check func(t *testing.T, d *DeltaDeFi)
+	}{
+		{
+			name:  "operation wallet field",
+			setup: func(d *DeltaDeFi) { d.OperationWallet = operationWallet },
+			check: func(t *testing.T, d *DeltaDeFi) {
+				if d.GetOperationWallet() != operationWallet {
+					t.Error("GetOperationWallet() ignores the OperationWallet field")
+				}
+				if _, err := d.PostOrder(&BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 1}); err != nil {
+					t.Errorf("PostOrder() with the OperationWallet field: %v", err)
+				}
+			},
+		},
+		{
+			name:  "master wallet field",
+			setup: func(d *DeltaDeFi) { d.SetMasterWallet(operationWallet) },
+			check: func(t *testing.T, d *DeltaDeFi) {
+				if d.MasterWallet != operationWallet || d.GetMasterWallet() != operationWallet {
+					t.Error("SetMasterWallet() and the MasterWallet field disagree")
+				}
+			},
+		},
+		{
+			name:  "passcode setter",
+			setup: func(d *DeltaDeFi) { d.SetOperationPasscode("passcode") },
+			check: func(t *testing.T, d *DeltaDeFi) {
+				if err := d.LoadOperationKey(""); err != nil {
+					t.Errorf("LoadOperationKey() with SetOperationPasscode: %v", err)
+				}
+			},
+		},
+		{
+			name:  "passcode field",
+			setup: func(d *DeltaDeFi) { d.client.OperationPasscode = "passcode" },
+			check: func(t *testing.T, d *DeltaDeFi) {
+				if err := d.LoadOperationKey(""); err != nil {
+					t.Errorf("LoadOperationKey() with the OperationPasscode field: %v", err)
+				}
+			},
+		},
+		{
+			name:  "api key field",
+			setup: func(d *DeltaDeFi) {},
+			check: func(t *testing.T, d *DeltaDeFi) {
+				if d.client.ApiKey != "key" {
+					t.Errorf("Client.ApiKey = %q, want key", d.client.ApiKey)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeltaDeFi(ApiConfig{ProvidedBaseUrl: server.URL, ApiKey: "key"})
+			tt.setup(d)
+			tt.check(t, d)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package deltadefi
+
+import "fmt"
+
+// Deposit is a high-level method for depositing assets into the account.
+// It validates the request, builds the transaction, signs it with the master wallet and submits it.
+// The master wallet must be set with SetMasterWallet before calling this method.
+//
+// Parameters:
+//   - data: Deposit amounts and input UTxOs
+//
+// Returns:
+//   - *SubmitDepositTransactionResponse: Transaction hash of the deposit
+//   - error: nil on success, *DryRunError in dry-run mode, other error on failure
+func (d *DeltaDeFi) Deposit(data *BuildDepositTransactionRequest) (*SubmitDepositTransactionResponse, error) {
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunDeposit(data))
+	}
+	masterWallet := d.GetMasterWallet()
+	if masterWallet == nil {
+		return nil, fmt.Errorf("master wallet is not set")
+	}
+	if err := validateAssetAmounts("deposit", data.DepositAmount); err != nil {
+		return nil, err
+	}
+
+	buildRes, err := d.Accounts.BuildDepositTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := d.signTransaction(masterWallet, buildRes.TxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Accounts.SubmitDepositTransaction(&SubmitDepositTransactionRequest{
+		SignedTx: signedTx,
+	})
+}
+
+// Withdraw is a high-level method for withdrawing assets from the account.
+// It validates the request, builds the transaction, signs it with the master wallet and submits it.
+// The master wallet must be set with SetMasterWallet before calling this method.
+//
+// Parameters:
+//   - data: Withdrawal amounts
+//
+// Returns:
+//   - *SubmitWithdrawalTransactionResponse: Transaction hash of the withdrawal
+//   - error: nil on success, *DryRunError in dry-run mode, other error on failure
+func (d *DeltaDeFi) Withdraw(data *BuildWithdrawalTransactionRequest) (*SubmitWithdrawalTransactionResponse, error) {
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunWithdrawal(data))
+	}
+	masterWallet := d.GetMasterWallet()
+	if masterWallet == nil {
+		return nil, fmt.Errorf("master wallet is not set")
+	}
+	if err := validateAssetAmounts("withdrawal", data.WithdrawalAmount); err != nil {
+		return nil, err
+	}
+
+	buildRes, err := d.Accounts.BuildWithdrawalTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := d.signTransaction(masterWallet, buildRes.TxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Accounts.SubmitWithdrawalTransaction(&SubmitWithdrawalTransactionRequest{
+		SignedTx: signedTx,
+	})
+}
+
+// Transfer is a high-level method for transferring assets to another address.
+// It validates the request, builds the transaction, signs it with the master wallet and submits it.
+// The master wallet must be set with SetMasterWallet before calling this method.
+//
+// Parameters:
+//   - data: Transfer amounts and destination address
+//
+// Returns:
+//   - *SubmitTransferalTransactionResponse: Transaction hash of the transfer
+//   - error: nil on success, *DryRunError in dry-run mode, other error on failure
+func (d *DeltaDeFi) Transfer(data *BuildTransferalTransactionRequest) (*SubmitTransferalTransactionResponse, error) {
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunTransferal(data))
+	}
+	masterWallet := d.GetMasterWallet()
+	if masterWallet == nil {
+		return nil, fmt.Errorf("master wallet is not set")
+	}
+	if data.ToAddress == "" {
+		return nil, fmt.Errorf("transferal destination address is required")
+	}
+	if err := validateAssetAmounts("transferal", data.TransferalAmount); err != nil {
+		return nil, err
+	}
+
+	buildRes, err := d.Accounts.BuildTransferalTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+
+	signedTx, err := d.signTransaction(masterWallet, buildRes.TxHex)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.Accounts.SubmitTransferalTransaction(&SubmitTransferalTransactionRequest{
+		SignedTx: signedTx,
+	})
+}
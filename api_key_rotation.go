@@ -0,0 +1,113 @@
+package deltadefi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// APIKeyValidationError is returned when a newly created API key could not be validated, e.g.
+// because of a network error. The key exists on the server but was not installed; it is kept here
+// so the caller can persist it, retry the validation or revoke it instead of losing it.
+type APIKeyValidationError struct {
+	// APIKey is the new, uninstalled key
+	APIKey string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *APIKeyValidationError) Error() string {
+	return fmt.Sprintf("validating new API key: %v", e.Err)
+}
+
+// Unwrap returns the validation error.
+func (e *APIKeyValidationError) Unwrap() error {
+	return e.Err
+}
+
+// APIKeyRotationOptions configures scheduled API key rotation.
+type APIKeyRotationOptions struct {
+	// Interval is the time between rotations
+	Interval time.Duration
+	// OnRotate is called with every newly installed key so it can be persisted (optional)
+	OnRotate func(apiKey string) error
+	// OnError is called when a scheduled rotation fails (optional). A key that was created but not
+	// validated is reported as *APIKeyValidationError.
+	OnError func(err error)
+}
+
+// RotateAPIKey replaces the API key of the running client.
+// It creates a new key, validates it with a lightweight authenticated call, installs it
+// atomically for all endpoint clients and finally notifies onRotate so it can be persisted.
+// If validation fails, the current key stays installed and the new key is returned together with
+// an *APIKeyValidationError, since it already exists on the server.
+//
+// Parameters:
+//   - onRotate: Callback receiving the new key after it has been installed (optional)
+//
+// Returns:
+//   - string: The new API key, also returned when validation or onRotate fails
+//   - error: nil on success, error on failure. If only onRotate fails, the new key is
+//     still installed and returned together with the error.
+func (d *DeltaDeFi) RotateAPIKey(onRotate func(apiKey string) error) (string, error) {
+	d.rotateMu.Lock()
+	defer d.rotateMu.Unlock()
+
+	res, err := d.Accounts.CreateNewAPIKey()
+	if err != nil {
+		return "", fmt.Errorf("creating API key: %w", err)
+	}
+	if res.APIKey == "" {
+		return "", fmt.Errorf("creating API key: empty API key in response")
+	}
+
+	if err := d.client.validateApiKey(res.APIKey); err != nil {
+		return res.APIKey, &APIKeyValidationError{APIKey: res.APIKey, Err: err}
+	}
+
+	d.client.setApiKey(res.APIKey)
+
+	if onRotate != nil {
+		if err := onRotate(res.APIKey); err != nil {
+			return res.APIKey, fmt.Errorf("new API key installed but rotation callback failed: %w", err)
+		}
+	}
+	return res.APIKey, nil
+}
+
+// StartAPIKeyRotation rotates the API key periodically in a background goroutine.
+//
+// Parameters:
+//   - opts: Rotation interval and callbacks
+//
+// Returns:
+//   - func(): Stops the scheduled rotation; safe to call more than once
+//   - error: nil on success, error if the options are invalid
+func (d *DeltaDeFi) StartAPIKeyRotation(opts APIKeyRotationOptions) (func(), error) {
+	if opts.Interval <= 0 {
+		return nil, fmt.Errorf("rotation interval must be positive")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				_, err := d.RotateAPIKey(opts.OnRotate)
+				if err != nil && opts.OnError != nil {
+					opts.OnError(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}, nil
+}
@@ -0,0 +1,158 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRotateAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		// reject is the status returned for requests using the new key, 0 accepts it
+		reject        int
+		callbackErr   error
+		wantInstalled bool
+		wantCallback  bool
+		wantErr       bool
+		wantKeyInErr  bool
+	}{
+		{name: "rotated", wantInstalled: true, wantCallback: true},
+		{name: "callback failure keeps the new key", callbackErr: errors.New("disk full"), wantInstalled: true, wantCallback: true, wantErr: true},
+		{name: "rejected key is not installed", reject: http.StatusUnauthorized, wantErr: true, wantKeyInErr: true},
+		{name: "validation outage returns the key", reject: http.StatusBadGateway, wantErr: true, wantKeyInErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{ApiKey: "old"})
+			exchange.authorize = func(apiKey string) int {
+				if apiKey != "old" && tt.reject != 0 {
+					return tt.reject
+				}
+				return http.StatusOK
+			}
+			var called []string
+			newKey, err := d.RotateAPIKey(func(apiKey string) error {
+				called = append(called, apiKey)
+				return tt.callbackErr
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RotateAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(newKey, "key-") {
+				t.Errorf("RotateAPIKey() = %q, want the new key", newKey)
+			}
+			var validationErr *APIKeyValidationError
+			if errors.As(err, &validationErr) != tt.wantKeyInErr {
+				t.Errorf("error = %v, want *APIKeyValidationError %v", err, tt.wantKeyInErr)
+			} else if tt.wantKeyInErr && validationErr.APIKey != newKey {
+				t.Errorf("error key = %q, want %q", validationErr.APIKey, newKey)
+			}
+
+			installed, err := d.client.apiKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (installed == newKey) != tt.wantInstalled {
+				t.Errorf("installed key = %q, want new key installed %v", installed, tt.wantInstalled)
+			}
+			if tt.wantInstalled {
+				// Later requests use the new key
+				if _, err := d.Accounts.GetAccountBalance(); err != nil {
+					t.Fatal(err)
+				}
+				exchange.mu.Lock()
+				uses := exchange.apiKeys[newKey]
+				exchange.mu.Unlock()
+				if uses < 2 {
+					t.Errorf("new key used %d times, want validation and the next request", uses)
+				}
+			}
+			if (len(called) == 1 && called[0] == newKey) != tt.wantCallback {
+				t.Errorf("callback calls = %v, want callback %v", called, tt.wantCallback)
+			}
+		})
+	}
+}
+
+func TestStartAPIKeyRotation(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{ApiKey: "old"})
+	if _, err := d.StartAPIKeyRotation(APIKeyRotationOptions{}); err == nil {
+		t.Error("StartAPIKeyRotation() accepted a zero interval")
+	}
+
+	var (
+		mu      sync.Mutex
+		rotated []string
+		errs    []error
+	)
+	// authorize runs under the exchange lock, which also guards reject
+	reject := true
+	exchange.authorize = func(apiKey string) int {
+		if reject && apiKey != "old" {
+			return http.StatusUnauthorized
+		}
+		return http.StatusOK
+	}
+
+	stop, err := d.StartAPIKeyRotation(APIKeyRotationOptions{
+		Interval: 10 * time.Millisecond,
+		OnRotate: func(apiKey string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rotated = append(rotated, apiKey)
+			return nil
+		},
+		OnError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// Rejected keys are reported with the key, then rotation succeeds once keys validate
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	})
+	exchange.mu.Lock()
+	reject = false
+	exchange.mu.Unlock()
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(rotated) > 0
+	})
+	stop()
+	stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var validationErr *APIKeyValidationError
+	if !errors.As(errs[0], &validationErr) || validationErr.APIKey == "" {
+		t.Errorf("OnError = %v, want *APIKeyValidationError with the key", errs[0])
+	}
+	if installed, _ := d.client.apiKey(); installed == "old" {
+		t.Error("scheduled rotation did not install a new key")
+	}
+}
+
+// waitFor polls cond until it holds, failing the test after two seconds.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
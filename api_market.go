@@ -44,6 +44,31 @@ func (c *MarketClient) GetMarketPrice(symbol string) (*GetMarketPriceResponse, e
 	return &getMarketPriceResponse, nil
 }
 
+// GetMarketDepth retrieves the current order book for the specified trading pair.
+//
+// Parameters:
+//   - symbol: Trading pair symbol (e.g., "ADAUSDM")
+//
+// Returns:
+//   - *GetMarketDepthResponse: Bid and ask price levels
+//   - error: nil on success, error on failure
+func (c *MarketClient) GetMarketDepth(symbol string) (*GetMarketDepthResponse, error) {
+	params := make(map[string]string)
+	params["symbol"] = symbol
+
+	bodyBytes, err := c.client.getWithParams(c.pathUrl+"/depth", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var getMarketDepthResponse GetMarketDepthResponse
+	err = json.Unmarshal(bodyBytes, &getMarketDepthResponse)
+	if err != nil {
+		return nil, err
+	}
+	return &getMarketDepthResponse, nil
+}
+
 // GetAggregatedPrice retrieves historical price data (candlesticks) for the specified parameters.
 // Supports various time intervals and date ranges for technical analysis.
 //
@@ -0,0 +1,119 @@
+package deltadefi
+
+import (
+	"context"
+	"fmt"
+)
+
+// QuoteOrderRequest describes a market order sized in quote currency, e.g. "buy 500 USDM worth of ADA".
+type QuoteOrderRequest struct {
+	Symbol Symbol
+	Side   OrderSide
+	// QuoteAmount is the quote amount to spend (buys) or receive (sells)
+	QuoteAmount float64
+	// MaxSlippageBasisPoint bounds the distance from the best price, both for the depth walk and on the placed order
+	MaxSlippageBasisPoint int
+	// LotSize is the base quantity step; the quantity is rounded down to a multiple of it (optional)
+	LotSize float64
+}
+
+// QuoteOrderResult reports a quote-denominated order and how its fills compare to the estimate.
+type QuoteOrderResult struct {
+	// Estimate is the depth walk the base quantity was derived from
+	Estimate *QuoteEstimate
+	// Request is the market order that was placed
+	Request *BuildPlaceOrderTransactionRequest
+	// Order is the submission result
+	Order *SubmitPlaceOrderTransactionResponse
+	// Final is the last observed state of the order
+	Final *OrderJSON
+	// ExecutedQuantity is the base quantity filled
+	ExecutedQuantity float64
+	// ActualQuoteAmount is the quote amount actually spent (buys) or received (sells)
+	ActualQuoteAmount float64
+	// AveragePrice is the volume-weighted fill price
+	AveragePrice float64
+}
+
+// EstimateQuoteOrder converts a quote-denominated order into a base quantity using the current
+// order book, without placing anything.
+//
+// Parameters:
+//   - req: The quote-denominated order
+//
+// Returns:
+//   - *QuoteEstimate: The base quantity and estimated quote amount
+//   - error: ErrNotFillable if the book within the slippage bound cannot absorb the amount, other error on failure
+func (d *DeltaDeFi) EstimateQuoteOrder(req *QuoteOrderRequest) (*QuoteEstimate, error) {
+	depth, err := d.Market.GetMarketDepth(string(req.Symbol))
+	if err != nil {
+		return nil, fmt.Errorf("fetching market depth: %w", err)
+	}
+	return depth.EstimateQuoteOrder(req.Side, req.QuoteAmount, req.MaxSlippageBasisPoint, req.LotSize)
+}
+
+// PostQuoteOrder places a market order sized in quote currency. The base quantity is derived by
+// walking the current order book within the slippage bound and rounding down to the lot size; the
+// order is placed with the same slippage bound, and its fills are awaited to report the actual spend.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - ctx: Context bounding the wait for fills
+//   - req: The quote-denominated order
+//
+// Returns:
+//   - *QuoteOrderResult: The estimate, the placed order and the actual fills
+//   - error: nil on success; if the order was placed but its fills could not be awaited, the result
+//     is returned together with the error
+func (d *DeltaDeFi) PostQuoteOrder(ctx context.Context, req *QuoteOrderRequest) (*QuoteOrderResult, error) {
+	estimate, err := d.EstimateQuoteOrder(req)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &BuildPlaceOrderTransactionRequest{
+		Symbol:   req.Symbol,
+		Side:     req.Side,
+		Type:     OrderTypeMarket,
+		Quantity: estimate.Quantity,
+	}
+	if req.MaxSlippageBasisPoint > 0 {
+		order.MaxSlippageBasisPoint = IntPtr(req.MaxSlippageBasisPoint)
+		order.LimitSlippage = BoolPtr(true)
+	}
+
+	res, err := d.PostOrder(order)
+	if err != nil {
+		return nil, err
+	}
+	result := &QuoteOrderResult{Estimate: estimate, Request: order, Order: res}
+
+	final, err := d.WaitForOrder(ctx, res.Order.OrderID, OrderIsTerminal)
+	if final == nil {
+		return result, err
+	}
+	result.Final = final
+	result.ExecutedQuantity, result.ActualQuoteAmount, result.AveragePrice = executedQuote(final)
+	return result, err
+}
+
+// executedQuote sums the filled base quantity and quote amount of an order, from its fills when
+// present and from its executed quantity and price otherwise.
+func executedQuote(order *OrderJSON) (quantity, quoteAmount, averagePrice float64) {
+	for _, fill := range order.Fills {
+		filled, err := parseQuantity(fill.FilledAmount)
+		if err != nil {
+			continue
+		}
+		quantity += filled
+		quoteAmount += filled * fill.ExecutionPrice
+	}
+	if quantity <= quantityEpsilon {
+		quantity, _ = order.ExecutedQuantity()
+		quoteAmount = quantity * order.ExecutedPrice
+	}
+	if quantity > quantityEpsilon {
+		averagePrice = quoteAmount / quantity
+	}
+	return quantity, quoteAmount, averagePrice
+}
@@ -0,0 +1,91 @@
+package deltadefi
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestExecutedQuote(t *testing.T) {
+	tests := []struct {
+		name         string
+		order        OrderJSON
+		wantQuantity float64
+		wantQuote    float64
+		wantAverage  float64
+	}{
+		{
+			name: "from fills",
+			order: OrderJSON{ExecutedQty: "30", ExecutedPrice: 9, Fills: []OrderExecutionRecordJSON{
+				{FilledAmount: "10", ExecutionPrice: 1},
+				{FilledAmount: "20", ExecutionPrice: 1.3},
+			}},
+			wantQuantity: 30,
+			wantQuote:    36,
+			wantAverage:  1.2,
+		},
+		{
+			name:         "from the executed quantity without fills",
+			order:        OrderJSON{ExecutedQty: "20", ExecutedPrice: 1.1},
+			wantQuantity: 20,
+			wantQuote:    22,
+			wantAverage:  1.1,
+		},
+		{
+			name:  "unfilled",
+			order: OrderJSON{ExecutedQty: "0", ExecutedPrice: 1.1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, quote, average := executedQuote(&tt.order)
+			if math.Abs(quantity-tt.wantQuantity) > 1e-9 || math.Abs(quote-tt.wantQuote) > 1e-9 || math.Abs(average-tt.wantAverage) > 1e-9 {
+				t.Errorf("executedQuote() = %v, %v, %v, want %v, %v, %v", quantity, quote, average, tt.wantQuantity, tt.wantQuote, tt.wantAverage)
+			}
+		})
+	}
+}
+
+func TestPostQuoteOrder(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.depth = testDepth
+	// The order fills on its first lookup, at worse prices than the book suggested
+	exchange.lookup = func(orderID string) int {
+		order := exchange.orders[orderID]
+		order.Status, order.ExecutedQty = "closed", order.OrigQty
+		order.Fills = []OrderExecutionRecordJSON{
+			{FilledAmount: "100", ExecutionPrice: 1},
+			{FilledAmount: "49", ExecutionPrice: 1.02},
+		}
+		return http.StatusOK
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := d.PostQuoteOrder(ctx, &QuoteOrderRequest{
+		Symbol: ADAUSDM, Side: OrderSideBuy, QuoteAmount: 150, MaxSlippageBasisPoint: 200, LotSize: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	placed := exchange.order(result.Order.Order.OrderID)
+	if quantity, _ := placed.OriginalQuantity(); quantity != 149 || placed.Type != OrderTypeMarket {
+		t.Errorf("placed %s order of %v, want a market order of 149", placed.Type, quantity)
+	}
+	if result.Request.MaxSlippageBasisPoint == nil || *result.Request.MaxSlippageBasisPoint != 200 ||
+		result.Request.LimitSlippage == nil || !*result.Request.LimitSlippage {
+		t.Errorf("request slippage = %v / %v, want a 200 bp limit", result.Request.MaxSlippageBasisPoint, result.Request.LimitSlippage)
+	}
+	if math.Abs(result.Estimate.QuoteAmount-149.49) > 1e-9 {
+		t.Errorf("estimated quote = %v, want 149.49", result.Estimate.QuoteAmount)
+	}
+	if result.ExecutedQuantity != 149 || math.Abs(result.ActualQuoteAmount-149.98) > 1e-9 {
+		t.Errorf("executed %v for %v quote, want 149 for 149.98", result.ExecutedQuantity, result.ActualQuoteAmount)
+	}
+	if math.Abs(result.AveragePrice-149.98/149) > 1e-9 {
+		t.Errorf("AveragePrice = %v, want %v", result.AveragePrice, 149.98/149)
+	}
+}
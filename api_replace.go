@@ -0,0 +1,149 @@
+package deltadefi
+
+import (
+	"fmt"
+)
+
+// ReplaceOutcome describes the combined result of a ReplaceOrder call.
+type ReplaceOutcome string
+
+const (
+	// ReplaceOutcomeReplaced means the original order was cancelled and the replacement placed
+	ReplaceOutcomeReplaced ReplaceOutcome = "replaced"
+	// ReplaceOutcomeCancelOnly means the original order was cancelled but the replacement could not be placed
+	ReplaceOutcomeCancelOnly ReplaceOutcome = "cancel_only"
+	// ReplaceOutcomeOriginalFilled means the original order was fully filled, so nothing was replaced
+	ReplaceOutcomeOriginalFilled ReplaceOutcome = "original_filled"
+	// ReplaceOutcomeCancelledNoRemainder means the original order was cancelled and its fills already
+	// cover the requested quantity, so no replacement was placed
+	ReplaceOutcomeCancelledNoRemainder ReplaceOutcome = "cancelled_no_remainder"
+	// ReplaceOutcomeFailed means the original order could not be cancelled and is unchanged
+	ReplaceOutcomeFailed ReplaceOutcome = "failed"
+)
+
+// ReplaceOrderResult contains the outcome of a ReplaceOrder call.
+type ReplaceOrderResult struct {
+	// Outcome is the combined result of the cancel and re-place steps
+	Outcome ReplaceOutcome
+	// Original is the last observed state of the original order
+	Original *OrderJSON
+	// FilledQuantity is the quantity of the original order that was executed before cancellation
+	FilledQuantity float64
+	// Cancel is the cancellation result, nil if the original was not cancelled
+	Cancel *SubmitCancelOrderTransactionResponse
+	// Replacement is the replacement order, nil if it was not placed
+	Replacement *SubmitPlaceOrderTransactionResponse
+	// Err is the error that stopped the replacement, nil when Outcome is replaced, original_filled
+	// or cancelled_no_remainder
+	Err error
+}
+
+// ReplaceOrder amends an open order by cancelling it and placing newRequest in its place.
+// The fill state of the original is checked before cancelling and again after the cancellation,
+// and only the quantity the original did not fill is re-placed: the replacement quantity is
+// newRequest.Quantity minus the executed quantity of the original.
+// newRequest is checked before anything is cancelled. If the original cannot be fetched after the
+// cancellation, its final fill state is unknown and nothing is re-placed.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - orderId: The ID of the order to replace
+//   - newRequest: The replacement order; Quantity is the desired total including any fills of the original
+//
+// Returns:
+//   - *ReplaceOrderResult: The combined outcome, always non-nil
+//   - error: nil when the outcome is replaced, original_filled or cancelled_no_remainder,
+//     otherwise the error in ReplaceOrderResult.Err
+func (d *DeltaDeFi) ReplaceOrder(orderId string, newRequest *BuildPlaceOrderTransactionRequest) (*ReplaceOrderResult, error) {
+	result := &ReplaceOrderResult{Outcome: ReplaceOutcomeFailed}
+	fail := func(err error) (*ReplaceOrderResult, error) {
+		result.Err = err
+		return result, err
+	}
+
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
+		return fail(fmt.Errorf("operation wallet is not loaded"))
+	}
+	if newRequest == nil {
+		return fail(fmt.Errorf("replacement order is nil"))
+	}
+	if newRequest.Quantity <= 0 {
+		return fail(fmt.Errorf("replacement quantity must be positive, got %v", newRequest.Quantity))
+	}
+
+	original, err := d.fetchOrder(orderId)
+	if err != nil {
+		return fail(fmt.Errorf("fetching original order: %w", err))
+	}
+	result.Original = original
+
+	remaining, err := original.RemainingQuantity()
+	if err != nil {
+		return fail(err)
+	}
+	status := original.OrderStatus()
+	if remaining == 0 || status == OrderStatusClosed {
+		return d.finishFilledReplace(result, original)
+	}
+	if status.IsTerminal() {
+		return fail(fmt.Errorf("order %s is %s and cannot be replaced", orderId, original.Status))
+	}
+
+	cancelRes, err := d.cancelOrder(operationWallet, orderId)
+	if err != nil {
+		// The cancellation may have failed because the order filled in the meantime
+		if latest, fetchErr := d.fetchOrder(orderId); fetchErr == nil {
+			result.Original = latest
+			if left, qtyErr := latest.RemainingQuantity(); qtyErr == nil && left == 0 {
+				return d.finishFilledReplace(result, latest)
+			}
+		}
+		return fail(fmt.Errorf("cancelling original order: %w", err))
+	}
+	result.Cancel = cancelRes
+	result.Outcome = ReplaceOutcomeCancelOnly
+
+	// Fills may have happened between the first check and the cancellation, so the state
+	// fetched before it cannot size the replacement
+	latest, err := d.fetchOrder(orderId)
+	if err != nil {
+		return fail(fmt.Errorf("fetching original order after cancellation: %w", err))
+	}
+	result.Original = latest
+	filled, err := latest.ExecutedQuantity()
+	if err != nil {
+		return fail(err)
+	}
+	result.FilledQuantity = filled
+
+	quantity := newRequest.Quantity - filled
+	if quantity <= 0 {
+		result.Outcome = ReplaceOutcomeCancelledNoRemainder
+		return result, nil
+	}
+
+	replacement := *newRequest
+	replacement.Quantity = quantity
+	placeRes, err := d.placeOrder(operationWallet, &replacement)
+	if err != nil {
+		return fail(fmt.Errorf("placing replacement order: %w", err))
+	}
+
+	result.Replacement = placeRes
+	result.Outcome = ReplaceOutcomeReplaced
+	return result, nil
+}
+
+// finishFilledReplace completes a ReplaceOrder call whose original order is fully filled.
+func (d *DeltaDeFi) finishFilledReplace(result *ReplaceOrderResult, original *OrderJSON) (*ReplaceOrderResult, error) {
+	filled, err := original.ExecutedQuantity()
+	if err != nil {
+		result.Err = err
+		return result, err
+	}
+	result.Original = original
+	result.FilledQuantity = filled
+	result.Outcome = ReplaceOutcomeOriginalFilled
+	return result, nil
+}
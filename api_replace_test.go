@@ -0,0 +1,190 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestReplaceOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		original     OrderJSON
+		quantity     float64
+		beforeCancel func(order *OrderJSON) error
+		beforePlace  func(req *BuildPlaceOrderTransactionRequest) error
+		// failCancelledLookup fails lookups of the original once it is cancelled
+		failCancelledLookup bool
+		wantOutcome         ReplaceOutcome
+		wantFilled          float64
+		wantQuantity        float64
+		wantErr             bool
+		wantCancel          bool
+	}{
+		{
+			name:         "replaced",
+			original:     OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity:     10,
+			wantOutcome:  ReplaceOutcomeReplaced,
+			wantQuantity: 10,
+			wantCancel:   true,
+		},
+		{
+			name:     "fill before cancel reduces replacement",
+			original: OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity: 10,
+			beforeCancel: func(order *OrderJSON) error {
+				order.ExecutedQty = "4"
+				return nil
+			},
+			wantOutcome:  ReplaceOutcomeReplaced,
+			wantFilled:   4,
+			wantQuantity: 6,
+			wantCancel:   true,
+		},
+		{
+			name:        "already filled",
+			original:    OrderJSON{Status: "closed", OrigQty: "10", ExecutedQty: "10"},
+			quantity:    10,
+			wantOutcome: ReplaceOutcomeOriginalFilled,
+			wantFilled:  10,
+		},
+		{
+			name:     "filled while cancelling",
+			original: OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity: 10,
+			beforeCancel: func(order *OrderJSON) error {
+				order.Status, order.ExecutedQty = "closed", "10"
+				return nil
+			},
+			wantOutcome: ReplaceOutcomeOriginalFilled,
+			wantFilled:  10,
+		},
+		{
+			name:        "cancelled with no remainder",
+			original:    OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "4"},
+			quantity:    4,
+			wantOutcome: ReplaceOutcomeCancelledNoRemainder,
+			wantFilled:  4,
+			wantCancel:  true,
+		},
+		{
+			name:     "replacement rejected",
+			original: OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity: 10,
+			beforePlace: func(req *BuildPlaceOrderTransactionRequest) error {
+				return errors.New("insufficient balance")
+			},
+			wantOutcome: ReplaceOutcomeCancelOnly,
+			wantErr:     true,
+			wantCancel:  true,
+		},
+		{
+			name:     "cancel rejected",
+			original: OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity: 10,
+			beforeCancel: func(order *OrderJSON) error {
+				return errors.New("rejected")
+			},
+			wantOutcome: ReplaceOutcomeFailed,
+			wantErr:     true,
+		},
+		{
+			name:                "lookup after cancellation fails",
+			original:            OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity:            10,
+			failCancelledLookup: true,
+			wantOutcome:         ReplaceOutcomeCancelOnly,
+			wantErr:             true,
+			wantCancel:          true,
+		},
+		{
+			name:        "zero quantity is rejected before cancelling",
+			original:    OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			quantity:    0,
+			wantOutcome: ReplaceOutcomeFailed,
+			wantErr:     true,
+		},
+		{
+			name:        "already cancelled",
+			original:    OrderJSON{Status: "cancelled", OrigQty: "10", ExecutedQty: "2"},
+			quantity:    10,
+			wantOutcome: ReplaceOutcomeFailed,
+			wantErr:     true,
+		},
+		{
+			name:        "missing executed quantity",
+			original:    OrderJSON{Status: "open", OrigQty: "10"},
+			quantity:    10,
+			wantOutcome: ReplaceOutcomeFailed,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			tt.original.OrderID = "original"
+			tt.original.Symbol = ADAUSDM
+			exchange.addOrder(tt.original)
+			exchange.beforeCancel = tt.beforeCancel
+			exchange.beforePlace = tt.beforePlace
+			if tt.failCancelledLookup {
+				exchange.lookup = func(orderID string) int {
+					if exchange.orders[orderID].Status == "cancelled" {
+						return http.StatusServiceUnavailable
+					}
+					return http.StatusOK
+				}
+			}
+
+			result, err := d.ReplaceOrder("original", &BuildPlaceOrderTransactionRequest{
+				Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: tt.quantity, Price: FloatPtr(0.5),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReplaceOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result.Err != err {
+				t.Errorf("result.Err = %v, want %v", result.Err, err)
+			}
+			if result.Outcome != tt.wantOutcome {
+				t.Errorf("Outcome = %s, want %s", result.Outcome, tt.wantOutcome)
+			}
+			if result.FilledQuantity != tt.wantFilled {
+				t.Errorf("FilledQuantity = %v, want %v", result.FilledQuantity, tt.wantFilled)
+			}
+			if (result.Cancel != nil) != tt.wantCancel {
+				t.Errorf("Cancel = %v, want cancelled %v", result.Cancel, tt.wantCancel)
+			}
+
+			if tt.wantQuantity == 0 {
+				if result.Replacement != nil {
+					t.Errorf("unexpected replacement %+v", result.Replacement.Order)
+				}
+				if n := exchange.count(http.MethodPost, "/order/submit"); n != 0 {
+					t.Errorf("%d orders submitted, want none", n)
+				}
+				return
+			}
+			if result.Replacement == nil {
+				t.Fatal("no replacement placed")
+			}
+			placed := exchange.order(result.Replacement.Order.OrderID)
+			if quantity, _ := placed.OriginalQuantity(); quantity != tt.wantQuantity {
+				t.Errorf("replacement quantity = %v, want %v", quantity, tt.wantQuantity)
+			}
+		})
+	}
+}
+
+func TestReplaceOrderRejectsNilRequest(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.addOrder(OrderJSON{OrderID: "original", Symbol: ADAUSDM, Status: "open", OrigQty: "10", ExecutedQty: "0"})
+
+	result, err := d.ReplaceOrder("original", nil)
+	if err == nil || result.Outcome != ReplaceOutcomeFailed {
+		t.Fatalf("ReplaceOrder(nil) = %s, %v, want failed", result.Outcome, err)
+	}
+	if exchange.order("original").Status != "open" {
+		t.Error("original cancelled for an invalid replacement")
+	}
+}
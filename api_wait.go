@@ -0,0 +1,95 @@
+package deltadefi
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// waitInitialInterval is the first polling interval of WaitForOrder
+	waitInitialInterval = 250 * time.Millisecond
+	// waitMaxInterval caps the polling interval of WaitForOrder
+	waitMaxInterval = 5 * time.Second
+)
+
+// OrderPredicate reports whether an order has reached the state a caller is waiting for.
+type OrderPredicate func(order *OrderJSON) bool
+
+// OrderIsTerminal is an OrderPredicate matching orders that are closed, cancelled or failed.
+func OrderIsTerminal(order *OrderJSON) bool {
+	return order.OrderStatus().IsTerminal()
+}
+
+// OrderExecutedAtLeast returns an OrderPredicate matching orders with at least qty executed.
+func OrderExecutedAtLeast(qty float64) OrderPredicate {
+	return func(order *OrderJSON) bool {
+		executed, err := order.ExecutedQuantity()
+		return err == nil && executed >= qty
+	}
+}
+
+// WaitForOrder blocks until the order satisfies the predicate, or reaches a terminal status
+// (closed, cancelled, failed) when predicate is nil.
+// The order is polled with GetOrderRecord using exponential backoff; failed lookups are retried.
+// The SDK has no order stream yet, so polling is the only source of updates.
+// An impossible status change between two polls is reported as *OrderStatusTransitionError.
+//
+// Parameters:
+//   - ctx: Context bounding the wait
+//   - orderId: The ID of the order to wait for
+//   - predicate: The condition to wait for (optional, defaults to OrderIsTerminal)
+//
+// Returns:
+//   - *OrderJSON: The order state that satisfied the condition
+//   - error: nil on success, the context error (wrapping the last lookup error, if any) on timeout or cancellation
+func (d *DeltaDeFi) WaitForOrder(ctx context.Context, orderId string, predicate OrderPredicate) (*OrderJSON, error) {
+	if predicate == nil {
+		predicate = OrderIsTerminal
+	}
+
+	interval := waitInitialInterval
+	var (
+		lastErr  error
+		previous OrderStatus
+	)
+	for {
+		order, err := d.fetchOrder(orderId)
+		if err == nil {
+			status := order.OrderStatus()
+			if previous != "" {
+				if err := ValidateOrderStatusTransition(orderId, previous, status); err != nil {
+					return order, err
+				}
+			}
+			previous = status
+
+			if predicate(order) {
+				return order, nil
+			}
+			// A terminal order never changes again, so a custom predicate can no longer match
+			if OrderIsTerminal(order) {
+				return order, fmt.Errorf("order %s reached terminal status %s without matching the condition", orderId, order.Status)
+			}
+			lastErr = nil
+		} else {
+			lastErr = err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if lastErr != nil {
+				return nil, fmt.Errorf("waiting for order %s: %w (last error: %w)", orderId, ctx.Err(), lastErr)
+			}
+			return nil, fmt.Errorf("waiting for order %s: %w", orderId, ctx.Err())
+		case <-timer.C:
+		}
+
+		interval *= 2
+		if interval > waitMaxInterval {
+			interval = waitMaxInterval
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package deltadefi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWaitForOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		initial   OrderJSON
+		predicate OrderPredicate
+		// update changes the order on the given lookup, counted from 1
+		update      func(lookup int, order *OrderJSON)
+		wantStatus  string
+		wantErr     bool
+		wantBadMove bool
+	}{
+		{
+			name:       "terminal by default",
+			initial:    OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			update:     closeOnSecondLookup,
+			wantStatus: "closed",
+		},
+		{
+			name:      "predicate matches before terminal",
+			initial:   OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			predicate: OrderExecutedAtLeast(5),
+			update: func(lookup int, order *OrderJSON) {
+				if lookup == 2 {
+					order.ExecutedQty = "5"
+				}
+			},
+			wantStatus: "open",
+		},
+		{
+			name:       "terminal without matching",
+			initial:    OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			predicate:  OrderExecutedAtLeast(20),
+			update:     closeOnSecondLookup,
+			wantStatus: "closed",
+			wantErr:    true,
+		},
+		{
+			name:    "impossible transition",
+			initial: OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			update: func(lookup int, order *OrderJSON) {
+				if lookup == 2 {
+					order.Status = "building"
+				}
+			},
+			wantStatus:  "building",
+			wantErr:     true,
+			wantBadMove: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			tt.initial.OrderID = "order"
+			exchange.addOrder(tt.initial)
+			lookups := 0
+			exchange.lookup = func(orderID string) int {
+				lookups++
+				tt.update(lookups, exchange.orders[orderID])
+				return http.StatusOK
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			order, err := d.WaitForOrder(ctx, "order", tt.predicate)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WaitForOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var transitionErr *OrderStatusTransitionError
+			if errors.As(err, &transitionErr) != tt.wantBadMove {
+				t.Errorf("WaitForOrder() error = %v, want transition error %v", err, tt.wantBadMove)
+			}
+			if order == nil || order.Status != tt.wantStatus {
+				t.Fatalf("WaitForOrder() order = %+v, want status %s", order, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// closeOnSecondLookup fills and closes the order when it is looked up the second time.
+func closeOnSecondLookup(lookup int, order *OrderJSON) {
+	if lookup == 2 {
+		order.Status, order.ExecutedQty = "closed", order.OrigQty
+	}
+}
+
+func TestWaitForOrderTimeout(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.addOrder(OrderJSON{OrderID: "order", Status: "open", OrigQty: "10", ExecutedQty: "0"})
+	exchange.lookup = func(string) int { return http.StatusServiceUnavailable }
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	order, err := d.WaitForOrder(ctx, "order", nil)
+	if order != nil {
+		t.Errorf("WaitForOrder() order = %+v, want nil", order)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitForOrder() error = %v, want the context error", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("WaitForOrder() error = %v, want the last lookup error wrapped", err)
+	}
+}
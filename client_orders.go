@@ -0,0 +1,461 @@
+package deltadefi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrClientOrderNotFound is returned when no record exists for a client order ID.
+var ErrClientOrderNotFound = errors.New("client order not found")
+
+// ClientOrderState represents how far the SDK got in placing an order with a client order ID.
+type ClientOrderState string
+
+const (
+	// ClientOrderStateBuilt means the server assigned an OrderID but the submission was not confirmed
+	ClientOrderStateBuilt ClientOrderState = "built"
+	// ClientOrderStateSubmitted means the signed order was accepted by the server
+	ClientOrderStateSubmitted ClientOrderState = "submitted"
+)
+
+// ClientOrderRecord maps a caller-supplied client order ID to the server OrderID.
+type ClientOrderRecord struct {
+	ClientOrderID string           `json:"client_order_id"`
+	OrderID       string           `json:"order_id"`
+	State         ClientOrderState `json:"state"`
+	Symbol        Symbol           `json:"symbol"`
+	Side          OrderSide        `json:"side"`
+	CreatedAt     int64            `json:"created_at"`
+	UpdatedAt     int64            `json:"updated_at"`
+}
+
+// ClientOrderStore persists client order ID mappings.
+// Implementations must be safe for concurrent use.
+type ClientOrderStore interface {
+	// Get returns the record for the client order ID, or ErrClientOrderNotFound
+	Get(clientOrderID string) (*ClientOrderRecord, error)
+	// GetByOrderID returns the record for the server OrderID, or ErrClientOrderNotFound
+	GetByOrderID(orderID string) (*ClientOrderRecord, error)
+	// Put creates or replaces a record
+	Put(record *ClientOrderRecord) error
+	// Delete removes a record
+	Delete(clientOrderID string) error
+	// List returns all records
+	List() ([]ClientOrderRecord, error)
+}
+
+// MemoryClientOrderStore keeps client order ID mappings in memory.
+// Mappings are lost when the process exits; use FileClientOrderStore to survive crashes.
+type MemoryClientOrderStore struct {
+	mu      sync.RWMutex
+	records clientOrderRecords
+}
+
+// NewMemoryClientOrderStore creates an empty in-memory store.
+func NewMemoryClientOrderStore() *MemoryClientOrderStore {
+	return &MemoryClientOrderStore{
+		records: newClientOrderRecords(),
+	}
+}
+
+// Get returns the record for the client order ID.
+func (s *MemoryClientOrderStore) Get(clientOrderID string) (*ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.get(clientOrderID)
+}
+
+// GetByOrderID returns the record for the server OrderID.
+func (s *MemoryClientOrderStore) GetByOrderID(orderID string) (*ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.getByOrderID(orderID)
+}
+
+// Put creates or replaces a record.
+func (s *MemoryClientOrderStore) Put(record *ClientOrderRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records.put(*record)
+	return nil
+}
+
+// Delete removes a record.
+func (s *MemoryClientOrderStore) Delete(clientOrderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records.remove(clientOrderID)
+	return nil
+}
+
+// List returns all records sorted by creation time.
+func (s *MemoryClientOrderStore) List() ([]ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.sorted(), nil
+}
+
+// FileClientOrderStore keeps client order ID mappings in a JSON file, rewritten atomically on every change.
+type FileClientOrderStore struct {
+	mu      sync.RWMutex
+	path    string
+	records clientOrderRecords
+}
+
+// NewFileClientOrderStore opens (and creates if needed) a file-backed store.
+//
+// Parameters:
+//   - path: The JSON file holding the mappings
+//
+// Returns:
+//   - *FileClientOrderStore: The opened store
+//   - error: nil on success, error if the file exists but cannot be read
+func NewFileClientOrderStore(path string) (*FileClientOrderStore, error) {
+	store := &FileClientOrderStore{
+		path:    path,
+		records: newClientOrderRecords(),
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var records []ClientOrderRecord
+	err = json.Unmarshal(content, &records)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client order store %s: %w", path, err)
+	}
+	for _, record := range records {
+		store.records.put(record)
+	}
+	return store, nil
+}
+
+// Get returns the record for the client order ID.
+func (s *FileClientOrderStore) Get(clientOrderID string) (*ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.get(clientOrderID)
+}
+
+// GetByOrderID returns the record for the server OrderID.
+func (s *FileClientOrderStore) GetByOrderID(orderID string) (*ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.getByOrderID(orderID)
+}
+
+// Put creates or replaces a record and writes the file.
+func (s *FileClientOrderStore) Put(record *ClientOrderRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.records.put(*record)
+	if err := s.flush(); err != nil {
+		if existed {
+			s.records.put(previous)
+		} else {
+			s.records.remove(record.ClientOrderID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete removes a record and writes the file.
+func (s *FileClientOrderStore) Delete(clientOrderID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, existed := s.records.remove(clientOrderID)
+	if !existed {
+		return nil
+	}
+	if err := s.flush(); err != nil {
+		s.records.put(previous)
+		return err
+	}
+	return nil
+}
+
+// List returns all records sorted by creation time.
+func (s *FileClientOrderStore) List() ([]ClientOrderRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.records.sorted(), nil
+}
+
+// flush writes all records to the store file. The caller must hold s.mu.
+func (s *FileClientOrderStore) flush() error {
+	content, err := json.MarshalIndent(s.records.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, content, 0o600)
+}
+
+// clientOrderRecords holds client order records keyed by client order ID and indexed by server OrderID.
+// It is not safe for concurrent use; the stores guard it with their own lock.
+type clientOrderRecords struct {
+	byClientID map[string]ClientOrderRecord
+	// byOrderID maps server OrderIDs to client order IDs
+	byOrderID map[string]string
+}
+
+func newClientOrderRecords() clientOrderRecords {
+	return clientOrderRecords{
+		byClientID: make(map[string]ClientOrderRecord),
+		byOrderID:  make(map[string]string),
+	}
+}
+
+// get returns the record for the client order ID, or ErrClientOrderNotFound.
+func (r clientOrderRecords) get(clientOrderID string) (*ClientOrderRecord, error) {
+	record, ok := r.byClientID[clientOrderID]
+	if !ok {
+		return nil, ErrClientOrderNotFound
+	}
+	return &record, nil
+}
+
+// getByOrderID returns the record for the server OrderID, or ErrClientOrderNotFound.
+func (r clientOrderRecords) getByOrderID(orderID string) (*ClientOrderRecord, error) {
+	clientOrderID, ok := r.byOrderID[orderID]
+	if !ok {
+		return nil, ErrClientOrderNotFound
+	}
+	return r.get(clientOrderID)
+}
+
+// put creates or replaces a record and returns the record it replaced.
+func (r clientOrderRecords) put(record ClientOrderRecord) (ClientOrderRecord, bool) {
+	previous, existed := r.remove(record.ClientOrderID)
+	r.byClientID[record.ClientOrderID] = record
+	if record.OrderID != "" {
+		r.byOrderID[record.OrderID] = record.ClientOrderID
+	}
+	return previous, existed
+}
+
+// remove deletes a record and returns it.
+func (r clientOrderRecords) remove(clientOrderID string) (ClientOrderRecord, bool) {
+	previous, existed := r.byClientID[clientOrderID]
+	if !existed {
+		return previous, false
+	}
+	delete(r.byClientID, clientOrderID)
+	if r.byOrderID[previous.OrderID] == clientOrderID {
+		delete(r.byOrderID, previous.OrderID)
+	}
+	return previous, true
+}
+
+// sorted returns the records ordered by creation time and client order ID.
+func (r clientOrderRecords) sorted() []ClientOrderRecord {
+	list := make([]ClientOrderRecord, 0, len(r.byClientID))
+	for _, record := range r.byClientID {
+		list = append(list, record)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ClientOrderID < list[j].ClientOrderID
+	})
+	return list
+}
+
+// writeFileAtomic writes content to a temporary file and renames it over path,
+// so readers never observe a partially written file.
+func writeFileAtomic(path string, content []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// clientOrderLocks serializes operations on the same client order ID.
+type clientOrderLocks struct {
+	mu       sync.Mutex
+	inflight map[string]chan struct{}
+}
+
+// lock blocks until no other operation on the client order ID is in flight and returns the unlock function.
+func (l *clientOrderLocks) lock(clientOrderID string) func() {
+	for {
+		l.mu.Lock()
+		if l.inflight == nil {
+			l.inflight = make(map[string]chan struct{})
+		}
+		wait, busy := l.inflight[clientOrderID]
+		if !busy {
+			done := make(chan struct{})
+			l.inflight[clientOrderID] = done
+			l.mu.Unlock()
+			return func() {
+				l.mu.Lock()
+				delete(l.inflight, clientOrderID)
+				l.mu.Unlock()
+				close(done)
+			}
+		}
+		l.mu.Unlock()
+		<-wait
+	}
+}
+
+// PostOrderWithClientID places an order identified by a caller-supplied client order ID.
+// The mapping from client order ID to server OrderID is recorded in the client order store
+// as soon as the order is built, so a retry with the same client order ID after a crash or
+// timeout returns the existing order instead of placing a second one. The order is placed
+// again only when the server confirms that the previous attempt does not exist or failed;
+// if the previous order is still building or cannot be looked up, an error is returned.
+// Concurrent calls with the same client order ID are serialized.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - clientOrderID: The caller-supplied unique ID of the order
+//   - data: Order details including symbol, side, type, quantity, and optional price
+//
+// Returns:
+//   - *SubmitPlaceOrderTransactionResponse: The placed order, or the existing order for a duplicate
+//   - error: nil on success, error on failure
+func (d *DeltaDeFi) PostOrderWithClientID(clientOrderID string, data *BuildPlaceOrderTransactionRequest) (*SubmitPlaceOrderTransactionResponse, error) {
+	if clientOrderID == "" {
+		return nil, fmt.Errorf("client order ID is required")
+	}
+	operationWallet := d.GetOperationWallet()
+	if operationWallet == nil {
+		return nil, fmt.Errorf("operation wallet is not loaded")
+	}
+
+	if d.dryRun {
+		return nil, dryRunError(d.DryRunPostOrder(data))
+	}
+
+	unlock := d.clientOrderLocks.lock(clientOrderID)
+	defer unlock()
+
+	record, err := d.clientOrders.Get(clientOrderID)
+	if err != nil && !errors.Is(err, ErrClientOrderNotFound) {
+		return nil, err
+	}
+	if record != nil && record.OrderID != "" {
+		existing, err := d.fetchOrder(record.OrderID)
+		switch {
+		case errors.Is(err, ErrOrderNotFound) && record.State == ClientOrderStateSubmitted:
+			return nil, fmt.Errorf("client order %s was submitted as %s but the server does not know it: %w", clientOrderID, record.OrderID, err)
+		case errors.Is(err, ErrOrderNotFound):
+			// The previous attempt never reached the server; place the order again
+		case err != nil:
+			return nil, fmt.Errorf("client order %s (%s) cannot be verified: %w", clientOrderID, record.OrderID, err)
+		case existing.OrderStatus() == OrderStatusFailed:
+			// The previous attempt failed; place the order again
+		case existing.OrderStatus() == OrderStatusBuilding:
+			return nil, fmt.Errorf("client order %s (%s) is still building; retry once it is submitted or failed", clientOrderID, record.OrderID)
+		default:
+			if record.State != ClientOrderStateSubmitted {
+				record.State = ClientOrderStateSubmitted
+				record.UpdatedAt = time.Now().Unix()
+				if err := d.clientOrders.Put(record); err != nil {
+					return nil, err
+				}
+			}
+			return &SubmitPlaceOrderTransactionResponse{Order: *existing}, nil
+		}
+	}
+
+	submitRes, err := d.placeOrderWithHook(operationWallet, data, func(prepared *BuildPlaceOrderTransactionRequest, orderID string) error {
+		now := time.Now().Unix()
+		record = &ClientOrderRecord{
+			ClientOrderID: clientOrderID,
+			OrderID:       orderID,
+			State:         ClientOrderStateBuilt,
+			Symbol:        prepared.Symbol,
+			Side:          prepared.Side,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := d.clientOrders.Put(record); err != nil {
+			return fmt.Errorf("recording client order: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record.State = ClientOrderStateSubmitted
+	record.UpdatedAt = time.Now().Unix()
+	if err := d.clientOrders.Put(record); err != nil {
+		return submitRes, fmt.Errorf("order placed but client order record not updated: %w", err)
+	}
+	return submitRes, nil
+}
+
+// ClientOrder returns the recorded mapping for a client order ID.
+//
+// Parameters:
+//   - clientOrderID: The caller-supplied order ID
+//
+// Returns:
+//   - *ClientOrderRecord: The mapping to the server OrderID
+//   - error: ErrClientOrderNotFound if the client order ID is unknown, other error on failure
+func (d *DeltaDeFi) ClientOrder(clientOrderID string) (*ClientOrderRecord, error) {
+	return d.clientOrders.Get(clientOrderID)
+}
+
+// ClientOrderIDOf returns the client order ID recorded for a server OrderID.
+//
+// Parameters:
+//   - orderId: The server order ID
+//
+// Returns:
+//   - string: The client order ID, empty if the order was not placed with one
+//   - error: nil on success, error on failure
+func (d *DeltaDeFi) ClientOrderIDOf(orderId string) (string, error) {
+	record, err := d.clientOrders.GetByOrderID(orderId)
+	if errors.Is(err, ErrClientOrderNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return record.ClientOrderID, nil
+}
@@ -0,0 +1,205 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestPostOrderWithClientID(t *testing.T) {
+	tests := []struct {
+		name string
+		// record is the stored mapping from a previous attempt, nil for a new client order ID
+		record *ClientOrderRecord
+		// previous is the server state of the previous order, nil if the server does not know it
+		previous *OrderJSON
+		// lookupStatus overrides the status of the order lookup
+		lookupStatus int
+		wantPlaced   bool
+		wantExisting bool
+		wantErr      bool
+	}{
+		{name: "new client order", wantPlaced: true},
+		{
+			name:         "submitted order is returned",
+			record:       &ClientOrderRecord{State: ClientOrderStateSubmitted},
+			previous:     &OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			wantExisting: true,
+		},
+		{
+			name:         "built order that reached the server is returned",
+			record:       &ClientOrderRecord{State: ClientOrderStateBuilt},
+			previous:     &OrderJSON{Status: "processing", OrigQty: "10", ExecutedQty: "0"},
+			wantExisting: true,
+		},
+		{
+			name:       "failed order is placed again",
+			record:     &ClientOrderRecord{State: ClientOrderStateBuilt},
+			previous:   &OrderJSON{Status: "failed", OrigQty: "10", ExecutedQty: "0"},
+			wantPlaced: true,
+		},
+		{
+			name:       "unknown built order is placed again",
+			record:     &ClientOrderRecord{State: ClientOrderStateBuilt},
+			wantPlaced: true,
+		},
+		{
+			name:     "building order is not placed again",
+			record:   &ClientOrderRecord{State: ClientOrderStateBuilt},
+			previous: &OrderJSON{Status: "building", OrigQty: "10", ExecutedQty: "0"},
+			wantErr:  true,
+		},
+		{
+			name:         "lookup failure is not treated as missing",
+			record:       &ClientOrderRecord{State: ClientOrderStateBuilt},
+			previous:     &OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			lookupStatus: http.StatusInternalServerError,
+			wantErr:      true,
+		},
+		{
+			name:    "unknown submitted order",
+			record:  &ClientOrderRecord{State: ClientOrderStateSubmitted},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			if tt.record != nil {
+				tt.record.ClientOrderID = "client-1"
+				tt.record.OrderID = "previous"
+				if err := d.clientOrders.Put(tt.record); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.previous != nil {
+				tt.previous.OrderID = "previous"
+				exchange.addOrder(*tt.previous)
+			}
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+
+			res, err := d.PostOrderWithClientID("client-1", &BuildPlaceOrderTransactionRequest{
+				Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PostOrderWithClientID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			placed := exchange.placed.Load()
+			if tt.wantPlaced != (placed == 1) {
+				t.Errorf("placed %d orders, want placed %v", placed, tt.wantPlaced)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			record, err := d.ClientOrder("client-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.State != ClientOrderStateSubmitted || record.OrderID != res.Order.OrderID {
+				t.Errorf("record = %+v, want submitted as %s", record, res.Order.OrderID)
+			}
+			if tt.wantExisting && res.Order.OrderID != "previous" {
+				t.Errorf("returned order %s, want the previous order", res.Order.OrderID)
+			}
+			if id, err := d.ClientOrderIDOf(res.Order.OrderID); err != nil || id != "client-1" {
+				t.Errorf("ClientOrderIDOf(%s) = %q, %v, want client-1", res.Order.OrderID, id, err)
+			}
+			if tt.wantPlaced && tt.record != nil {
+				// The replaced attempt no longer maps to the client order ID
+				if id, err := d.ClientOrderIDOf("previous"); err != nil || id != "" {
+					t.Errorf("ClientOrderIDOf(previous) = %q, %v, want none", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchOrderNotFound(t *testing.T) {
+	tests := []struct {
+		name         string
+		order        *OrderJSON
+		lookupStatus int
+		wantNotFound bool
+		wantErr      bool
+	}{
+		{name: "found", order: &OrderJSON{OrderID: "order", Status: "open"}},
+		{name: "404", wantNotFound: true, wantErr: true},
+		{name: "empty order", order: &OrderJSON{}, wantNotFound: true, wantErr: true},
+		{name: "server error", order: &OrderJSON{OrderID: "order"}, lookupStatus: http.StatusBadGateway, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			if tt.order != nil {
+				// Stored under the requested ID even when the returned record is empty
+				order := *tt.order
+				exchange.orders["order"] = &order
+			}
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+
+			_, err := d.fetchOrder("order")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetchOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrOrderNotFound) != tt.wantNotFound {
+				t.Errorf("fetchOrder() error = %v, want not found %v", err, tt.wantNotFound)
+			}
+			var apiErr *APIError
+			if tt.lookupStatus != 0 && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.lookupStatus) {
+				t.Errorf("fetchOrder() error = %v, want *APIError with status %d", err, tt.lookupStatus)
+			}
+		})
+	}
+}
+
+func TestFileClientOrderStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client-orders.json")
+	store, err := NewFileClientOrderStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []ClientOrderRecord{
+		{ClientOrderID: "b", OrderID: "order-b", State: ClientOrderStateBuilt, CreatedAt: 2},
+		{ClientOrderID: "a", OrderID: "order-a", State: ClientOrderStateSubmitted, CreatedAt: 1},
+	}
+	for i := range records {
+		if err := store.Put(&records[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reopened, err := NewFileClientOrderStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := reopened.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ClientOrderID != "a" || list[1].ClientOrderID != "b" {
+		t.Errorf("List() = %+v, want a, b", list)
+	}
+	if record, err := reopened.GetByOrderID("order-a"); err != nil || record.ClientOrderID != "a" {
+		t.Errorf("GetByOrderID() = %+v, %v, want a", record, err)
+	}
+
+	if err := reopened.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reopened.Get("a"); !errors.Is(err, ErrClientOrderNotFound) {
+		t.Errorf("Get() after Delete error = %v", err)
+	}
+	if _, err := reopened.GetByOrderID("order-a"); !errors.Is(err, ErrClientOrderNotFound) {
+		t.Errorf("GetByOrderID() after Delete error = %v", err)
+	}
+	if record, err := reopened.Get("b"); err != nil || record.OrderID != "order-b" {
+		t.Errorf("Get() = %+v, %v", record, err)
+	}
+}
@@ -10,6 +10,24 @@ type ApiConfig struct {
 	OperationPasscode string
 	// ProvidedBaseUrl allows overriding the default API base URL (optional)
 	ProvidedBaseUrl string
+	// ApiKeyProvider resolves the API key on first use when ApiKey is empty (optional)
+	ApiKeyProvider SecretProvider
+	// OperationPasscodeProvider resolves the operation passcode when OperationPasscode is empty (optional)
+	OperationPasscodeProvider SecretProvider
+	// Keystore caches the encrypted operation key locally so LoadOperationKey works while the API is unavailable (optional)
+	Keystore *Keystore
+	// KeystoreID selects the keystore entry for this account (defaults to DefaultKeystoreID)
+	KeystoreID string
+	// ClientOrderStore records client order ID mappings (defaults to an in-memory store)
+	ClientOrderStore ClientOrderStore
+	// RiskLimits configures the pre-trade risk checks applied to every order (optional, checks are disabled by default)
+	RiskLimits *RiskLimits
+	// DryRun makes transactional methods validate and build transactions but never sign or submit them;
+	// they return a *DryRunError describing what would have happened. Building an order still creates
+	// an inactive order record on the exchange (optional)
+	DryRun bool
+	// AutoSlippage attaches a calibrated slippage bound to market orders placed without MaxSlippageBasisPoint (optional)
+	AutoSlippage *SlippageCalibration
 }
 
 // ApiNetwork represents the different network environments available.
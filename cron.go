@@ -0,0 +1,185 @@
+package deltadefi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression with the standard five fields:
+// minute, hour, day of month, month and day of week.
+type CronSchedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	anyDom bool
+	anyDow bool
+}
+
+// cronDescriptors maps the supported shorthand expressions to their five-field form.
+var cronDescriptors = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseCronSchedule parses a cron expression. Fields accept *, single values, ranges (1-5),
+// lists (1,3,5) and steps (*/15, 0-30/5). Days of week run from 0 (Sunday) to 6, with 7 also
+// meaning Sunday. The shorthands @hourly, @daily, @weekly, @monthly and @yearly are supported.
+// As in standard cron, when both day of month and day of week are restricted, a day matching
+// either runs; a day field starting with * (such as */2) does not count as restricted.
+//
+// Parameters:
+//   - expr: The cron expression
+//
+// Returns:
+//   - *CronSchedule: The parsed schedule
+//   - error: nil on success, error if the expression is invalid
+func ParseCronSchedule(expr string) (*CronSchedule, error) {
+	spec := strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[spec]; ok {
+		spec = descriptor
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	s := &CronSchedule{expr: expr}
+	var err error
+	if s.minute, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cron minute: %w", err)
+	}
+	if s.hour, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cron hour: %w", err)
+	}
+	if s.dom, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cron day of month: %w", err)
+	}
+	if s.month, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cron month: %w", err)
+	}
+	if s.dow, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cron day of week: %w", err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.anyDom = strings.HasPrefix(fields[2], "*")
+	s.anyDow = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t matching the schedule, in t's location,
+// or the zero time if none exists within five years.
+// Schedules follow the wall clock of the location: times skipped when clocks go forward do not
+// run, and times repeated when clocks go back run once, at their first occurrence.
+//
+// Parameters:
+//   - t: The reference time
+//
+// Returns:
+//   - time.Time: The next matching time
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	// Candidates are searched in wall-clock time, represented in UTC where no transitions occur
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC).Add(time.Minute)
+	limit := wall.AddDate(5, 0, 0)
+
+	for wall.Before(limit) {
+		if s.month&(1<<uint(wall.Month())) == 0 {
+			wall = time.Date(wall.Year(), wall.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if !s.dayMatches(wall) {
+			wall = time.Date(wall.Year(), wall.Month(), wall.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if s.hour&(1<<uint(wall.Hour())) == 0 {
+			wall = wall.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+		if s.minute&(1<<uint(wall.Minute())) == 0 {
+			wall = wall.Add(time.Minute)
+			continue
+		}
+
+		next := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+		// Date normalizes a skipped time to another wall clock, and maps a repeated time to its
+		// first occurrence, which may lie before t
+		sameWall := next.Hour() == wall.Hour() && next.Minute() == wall.Minute()
+		if sameWall && next.After(t) {
+			return next
+		}
+		wall = wall.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron day of month / day of week rule.
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.anyDom || s.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// parseCronField parses one field into a bit set of allowed values.
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = part[:i]
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", rangePart)
+			}
+			low, high = value, value
+			if strings.Contains(part, "/") {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q out of range %d-%d", rangePart, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,133 @@
+package deltadefi
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "every minute", expr: "* * * * *"},
+		{name: "lists, ranges and steps", expr: "0,30 9-17/2 1-15 */3 1-5"},
+		{name: "sunday as 7", expr: "0 0 * * 7"},
+		{name: "descriptor", expr: "@weekly"},
+		{name: "surrounding space", expr: "  @daily "},
+		{name: "too few fields", expr: "* * * *", wantErr: true},
+		{name: "too many fields", expr: "* * * * * *", wantErr: true},
+		{name: "minute out of range", expr: "60 * * * *", wantErr: true},
+		{name: "day of month zero", expr: "0 0 0 * *", wantErr: true},
+		{name: "month out of range", expr: "0 0 1 13 *", wantErr: true},
+		{name: "day of week out of range", expr: "0 0 * * 8", wantErr: true},
+		{name: "reversed range", expr: "0 10-5 * * *", wantErr: true},
+		{name: "zero step", expr: "*/0 * * * *", wantErr: true},
+		{name: "invalid value", expr: "a * * * *", wantErr: true},
+		{name: "unknown descriptor", expr: "@often", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCronSchedule(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCronSchedule(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if err == nil && s.String() != tt.expr {
+				t.Errorf("String() = %q, want %q", s.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	ny := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, newYork)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "step", expr: "*/15 * * * *", from: utc(10, 1, 10, 7), want: utc(10, 1, 10, 15)},
+		{name: "strictly after", expr: "*/15 * * * *", from: utc(10, 1, 10, 15), want: utc(10, 1, 10, 30)},
+		{name: "seconds are truncated", expr: "* * * * *", from: utc(10, 1, 10, 7).Add(30 * time.Second), want: utc(10, 1, 10, 8)},
+		{name: "weekdays skip the weekend", expr: "0 9 * * 1-5", from: utc(10, 16, 10, 0), want: utc(10, 19, 9, 0)},
+		{name: "sunday as 7", expr: "0 0 * * 7", from: utc(10, 19, 0, 0), want: utc(10, 25, 0, 0)},
+		{name: "month rollover", expr: "@monthly", from: utc(10, 19, 0, 0), want: utc(11, 1, 0, 0)},
+		{name: "year rollover", expr: "@yearly", from: utc(10, 19, 0, 0), want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "restricted days match either", expr: "0 0 1 * 1", from: utc(10, 2, 0, 0), want: utc(10, 5, 0, 0)},
+		{name: "stepped day of month is not restricted", expr: "0 0 */2 * 1", from: utc(10, 1, 0, 0), want: utc(10, 5, 0, 0)},
+		{name: "stepped day of week is not restricted", expr: "0 0 5 * */2", from: utc(10, 1, 0, 0), want: utc(11, 5, 0, 0)},
+		{name: "impossible date", expr: "0 0 31 2 *", from: utc(10, 1, 0, 0), want: time.Time{}},
+		{name: "keeps the location", expr: "0 9 * * *", from: ny(10, 19, 10, 0), want: ny(10, 20, 9, 0)},
+		{name: "skipped time does not run", expr: "30 2 * * *", from: ny(3, 7, 12, 0), want: ny(3, 9, 2, 30)},
+		{name: "skipped hour moves to the next valid time", expr: "*/30 * * * *", from: ny(3, 8, 1, 45), want: ny(3, 8, 3, 0)},
+		{name: "repeated time runs at its first occurrence", expr: "30 1 * * *", from: ny(11, 1, 0, 0), want: ny(11, 1, 1, 30)},
+		{name: "repeated time runs once", expr: "30 1 * * *", from: ny(11, 1, 1, 30), want: ny(11, 2, 1, 30)},
+		{name: "repeated hour runs once", expr: "*/30 * * * *", from: ny(11, 1, 1, 45), want: ny(11, 1, 2, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseCronSchedule(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := s.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+			if !got.IsZero() && got.Location() != tt.from.Location() {
+				t.Errorf("Next() location = %v, want %v", got.Location(), tt.from.Location())
+			}
+		})
+	}
+}
+
+func TestCronScheduleNextAcrossDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := ParseCronSchedule("*/30 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Successive runs always move forward and never repeat a wall-clock time
+	tests := []struct {
+		name string
+		from time.Time
+	}{
+		{name: "spring forward", from: time.Date(2026, 3, 7, 22, 0, 0, 0, newYork)},
+		{name: "fall back", from: time.Date(2026, 10, 31, 22, 0, 0, 0, newYork)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen := make(map[string]bool)
+			current := tt.from
+			for i := 0; i < 20; i++ {
+				next := s.Next(current)
+				if !next.After(current) {
+					t.Fatalf("Next(%v) = %v, not after the reference", current, next)
+				}
+				wall := next.Format("2006-01-02 15:04")
+				if seen[wall] {
+					t.Fatalf("wall-clock time %s ran twice", wall)
+				}
+				seen[wall] = true
+				current = next
+			}
+		})
+	}
+}
@@ -0,0 +1,807 @@
+package deltadefi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultDCACheckInterval is how often DCA jobs are checked for due runs
+	DefaultDCACheckInterval = 30 * time.Second
+	// DefaultDCASettleTimeout bounds the wait for a DCA order to complete
+	DefaultDCASettleTimeout = time.Minute
+	// DefaultDCAHistoryLimit is how many executions the history keeps
+	DefaultDCAHistoryLimit = 1000
+)
+
+// ErrDCAJobNotFound is returned when no DCA job exists for an ID.
+var ErrDCAJobNotFound = errors.New("DCA job not found")
+
+// DCAAmountType selects how the size of each DCA order is determined.
+type DCAAmountType string
+
+const (
+	// DCAFixedBase trades Amount of the base asset every run
+	DCAFixedBase DCAAmountType = "fixed_base"
+	// DCAFixedQuote trades Amount worth of quote currency every run
+	DCAFixedQuote DCAAmountType = "fixed_quote"
+	// DCAValueAveraging buys whatever brings the value of the base acquired by the job to
+	// Amount times the number of periods so far, buying more when the price fell and less (or
+	// nothing) when it rose
+	DCAValueAveraging DCAAmountType = "value_averaging"
+)
+
+// DCAAmountRule determines the size of each DCA order.
+type DCAAmountRule struct {
+	Type DCAAmountType `json:"type"`
+	// Amount is the base quantity (DCAFixedBase) or quote amount (DCAFixedQuote, DCAValueAveraging) per period
+	Amount float64 `json:"amount"`
+	// MaxQuoteAmount caps the quote amount of a single value averaging order (optional)
+	MaxQuoteAmount float64 `json:"max_quote_amount,omitempty"`
+}
+
+// DCAJob is a recurring market order.
+type DCAJob struct {
+	ID string `json:"id"`
+	// Schedule is a cron expression (see ParseCronSchedule), evaluated in the scheduler's location
+	Schedule string        `json:"schedule"`
+	Symbol   Symbol        `json:"symbol"`
+	Side     OrderSide     `json:"side"`
+	Rule     DCAAmountRule `json:"rule"`
+	// MaxPrice skips buy runs while the market price is above it (optional)
+	MaxPrice *float64 `json:"max_price,omitempty"`
+	// MinPrice skips sell runs while the market price is below it (optional)
+	MinPrice *float64 `json:"min_price,omitempty"`
+	// CheckBalance skips runs the free balance cannot pay for
+	CheckBalance bool `json:"check_balance,omitempty"`
+	// MaxSlippageBasisPoint bounds the slippage of each order (optional)
+	MaxSlippageBasisPoint int `json:"max_slippage_basis_point,omitempty"`
+	// LotSize is the base quantity step; quantities are rounded down to a multiple of it (optional)
+	LotSize float64 `json:"lot_size,omitempty"`
+	Paused  bool    `json:"paused,omitempty"`
+	// NextRun is when the job runs next
+	NextRun time.Time `json:"next_run"`
+	// Periods counts the due runs so far, including skipped ones
+	Periods int `json:"periods"`
+	// TotalBase and TotalQuote are the base quantity and quote amount traded by the job
+	TotalBase  float64 `json:"total_base"`
+	TotalQuote float64 `json:"total_quote"`
+	// PendingOrders are the orders of pending runs, whose fills are not in the totals yet
+	PendingOrders []string  `json:"pending_orders,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// DCAExecutionStatus is the outcome of a DCA run.
+type DCAExecutionStatus string
+
+const (
+	DCAExecuted DCAExecutionStatus = "executed"
+	DCASkipped  DCAExecutionStatus = "skipped"
+	DCAFailed   DCAExecutionStatus = "failed"
+	// DCAPending is a run whose order did not complete within the settle timeout. The entry is
+	// updated to executed or failed once a later check finds the order terminal.
+	DCAPending DCAExecutionStatus = "pending"
+)
+
+// DCAExecution is an entry of the DCA history log.
+type DCAExecution struct {
+	JobID  string             `json:"job_id"`
+	Time   time.Time          `json:"time"`
+	Status DCAExecutionStatus `json:"status"`
+	// Reason explains a skipped or failed run
+	Reason string `json:"reason,omitempty"`
+	// MarketPrice is the market price when the run started
+	MarketPrice float64 `json:"market_price,omitempty"`
+	OrderID     string  `json:"order_id,omitempty"`
+	// Quantity and QuoteAmount are what the order actually traded
+	Quantity    float64 `json:"quantity,omitempty"`
+	QuoteAmount float64 `json:"quote_amount,omitempty"`
+}
+
+// DCAState is the persisted state of a DCA scheduler.
+type DCAState struct {
+	Jobs    []DCAJob       `json:"jobs"`
+	History []DCAExecution `json:"history"`
+}
+
+// DCAStore persists DCA jobs and their history.
+type DCAStore interface {
+	// Load returns the persisted state, or nil if none exists
+	Load() (*DCAState, error)
+	// Save replaces the persisted state
+	Save(state *DCAState) error
+}
+
+// FileDCAStore persists DCA state in a JSON file, rewritten atomically on every change.
+type FileDCAStore struct {
+	path string
+}
+
+// NewFileDCAStore creates a store backed by the given JSON file.
+func NewFileDCAStore(path string) *FileDCAStore {
+	return &FileDCAStore{path: path}
+}
+
+// Load reads the state from the file. A missing file yields nil.
+func (s *FileDCAStore) Load() (*DCAState, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state DCAState
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DCA store %s: %w", s.path, err)
+	}
+	return &state, nil
+}
+
+// Save writes the state to the file.
+func (s *FileDCAStore) Save(state *DCAState) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, content, 0o600)
+}
+
+// DCASchedulerOptions configures a DCAScheduler.
+type DCASchedulerOptions struct {
+	// Store persists jobs and history across restarts (optional)
+	Store DCAStore
+	// CheckInterval is how often jobs are checked for due runs (defaults to DefaultDCACheckInterval)
+	CheckInterval time.Duration
+	// SettleTimeout bounds the wait for each order to complete (defaults to DefaultDCASettleTimeout)
+	SettleTimeout time.Duration
+	// HistoryLimit is how many executions the history keeps (defaults to DefaultDCAHistoryLimit)
+	HistoryLimit int
+	// Location is the time zone of the cron schedules (defaults to UTC)
+	Location *time.Location
+	// OnExecution is called after every run, and again when a pending run completes (optional)
+	OnExecution func(execution DCAExecution)
+	// OnError is called when a periodic check fails (optional)
+	OnError func(err error)
+}
+
+// DCAScheduler runs recurring market orders on cron schedules. A run is recorded as done before
+// its order is placed, so a crash never places the same run twice; runs missed while the
+// scheduler was stopped are executed once on the next check. Orders still working after the
+// settle timeout are recorded as pending and counted once a later check finds them complete.
+// A DCAScheduler is safe for concurrent use.
+type DCAScheduler struct {
+	d    *DeltaDeFi
+	opts DCASchedulerOptions
+
+	mu        sync.Mutex
+	jobs      map[string]*DCAJob
+	schedules map[string]*CronSchedule
+	history   []DCAExecution
+
+	// runLock serializes runs so a slow order does not overlap with the next check
+	runLock sync.Mutex
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewDCAScheduler creates a scheduler and loads the persisted jobs and history.
+//
+// Parameters:
+//   - d: The client used to fetch prices and place orders
+//   - opts: Scheduler options
+//
+// Returns:
+//   - *DCAScheduler: The scheduler, not yet started
+//   - error: nil on success, error if the persisted state cannot be loaded
+func NewDCAScheduler(d *DeltaDeFi, opts DCASchedulerOptions) (*DCAScheduler, error) {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultDCACheckInterval
+	}
+	if opts.SettleTimeout <= 0 {
+		opts.SettleTimeout = DefaultDCASettleTimeout
+	}
+	if opts.HistoryLimit <= 0 {
+		opts.HistoryLimit = DefaultDCAHistoryLimit
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	s := &DCAScheduler{
+		d:         d,
+		opts:      opts,
+		jobs:      make(map[string]*DCAJob),
+		schedules: make(map[string]*CronSchedule),
+	}
+	if opts.Store == nil {
+		return s, nil
+	}
+
+	state, err := opts.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if state != nil {
+		for i := range state.Jobs {
+			job := state.Jobs[i]
+			schedule, err := ParseCronSchedule(job.Schedule)
+			if err != nil {
+				return nil, fmt.Errorf("DCA job %s: %w", job.ID, err)
+			}
+			s.jobs[job.ID] = &job
+			s.schedules[job.ID] = schedule
+		}
+		s.history = state.History
+	}
+	return s, nil
+}
+
+// AddJob validates and schedules a new job. ID, NextRun, CreatedAt and the totals are assigned by the scheduler.
+//
+// Parameters:
+//   - job: The job definition
+//
+// Returns:
+//   - DCAJob: The scheduled job
+//   - error: nil on success, error if the job is invalid or cannot be persisted
+func (s *DCAScheduler) AddJob(job DCAJob) (DCAJob, error) {
+	schedule, err := ParseCronSchedule(job.Schedule)
+	if err != nil {
+		return DCAJob{}, err
+	}
+	if job.Side == "" {
+		job.Side = OrderSideBuy
+	}
+	if job.Side != OrderSideBuy && job.Side != OrderSideSell {
+		return DCAJob{}, fmt.Errorf("invalid DCA side %q", job.Side)
+	}
+	switch job.Rule.Type {
+	case DCAFixedBase, DCAFixedQuote, DCAValueAveraging:
+	default:
+		return DCAJob{}, fmt.Errorf("unknown DCA amount type %q", job.Rule.Type)
+	}
+	if job.Rule.Amount <= 0 {
+		return DCAJob{}, fmt.Errorf("DCA amount must be positive")
+	}
+	if job.Rule.Type == DCAValueAveraging && job.Side != OrderSideBuy {
+		return DCAJob{}, fmt.Errorf("value averaging applies to buy jobs only")
+	}
+
+	job.ID, err = newRandomID()
+	if err != nil {
+		return DCAJob{}, err
+	}
+	now := time.Now().In(s.opts.Location)
+	job.CreatedAt = now
+	job.NextRun = schedule.Next(now)
+	if job.NextRun.IsZero() {
+		return DCAJob{}, fmt.Errorf("schedule %q never runs", job.Schedule)
+	}
+	job.Periods, job.TotalBase, job.TotalQuote = 0, 0, 0
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.ID] = &job
+	s.schedules[job.ID] = schedule
+	if err := s.persist(); err != nil {
+		delete(s.jobs, job.ID)
+		delete(s.schedules, job.ID)
+		return DCAJob{}, err
+	}
+	return job, nil
+}
+
+// RemoveJob deletes a job. Its history is kept.
+//
+// Parameters:
+//   - id: The job ID
+//
+// Returns:
+//   - error: ErrDCAJobNotFound if the ID is unknown, other error if the change cannot be persisted
+func (s *DCAScheduler) RemoveJob(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrDCAJobNotFound
+	}
+	schedule := s.schedules[id]
+	delete(s.jobs, id)
+	delete(s.schedules, id)
+	if err := s.persist(); err != nil {
+		s.jobs[id] = job
+		s.schedules[id] = schedule
+		return err
+	}
+	return nil
+}
+
+// PauseJob stops a job from running until ResumeJob is called.
+//
+// Parameters:
+//   - id: The job ID
+//
+// Returns:
+//   - error: ErrDCAJobNotFound if the ID is unknown, other error if the change cannot be persisted
+func (s *DCAScheduler) PauseJob(id string) error {
+	return s.setPaused(id, true)
+}
+
+// ResumeJob resumes a paused job from its next scheduled time; runs missed while paused are skipped.
+//
+// Parameters:
+//   - id: The job ID
+//
+// Returns:
+//   - error: ErrDCAJobNotFound if the ID is unknown, other error if the change cannot be persisted
+func (s *DCAScheduler) ResumeJob(id string) error {
+	return s.setPaused(id, false)
+}
+
+// Job returns a job.
+//
+// Parameters:
+//   - id: The job ID
+//
+// Returns:
+//   - DCAJob: The job
+//   - error: ErrDCAJobNotFound if the ID is unknown
+func (s *DCAScheduler) Job(id string) (DCAJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return DCAJob{}, ErrDCAJobNotFound
+	}
+	return *job, nil
+}
+
+// Jobs returns all jobs sorted by creation time.
+func (s *DCAScheduler) Jobs() []DCAJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := make([]DCAJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// History returns the executions of a job, oldest first.
+//
+// Parameters:
+//   - jobId: The job ID (empty returns the executions of all jobs)
+//
+// Returns:
+//   - []DCAExecution: The executions
+func (s *DCAScheduler) History(jobId string) []DCAExecution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var history []DCAExecution
+	for _, execution := range s.history {
+		if jobId == "" || execution.JobID == jobId {
+			history = append(history, execution)
+		}
+	}
+	return history
+}
+
+// Start begins running due jobs in a background goroutine. Calling Start on a running scheduler has no effect.
+func (s *DCAScheduler) Start() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.done != nil {
+		return
+	}
+	s.done = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.run(s.done)
+}
+
+// Stop stops the scheduler, waiting for a run in progress to finish.
+func (s *DCAScheduler) Stop() {
+	s.runMu.Lock()
+	if s.done == nil {
+		s.runMu.Unlock()
+		return
+	}
+	close(s.done)
+	s.done = nil
+	s.runMu.Unlock()
+
+	s.wg.Wait()
+}
+
+// RunDue settles pending runs whose order completed, then executes every job whose next run
+// time has passed. It is called periodically while the scheduler runs and can be called directly.
+//
+// Returns:
+//   - error: nil on success, error if pending orders cannot be looked up or settled runs cannot be
+//     persisted, or if the advanced schedule cannot be persisted (nothing is run)
+func (s *DCAScheduler) RunDue() error {
+	s.runLock.Lock()
+	defer s.runLock.Unlock()
+
+	settleErr := s.settlePending()
+	now := time.Now().In(s.opts.Location)
+
+	s.mu.Lock()
+	var due []DCAJob
+	for id, job := range s.jobs {
+		if job.Paused || job.NextRun.After(now) {
+			continue
+		}
+		due = append(due, *job)
+		job.Periods++
+		job.NextRun = s.schedules[id].Next(now)
+	}
+	if len(due) == 0 {
+		s.mu.Unlock()
+		return settleErr
+	}
+	// Record the runs as done before placing any order, so a crash cannot repeat them
+	if err := s.persist(); err != nil {
+		for _, job := range due {
+			current := s.jobs[job.ID]
+			current.Periods = job.Periods
+			current.NextRun = job.NextRun
+		}
+		s.mu.Unlock()
+		return errors.Join(settleErr, err)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].NextRun.Before(due[j].NextRun) })
+	for _, job := range due {
+		job.Periods++
+		execution := s.execute(&job)
+		s.record(execution)
+	}
+	return settleErr
+}
+
+// run calls RunDue periodically until done is closed, passing its errors to OnError.
+func (s *DCAScheduler) run(done chan struct{}) {
+	defer s.wg.Done()
+
+	runDue := func() {
+		if err := s.RunDue(); err != nil && s.opts.OnError != nil {
+			s.opts.OnError(err)
+		}
+	}
+
+	// Catch up on runs missed while stopped
+	runDue()
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			runDue()
+		}
+	}
+}
+
+// settlePending looks up the orders of pending runs and settles the runs whose order is terminal.
+// Runs of removed jobs are settled in the history only.
+func (s *DCAScheduler) settlePending() error {
+	type pendingOrder struct {
+		jobID   string
+		orderID string
+	}
+	s.mu.Lock()
+	seen := make(map[string]bool)
+	var pending []pendingOrder
+	for id, job := range s.jobs {
+		for _, orderID := range job.PendingOrders {
+			seen[orderID] = true
+			pending = append(pending, pendingOrder{jobID: id, orderID: orderID})
+		}
+	}
+	for _, execution := range s.history {
+		if execution.Status == DCAPending && !seen[execution.OrderID] {
+			seen[execution.OrderID] = true
+			pending = append(pending, pendingOrder{jobID: execution.JobID, orderID: execution.OrderID})
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(pending, func(i, j int) bool { return pending[i].orderID < pending[j].orderID })
+
+	var errs []error
+	for _, p := range pending {
+		order, err := s.d.fetchOrder(p.orderID)
+		if errors.Is(err, ErrOrderNotFound) {
+			order, err = nil, nil
+		} else if err != nil {
+			errs = append(errs, fmt.Errorf("DCA job %s: looking up order %s: %w", p.jobID, p.orderID, err))
+			continue
+		} else if !OrderIsTerminal(order) {
+			continue
+		}
+		if err := s.settle(p.jobID, p.orderID, order); err != nil {
+			errs = append(errs, fmt.Errorf("DCA job %s: persisting settled order %s: %w", p.jobID, p.orderID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// settle completes the pending run of an order from the order's terminal state (nil if the
+// server does not know the order): the fills are added to the job totals and the history entry
+// is updated and passed to OnExecution.
+func (s *DCAScheduler) settle(jobID, orderID string, order *OrderJSON) error {
+	s.mu.Lock()
+	execution := DCAExecution{JobID: jobID, Time: time.Now(), OrderID: orderID}
+	index := -1
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if s.history[i].JobID == jobID && s.history[i].OrderID == orderID {
+			index, execution = i, s.history[i]
+			break
+		}
+	}
+	settleExecution(&execution, order)
+	if index >= 0 {
+		s.history[index] = execution
+	}
+	if job, ok := s.jobs[jobID]; ok {
+		for i, id := range job.PendingOrders {
+			if id == orderID {
+				job.PendingOrders = append(job.PendingOrders[:i:i], job.PendingOrders[i+1:]...)
+				break
+			}
+		}
+		if len(job.PendingOrders) == 0 {
+			job.PendingOrders = nil
+		}
+		if execution.Status == DCAExecuted {
+			job.TotalBase += execution.Quantity
+			job.TotalQuote += execution.QuoteAmount
+		}
+	}
+	err := s.persist()
+	s.mu.Unlock()
+
+	if s.opts.OnExecution != nil {
+		s.opts.OnExecution(execution)
+	}
+	return err
+}
+
+// settleExecution completes a run from the terminal state of its order (nil if the server does
+// not know the order).
+func settleExecution(execution *DCAExecution, order *OrderJSON) {
+	if order == nil {
+		execution.Status = DCAFailed
+		execution.Reason = "order not found"
+		return
+	}
+	execution.Quantity, execution.QuoteAmount, _ = executedQuote(order)
+	if order.OrderStatus() == OrderStatusFailed && execution.Quantity <= quantityEpsilon {
+		execution.Status = DCAFailed
+		execution.Reason = "order failed"
+		return
+	}
+	execution.Status = DCAExecuted
+	execution.Reason = ""
+}
+
+// execute performs one run of a job. job.Periods already counts this run.
+func (s *DCAScheduler) execute(job *DCAJob) DCAExecution {
+	execution := DCAExecution{JobID: job.ID, Time: time.Now()}
+	fail := func(err error) DCAExecution {
+		execution.Status = DCAFailed
+		execution.Reason = err.Error()
+		return execution
+	}
+	skip := func(format string, args ...any) DCAExecution {
+		execution.Status = DCASkipped
+		execution.Reason = fmt.Sprintf(format, args...)
+		return execution
+	}
+
+	market, err := s.d.Market.GetMarketPrice(string(job.Symbol))
+	if err != nil {
+		return fail(fmt.Errorf("fetching market price: %w", err))
+	}
+	if market.Price <= 0 {
+		return fail(fmt.Errorf("no market price for %s", job.Symbol))
+	}
+	price := market.Price
+	execution.MarketPrice = price
+
+	if job.Side == OrderSideBuy && job.MaxPrice != nil && price > *job.MaxPrice {
+		return skip("price %g above maximum %g", price, *job.MaxPrice)
+	}
+	if job.Side == OrderSideSell && job.MinPrice != nil && price < *job.MinPrice {
+		return skip("price %g below minimum %g", price, *job.MinPrice)
+	}
+
+	var quantity, quoteAmount float64
+	switch job.Rule.Type {
+	case DCAFixedBase:
+		quantity = job.Rule.Amount
+	case DCAFixedQuote:
+		quoteAmount = job.Rule.Amount
+	case DCAValueAveraging:
+		// The target depends on everything bought so far, so wait for earlier orders to settle
+		if len(job.PendingOrders) > 0 {
+			return skip("waiting for %d pending orders to settle", len(job.PendingOrders))
+		}
+		target := job.Rule.Amount * float64(job.Periods)
+		held := job.TotalBase
+		quoteAmount = target - held*price
+		if job.Rule.MaxQuoteAmount > 0 && quoteAmount > job.Rule.MaxQuoteAmount {
+			quoteAmount = job.Rule.MaxQuoteAmount
+		}
+		if quoteAmount <= quantityEpsilon {
+			return skip("value %g already at target %g", held*price, target)
+		}
+	}
+
+	if quoteAmount > 0 {
+		depth, err := s.d.Market.GetMarketDepth(string(job.Symbol))
+		if err != nil {
+			return fail(fmt.Errorf("fetching market depth: %w", err))
+		}
+		estimate, err := depth.EstimateQuoteOrder(job.Side, quoteAmount, job.MaxSlippageBasisPoint, job.LotSize)
+		if err != nil {
+			return fail(err)
+		}
+		quantity = estimate.Quantity
+	} else if job.LotSize > 0 {
+		quantity = float64(int64(quantity/job.LotSize+quantityEpsilon)) * job.LotSize
+	}
+	if quantity <= quantityEpsilon {
+		return skip("order quantity rounds to zero")
+	}
+
+	if job.CheckBalance {
+		if reason, err := s.balanceShortfall(job, quantity, price); err != nil {
+			return fail(err)
+		} else if reason != "" {
+			return skip("%s", reason)
+		}
+	}
+
+	order := &BuildPlaceOrderTransactionRequest{
+		Symbol:   job.Symbol,
+		Side:     job.Side,
+		Type:     OrderTypeMarket,
+		Quantity: quantity,
+	}
+	if job.MaxSlippageBasisPoint > 0 {
+		order.MaxSlippageBasisPoint = IntPtr(job.MaxSlippageBasisPoint)
+		order.LimitSlippage = BoolPtr(true)
+	}
+	res, err := s.d.PostOrder(order)
+	var dryRun *DryRunError
+	if errors.As(err, &dryRun) {
+		execution.Quantity = quantity
+		return skip("dry run: order for %g built but not submitted", quantity)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	execution.OrderID = res.Order.OrderID
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.SettleTimeout)
+	defer cancel()
+	final, err := s.d.WaitForOrder(ctx, res.Order.OrderID, OrderIsTerminal)
+	if final == nil || !OrderIsTerminal(final) {
+		execution.Status = DCAPending
+		execution.Reason = fmt.Sprintf("order placed, fills not known yet: %v", err)
+		return execution
+	}
+	settleExecution(&execution, final)
+	return execution
+}
+
+// balanceShortfall reports why the free balance cannot pay for a run, or "" if it can.
+func (s *DCAScheduler) balanceShortfall(job *DCAJob, quantity, price float64) (string, error) {
+	assets, err := s.d.risk.limits.Load().assets(job.Symbol)
+	if err != nil {
+		return "", err
+	}
+	balances, err := s.d.Accounts.GetAccountBalance()
+	if err != nil {
+		return "", fmt.Errorf("fetching balances: %w", err)
+	}
+
+	if job.Side == OrderSideBuy {
+		cost := quantity * price
+		if job.MaxSlippageBasisPoint > 0 {
+			cost *= 1 + float64(job.MaxSlippageBasisPoint)/10000
+		}
+		if free := findAssetBalance(*balances, assets.Quote).Free; free < cost {
+			return fmt.Sprintf("insufficient %s balance: %g free, %g needed", assets.Quote, free, cost), nil
+		}
+		return "", nil
+	}
+	if free := findAssetBalance(*balances, assets.Base).Free; free < quantity {
+		return fmt.Sprintf("insufficient %s balance: %g free, %g needed", assets.Base, free, quantity), nil
+	}
+	return "", nil
+}
+
+// record appends an execution to the history, updates the job totals (or pending orders) and persists both.
+func (s *DCAScheduler) record(execution DCAExecution) {
+	s.mu.Lock()
+	if job, ok := s.jobs[execution.JobID]; ok {
+		switch execution.Status {
+		case DCAExecuted:
+			job.TotalBase += execution.Quantity
+			job.TotalQuote += execution.QuoteAmount
+		case DCAPending:
+			job.PendingOrders = append(job.PendingOrders, execution.OrderID)
+		}
+	}
+	s.history = append(s.history, execution)
+	if len(s.history) > s.opts.HistoryLimit {
+		s.history = append([]DCAExecution(nil), s.history[len(s.history)-s.opts.HistoryLimit:]...)
+	}
+	if err := s.persist(); err != nil && execution.Reason == "" {
+		execution.Reason = fmt.Sprintf("history not persisted: %v", err)
+	}
+	s.mu.Unlock()
+
+	if s.opts.OnExecution != nil {
+		s.opts.OnExecution(execution)
+	}
+}
+
+// setPaused changes the paused flag of a job; resuming reschedules it from now.
+func (s *DCAScheduler) setPaused(id string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrDCAJobNotFound
+	}
+	before := *job
+	job.Paused = paused
+	if !paused {
+		job.NextRun = s.schedules[id].Next(time.Now().In(s.opts.Location))
+	}
+	if err := s.persist(); err != nil {
+		*job = before
+		return err
+	}
+	return nil
+}
+
+// persist saves the jobs and history to the store. The caller must hold s.mu.
+func (s *DCAScheduler) persist() error {
+	if s.opts.Store == nil {
+		return nil
+	}
+	state := &DCAState{
+		Jobs:    make([]DCAJob, 0, len(s.jobs)),
+		History: s.history,
+	}
+	for _, job := range s.jobs {
+		state.Jobs = append(state.Jobs, *job)
+	}
+	sort.Slice(state.Jobs, func(i, j int) bool { return state.Jobs[i].CreatedAt.Before(state.Jobs[j].CreatedAt) })
+	return s.opts.Store.Save(state)
+}
@@ -0,0 +1,208 @@
+package deltadefi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// addDueDCAJob adds a job to the scheduler and makes it due immediately.
+func addDueDCAJob(t *testing.T, s *DCAScheduler, job DCAJob) DCAJob {
+	t.Helper()
+	job, err := s.AddJob(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.jobs[job.ID].NextRun = time.Now().Add(-time.Minute)
+	s.mu.Unlock()
+	return job
+}
+
+func TestDCASchedulerSettlesPendingRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		// complete changes the order on the exchange after the run timed out, nil leaves it open
+		complete     func(exchange *mockExchange, id string)
+		lookupStatus int
+		wantStatus   DCAExecutionStatus
+		wantBase     float64
+		wantQuote    float64
+		wantPending  int
+		wantErr      bool
+	}{
+		{
+			name: "filled order is counted",
+			complete: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) {
+					order.Status, order.ExecutedQty, order.ExecutedPrice = "closed", "10", 0.5
+				})
+			},
+			wantStatus: DCAExecuted,
+			wantBase:   10,
+			wantQuote:  5,
+		},
+		{
+			name: "partially filled cancelled order is counted",
+			complete: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) {
+					order.Status, order.ExecutedQty, order.ExecutedPrice = "cancelled", "4", 0.5
+				})
+			},
+			wantStatus: DCAExecuted,
+			wantBase:   4,
+			wantQuote:  2,
+		},
+		{
+			name: "failed order",
+			complete: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) { order.Status = "failed" })
+			},
+			wantStatus: DCAFailed,
+		},
+		{
+			name: "order unknown to the server",
+			complete: func(exchange *mockExchange, id string) {
+				exchange.mu.Lock()
+				delete(exchange.orders, id)
+				exchange.mu.Unlock()
+			},
+			wantStatus: DCAFailed,
+		},
+		{
+			name:        "open order stays pending",
+			wantStatus:  DCAPending,
+			wantPending: 1,
+		},
+		{
+			name:         "lookup failure is reported",
+			lookupStatus: http.StatusBadGateway,
+			wantStatus:   DCAPending,
+			wantPending:  1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.price = 0.5
+			var executions []DCAExecution
+			s, err := NewDCAScheduler(d, DCASchedulerOptions{
+				SettleTimeout: 50 * time.Millisecond,
+				OnExecution:   func(execution DCAExecution) { executions = append(executions, execution) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			job := addDueDCAJob(t, s, DCAJob{
+				Schedule: "@daily",
+				Symbol:   ADAUSDM,
+				Rule:     DCAAmountRule{Type: DCAFixedBase, Amount: 10},
+			})
+
+			if err := s.RunDue(); err != nil {
+				t.Fatal(err)
+			}
+			history := s.History(job.ID)
+			if len(history) != 1 || history[0].Status != DCAPending || history[0].OrderID == "" {
+				t.Fatalf("history = %+v, want one pending run", history)
+			}
+			if got, _ := s.Job(job.ID); got.TotalBase != 0 || len(got.PendingOrders) != 1 {
+				t.Fatalf("job = %+v, want no totals and one pending order", got)
+			}
+
+			if tt.complete != nil {
+				tt.complete(exchange, history[0].OrderID)
+			}
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+			if err := s.RunDue(); (err != nil) != tt.wantErr {
+				t.Fatalf("RunDue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			history = s.History(job.ID)
+			if len(history) != 1 || history[0].Status != tt.wantStatus {
+				t.Fatalf("history = %+v, want one %s run", history, tt.wantStatus)
+			}
+			got, err := s.Job(job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.TotalBase != tt.wantBase || got.TotalQuote != tt.wantQuote {
+				t.Errorf("totals = %v base, %v quote, want %v, %v", got.TotalBase, got.TotalQuote, tt.wantBase, tt.wantQuote)
+			}
+			if len(got.PendingOrders) != tt.wantPending {
+				t.Errorf("PendingOrders = %v, want %d", got.PendingOrders, tt.wantPending)
+			}
+			if settled := tt.wantStatus != DCAPending; settled != (len(executions) == 2) {
+				t.Errorf("OnExecution called %d times, settled %v", len(executions), settled)
+			}
+		})
+	}
+}
+
+func TestDCASchedulerValueAveragingWaitsForPendingOrders(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.price = 0.5
+	exchange.addOrder(OrderJSON{OrderID: "previous", Status: "open", OrigQty: "100", ExecutedQty: "0"})
+	s, err := NewDCAScheduler(d, DCASchedulerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job := addDueDCAJob(t, s, DCAJob{
+		Schedule: "@daily",
+		Symbol:   ADAUSDM,
+		Rule:     DCAAmountRule{Type: DCAValueAveraging, Amount: 50},
+	})
+	s.mu.Lock()
+	s.jobs[job.ID].PendingOrders = []string{"previous"}
+	s.mu.Unlock()
+
+	if err := s.RunDue(); err != nil {
+		t.Fatal(err)
+	}
+	history := s.History(job.ID)
+	if len(history) != 1 || history[0].Status != DCASkipped {
+		t.Fatalf("history = %+v, want one skipped run", history)
+	}
+	if placed := exchange.placed.Load(); placed != 0 {
+		t.Errorf("placed %d orders while the previous one was pending", placed)
+	}
+}
+
+func TestDCASchedulerReportsErrors(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.lookup = func(string) int { return http.StatusServiceUnavailable }
+	errs := make(chan error, 10)
+	s, err := NewDCAScheduler(d, DCASchedulerOptions{
+		CheckInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.AddJob(DCAJob{Schedule: "@daily", Symbol: ADAUSDM, Rule: DCAAmountRule{Type: DCAFixedBase, Amount: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.jobs[job.ID].PendingOrders = []string{"pending"}
+	s.mu.Unlock()
+
+	s.Start()
+	defer s.Stop()
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("OnError called with nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnError not called")
+	}
+}
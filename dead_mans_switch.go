@@ -0,0 +1,279 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultDeadMansSwitchRetries is how many failed cancellation attempts raise DeadMansSwitchCancelFailed
+	DefaultDeadMansSwitchRetries = 5
+	// DefaultDeadMansSwitchRetryDelay is the pause after the first failed cancellation attempt
+	DefaultDeadMansSwitchRetryDelay = time.Second
+	// DefaultDeadMansSwitchMaxRetryDelay caps the pause between cancellation attempts
+	DefaultDeadMansSwitchMaxRetryDelay = time.Minute
+)
+
+// ErrOrdersBlocked is returned when placing an order while a tripped dead man's switch blocks new orders.
+var ErrOrdersBlocked = errors.New("order placement blocked by dead man's switch")
+
+// DeadMansSwitchEventType identifies a dead man's switch event.
+type DeadMansSwitchEventType string
+
+const (
+	// DeadMansSwitchArmed is emitted when the switch starts or is re-armed
+	DeadMansSwitchArmed DeadMansSwitchEventType = "armed"
+	// DeadMansSwitchTripped is emitted when no heartbeat arrived within the timeout
+	DeadMansSwitchTripped DeadMansSwitchEventType = "tripped"
+	// DeadMansSwitchCancelRetry is emitted for every failed cancellation attempt that will be retried
+	DeadMansSwitchCancelRetry DeadMansSwitchEventType = "cancel_retry"
+	// DeadMansSwitchCancelled is emitted when all orders were cancelled
+	DeadMansSwitchCancelled DeadMansSwitchEventType = "cancelled"
+	// DeadMansSwitchCancelFailed is emitted once the configured number of attempts failed; the
+	// switch keeps retrying until a cancellation succeeds or it is re-armed or stopped
+	DeadMansSwitchCancelFailed DeadMansSwitchEventType = "cancel_failed"
+	// DeadMansSwitchStopped is emitted when the switch is stopped
+	DeadMansSwitchStopped DeadMansSwitchEventType = "stopped"
+)
+
+// DeadMansSwitchEvent describes a state change of a dead man's switch.
+type DeadMansSwitchEvent struct {
+	Type DeadMansSwitchEventType
+	Time time.Time
+	// LastHeartbeat is the time of the last heartbeat received
+	LastHeartbeat time.Time
+	// Attempt is the cancellation attempt, starting at 1 (cancellation events only)
+	Attempt int
+	// Response is the cancellation result (DeadMansSwitchCancelled only)
+	Response *SubmitCancelAllOrdersTransactionResponse
+	// Err is the cancellation error (DeadMansSwitchCancelRetry and DeadMansSwitchCancelFailed only)
+	Err error
+}
+
+// DeadMansSwitchOptions configures a dead man's switch.
+type DeadMansSwitchOptions struct {
+	// Timeout is how long the switch waits for a heartbeat before cancelling all orders (required)
+	Timeout time.Duration
+	// CheckInterval is how often the heartbeat is checked (defaults to a quarter of Timeout)
+	CheckInterval time.Duration
+	// Retries is the number of failed cancellation attempts after which DeadMansSwitchCancelFailed is
+	// emitted (defaults to DefaultDeadMansSwitchRetries); attempts continue afterwards
+	Retries int
+	// RetryDelay is the pause after the first failed cancellation attempt, doubled after every further
+	// failure (defaults to DefaultDeadMansSwitchRetryDelay)
+	RetryDelay time.Duration
+	// MaxRetryDelay caps the pause between cancellation attempts (defaults to DefaultDeadMansSwitchMaxRetryDelay)
+	MaxRetryDelay time.Duration
+	// BlockOrders makes order placement fail with ErrOrdersBlocked once the switch tripped, until Rearm is called
+	BlockOrders bool
+	// OnEvent is called for every event (optional). Trip and cancellation events are delivered from the
+	// watchdog goroutine, so OnEvent must not call Stop, which waits for that goroutine; Rearm is safe
+	OnEvent func(DeadMansSwitchEvent)
+}
+
+// DeadMansSwitch cancels all open orders when the application stops sending heartbeats,
+// so a hung process does not leave its quotes on the book. Once tripped, the switch stays
+// tripped until Rearm is called; further heartbeats do not reset it. A failed cancellation is
+// retried with backoff until it succeeds or the switch is re-armed or stopped.
+// A DeadMansSwitch is safe for concurrent use.
+type DeadMansSwitch struct {
+	d    *DeltaDeFi
+	opts DeadMansSwitchOptions
+
+	lastHeartbeat atomic.Int64
+	tripped       atomic.Bool
+	blocking      atomic.Bool
+	// rearmed wakes a trip waiting to retry its cancellation
+	rearmed chan struct{}
+
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// StartDeadMansSwitch arms a dead man's switch on the client. Heartbeat must then be called
+// more often than the configured timeout. Only one switch can be active per client.
+//
+// Parameters:
+//   - opts: Timeout, retry and blocking settings
+//
+// Returns:
+//   - *DeadMansSwitch: The armed switch
+//   - error: nil on success, error if the options are invalid or a switch is already active
+func (d *DeltaDeFi) StartDeadMansSwitch(opts DeadMansSwitchOptions) (*DeadMansSwitch, error) {
+	if opts.Timeout <= 0 {
+		return nil, fmt.Errorf("dead man's switch timeout must be positive")
+	}
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = opts.Timeout / 4
+	}
+	if opts.Retries <= 0 {
+		opts.Retries = DefaultDeadMansSwitchRetries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultDeadMansSwitchRetryDelay
+	}
+	if opts.MaxRetryDelay <= 0 {
+		opts.MaxRetryDelay = DefaultDeadMansSwitchMaxRetryDelay
+	}
+
+	s := &DeadMansSwitch{
+		d:       d,
+		opts:    opts,
+		rearmed: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	s.lastHeartbeat.Store(time.Now().UnixNano())
+	if !d.deadMansSwitch.CompareAndSwap(nil, s) {
+		return nil, fmt.Errorf("a dead man's switch is already active")
+	}
+
+	s.emit(DeadMansSwitchEvent{Type: DeadMansSwitchArmed})
+	s.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Heartbeat signals that the application is alive to the active dead man's switch.
+// It does nothing when no switch is active.
+func (d *DeltaDeFi) Heartbeat() {
+	if s := d.deadMansSwitch.Load(); s != nil {
+		s.Heartbeat()
+	}
+}
+
+// DeadMansSwitch returns the active dead man's switch, or nil.
+func (d *DeltaDeFi) DeadMansSwitch() *DeadMansSwitch {
+	return d.deadMansSwitch.Load()
+}
+
+// checkOrdersAllowed returns ErrOrdersBlocked while a tripped dead man's switch blocks new orders.
+func (d *DeltaDeFi) checkOrdersAllowed() error {
+	if s := d.deadMansSwitch.Load(); s != nil && s.blocking.Load() {
+		return ErrOrdersBlocked
+	}
+	return nil
+}
+
+// Heartbeat signals that the application is alive.
+func (s *DeadMansSwitch) Heartbeat() {
+	s.lastHeartbeat.Store(time.Now().UnixNano())
+}
+
+// Tripped reports whether the switch fired since it was last armed.
+func (s *DeadMansSwitch) Tripped() bool {
+	return s.tripped.Load()
+}
+
+// Rearm resets a tripped switch: order placement is allowed again, a cancellation still being
+// retried is abandoned and the heartbeat timeout restarts.
+func (s *DeadMansSwitch) Rearm() {
+	s.Heartbeat()
+	s.blocking.Store(false)
+	if s.tripped.CompareAndSwap(true, false) {
+		select {
+		case s.rearmed <- struct{}{}:
+		default:
+		}
+		s.emit(DeadMansSwitchEvent{Type: DeadMansSwitchArmed})
+	}
+}
+
+// Stop disarms the switch and detaches it from the client, lifting any order block.
+// It abandons a cancellation waiting to be retried and waits for an attempt in progress to finish.
+// Stop must not be called from OnEvent. Calling Stop more than once has no effect.
+func (s *DeadMansSwitch) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.blocking.Store(false)
+		s.d.deadMansSwitch.CompareAndSwap(s, nil)
+		s.emit(DeadMansSwitchEvent{Type: DeadMansSwitchStopped})
+	})
+}
+
+// run checks the heartbeat until the switch is stopped.
+func (s *DeadMansSwitch) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if s.tripped.Load() {
+				continue
+			}
+			last := time.Unix(0, s.lastHeartbeat.Load())
+			if time.Since(last) < s.opts.Timeout {
+				continue
+			}
+			s.trip(last)
+		}
+	}
+}
+
+// trip blocks order placement if configured and cancels all orders, retrying with backoff until
+// the cancellation succeeds or the switch is re-armed or stopped.
+func (s *DeadMansSwitch) trip(lastHeartbeat time.Time) {
+	// Drop a wake-up left by a Rearm that raced with the previous trip
+	select {
+	case <-s.rearmed:
+	default:
+	}
+	s.tripped.Store(true)
+	if s.opts.BlockOrders {
+		s.blocking.Store(true)
+	}
+	s.emit(DeadMansSwitchEvent{Type: DeadMansSwitchTripped, LastHeartbeat: lastHeartbeat})
+
+	delay := s.opts.RetryDelay
+	for attempt := 1; ; attempt++ {
+		res, err := s.d.CancelAllOrders()
+		if err == nil {
+			s.emit(DeadMansSwitchEvent{Type: DeadMansSwitchCancelled, LastHeartbeat: lastHeartbeat, Attempt: attempt, Response: res})
+			return
+		}
+		if !s.tripped.Load() {
+			return
+		}
+		eventType := DeadMansSwitchCancelRetry
+		if attempt == s.opts.Retries {
+			eventType = DeadMansSwitchCancelFailed
+		}
+		s.emit(DeadMansSwitchEvent{Type: eventType, LastHeartbeat: lastHeartbeat, Attempt: attempt, Err: err})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.done:
+			timer.Stop()
+			return
+		case <-s.rearmed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		delay *= 2
+		if delay > s.opts.MaxRetryDelay {
+			delay = s.opts.MaxRetryDelay
+		}
+	}
+}
+
+// emit delivers an event to the OnEvent callback.
+func (s *DeadMansSwitch) emit(event DeadMansSwitchEvent) {
+	if s.opts.OnEvent == nil {
+		return
+	}
+	event.Time = time.Now()
+	if event.LastHeartbeat.IsZero() {
+		event.LastHeartbeat = time.Unix(0, s.lastHeartbeat.Load())
+	}
+	s.opts.OnEvent(event)
+}
@@ -0,0 +1,175 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// switchEvents collects the events of a dead man's switch.
+type switchEvents struct {
+	mu     sync.Mutex
+	events []DeadMansSwitchEvent
+}
+
+func (e *switchEvents) record(event DeadMansSwitchEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+// types returns the types of the recorded events.
+func (e *switchEvents) types() []DeadMansSwitchEventType {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	types := make([]DeadMansSwitchEventType, len(e.events))
+	for i, event := range e.events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+// has reports whether an event of the type was recorded.
+func (e *switchEvents) has(eventType DeadMansSwitchEventType) bool {
+	for _, t := range e.types() {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// startTestSwitch starts a switch with a 50ms timeout whose cancel-all builds fail cancelFailures times.
+func startTestSwitch(t *testing.T, opts DeadMansSwitchOptions, cancelFailures int32) (*DeltaDeFi, *mockExchange, *DeadMansSwitch, *switchEvents) {
+	t.Helper()
+	d, exchange := newMockClient(t, ApiConfig{})
+	transport := &failingTransport{next: http.DefaultTransport, path: "/cancel-all/build"}
+	transport.failures.Store(cancelFailures)
+	d.client.HTTPClient.Transport = transport
+
+	events := &switchEvents{}
+	opts.Timeout = 50 * time.Millisecond
+	opts.CheckInterval = 5 * time.Millisecond
+	opts.OnEvent = events.record
+	s, err := d.StartDeadMansSwitch(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	return d, exchange, s, events
+}
+
+func TestDeadMansSwitchTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		heartbeats  bool
+		wantTripped bool
+	}{
+		{name: "heartbeats keep it armed", heartbeats: true},
+		{name: "missing heartbeats cancel all orders", wantTripped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange, s, events := startTestSwitch(t, DeadMansSwitchOptions{}, 0)
+			exchange.addOrder(OrderJSON{OrderID: "quote", Symbol: ADAUSDM, Status: "open", OrigQty: "10", ExecutedQty: "0"})
+
+			deadline := time.Now().Add(200 * time.Millisecond)
+			for time.Now().Before(deadline) {
+				if tt.heartbeats {
+					d.Heartbeat()
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			if tt.wantTripped {
+				waitFor(t, func() bool { return events.has(DeadMansSwitchCancelled) })
+			}
+
+			if s.Tripped() != tt.wantTripped {
+				t.Errorf("Tripped() = %v, want %v (events %v)", s.Tripped(), tt.wantTripped, events.types())
+			}
+			wantStatus := "open"
+			if tt.wantTripped {
+				wantStatus = "cancelled"
+			}
+			if status := exchange.order("quote").Status; status != wantStatus {
+				t.Errorf("order status = %s, want %s", status, wantStatus)
+			}
+		})
+	}
+}
+
+func TestDeadMansSwitchBlocksOrdersUntilRearm(t *testing.T) {
+	d, _, s, events := startTestSwitch(t, DeadMansSwitchOptions{BlockOrders: true}, 0)
+	order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5)}
+
+	waitFor(t, func() bool { return events.has(DeadMansSwitchCancelled) })
+	if _, err := d.PostOrder(order); !errors.Is(err, ErrOrdersBlocked) {
+		t.Fatalf("PostOrder() error = %v, want ErrOrdersBlocked", err)
+	}
+
+	s.Rearm()
+	if s.Tripped() {
+		t.Error("still tripped after Rearm")
+	}
+	if _, err := d.PostOrder(order); err != nil {
+		t.Errorf("PostOrder() after Rearm error = %v", err)
+	}
+	types := events.types()
+	if types[len(types)-1] != DeadMansSwitchArmed {
+		t.Errorf("events = %v, want armed last", types)
+	}
+}
+
+func TestDeadMansSwitchRetriesCancellation(t *testing.T) {
+	_, _, s, events := startTestSwitch(t, DeadMansSwitchOptions{Retries: 2, RetryDelay: time.Millisecond}, 3)
+
+	waitFor(t, func() bool { return events.has(DeadMansSwitchCancelled) })
+	want := []DeadMansSwitchEventType{
+		DeadMansSwitchArmed, DeadMansSwitchTripped,
+		DeadMansSwitchCancelRetry, DeadMansSwitchCancelFailed, DeadMansSwitchCancelRetry, DeadMansSwitchCancelled,
+	}
+	got := events.types()
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %v, want %v", got, want)
+		}
+	}
+	if !s.Tripped() {
+		t.Error("not tripped after the cancellation")
+	}
+}
+
+func TestDeadMansSwitchRearmStopsRetries(t *testing.T) {
+	_, _, s, events := startTestSwitch(t, DeadMansSwitchOptions{RetryDelay: 20 * time.Millisecond}, 1<<20)
+
+	waitFor(t, func() bool { return events.has(DeadMansSwitchCancelRetry) })
+	s.Rearm()
+	// Keep the switch armed while checking that no further attempt is made
+	attempts := func() int {
+		n := 0
+		for _, eventType := range events.types() {
+			if eventType == DeadMansSwitchCancelRetry || eventType == DeadMansSwitchCancelFailed {
+				n++
+			}
+		}
+		return n
+	}
+	before := attempts()
+	for i := 0; i < 20; i++ {
+		s.Heartbeat()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if after := attempts(); after != before {
+		t.Errorf("%d cancellation attempts after Rearm", after-before)
+	}
+
+	returnsWithin(t, time.Second, func() error {
+		s.Stop()
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+package deltadefi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// levels returns the side of the book an order on the given side trades against, best price
+// first: asks in ascending order for buys, bids in descending order for sells.
+func (d *GetMarketDepthResponse) levels(side OrderSide) []MarketDepth {
+	var levels []MarketDepth
+	if side == OrderSideBuy {
+		levels = append(levels, d.Asks...)
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
+	} else {
+		levels = append(levels, d.Bids...)
+		sort.SliceStable(levels, func(i, j int) bool { return levels[i].Price > levels[j].Price })
+	}
+	return levels
+}
+
+// crosses reports whether a level price is acceptable for an order on the given side with an
+// optional limit price.
+func crosses(side OrderSide, levelPrice float64, limitPrice *float64) bool {
+	if limitPrice == nil {
+		return true
+	}
+	if side == OrderSideBuy {
+		return levelPrice <= *limitPrice
+	}
+	return levelPrice >= *limitPrice
+}
+
+// AvailableQuantity returns the quantity an order on the given side could fill immediately
+// against the book, without trading through limitPrice.
+//
+// Parameters:
+//   - side: The side of the incoming order
+//   - limitPrice: The worst acceptable price (optional, nil for a market order)
+//
+// Returns:
+//   - float64: The fillable base quantity
+func (d *GetMarketDepthResponse) AvailableQuantity(side OrderSide, limitPrice *float64) float64 {
+	total := 0.0
+	for _, level := range d.levels(side) {
+		if !crosses(side, level.Price, limitPrice) {
+			break
+		}
+		total += level.Quantity
+	}
+	return total
+}
+
+// QuoteEstimate is the result of walking the book for an order sized in quote currency.
+type QuoteEstimate struct {
+	// Quantity is the base quantity, rounded down to the lot size
+	Quantity float64
+	// QuoteAmount is the estimated quote spent (buys) or received (sells) for Quantity
+	QuoteAmount float64
+	// AveragePrice is the estimated volume-weighted fill price
+	AveragePrice float64
+	// BestPrice is the price of the first level of the book
+	BestPrice float64
+	// WorstPrice is the price of the last level the order reaches
+	WorstPrice float64
+	// Levels is the number of book levels the order reaches
+	Levels int
+}
+
+// EstimateQuoteOrder converts a quote amount into the base quantity an order on the given side
+// can trade right now, walking the book from the best price without going beyond the slippage bound.
+//
+// Parameters:
+//   - side: The side of the incoming order
+//   - quoteAmount: The quote amount to spend (buys) or receive (sells)
+//   - maxSlippageBasisPoint: The largest distance from the best price a level may have (0 allows any level)
+//   - lotSize: The quantity step; the quantity is rounded down to a multiple of it (0 disables rounding)
+//
+// Returns:
+//   - *QuoteEstimate: The quantity and estimated quote amount
+//   - error: ErrNotFillable if the book within the bound cannot absorb the quote amount, other error on invalid input
+func (d *GetMarketDepthResponse) EstimateQuoteOrder(side OrderSide, quoteAmount float64, maxSlippageBasisPoint int, lotSize float64) (*QuoteEstimate, error) {
+	if quoteAmount <= 0 {
+		return nil, fmt.Errorf("quote amount must be positive")
+	}
+	levels := d.levels(side)
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: the book is empty", ErrNotFillable)
+	}
+
+	best := levels[0].Price
+	var bound *float64
+	if maxSlippageBasisPoint > 0 {
+		slippage := float64(maxSlippageBasisPoint) / 10000
+		limit := best * (1 + slippage)
+		if side == OrderSideSell {
+			limit = best * (1 - slippage)
+		}
+		bound = &limit
+	}
+
+	// Find the base quantity worth quoteAmount
+	remaining := quoteAmount
+	quantity := 0.0
+	for _, level := range levels {
+		if remaining <= quantityEpsilon || !crosses(side, level.Price, bound) {
+			break
+		}
+		levelQuote := level.Price * level.Quantity
+		if levelQuote >= remaining {
+			quantity += remaining / level.Price
+			remaining = 0
+			break
+		}
+		quantity += level.Quantity
+		remaining -= levelQuote
+	}
+	if remaining > quantityEpsilon {
+		return nil, fmt.Errorf("%w: %g of %g quote available within the slippage bound", ErrNotFillable, quoteAmount-remaining, quoteAmount)
+	}
+
+	if lotSize > 0 {
+		quantity = math.Floor(quantity/lotSize+quantityEpsilon) * lotSize
+	}
+	if quantity <= quantityEpsilon {
+		return nil, fmt.Errorf("%w: quote amount %g is below one lot", ErrNotFillable, quoteAmount)
+	}
+
+	// Price the rounded quantity
+	estimate := &QuoteEstimate{Quantity: quantity, BestPrice: best}
+	left := quantity
+	for _, level := range levels {
+		if left <= quantityEpsilon {
+			break
+		}
+		take := math.Min(left, level.Quantity)
+		estimate.QuoteAmount += take * level.Price
+		estimate.WorstPrice = level.Price
+		estimate.Levels++
+		left -= take
+	}
+	estimate.AveragePrice = estimate.QuoteAmount / quantity
+	return estimate, nil
+}
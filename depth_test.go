@@ -0,0 +1,151 @@
+package deltadefi
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// testDepth is a book with three ask and two bid levels.
+var testDepth = GetMarketDepthResponse{
+	Asks: []MarketDepth{{Price: 1.05, Quantity: 100}, {Price: 1, Quantity: 100}, {Price: 1.01, Quantity: 100}},
+	Bids: []MarketDepth{{Price: 0.98, Quantity: 100}, {Price: 0.99, Quantity: 100}},
+}
+
+func TestEstimateQuoteOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		depth        GetMarketDepthResponse
+		side         OrderSide
+		quoteAmount  float64
+		slippage     int
+		lotSize      float64
+		wantQuantity float64
+		wantQuote    float64
+		wantWorst    float64
+		wantLevels   int
+		wantErr      bool
+		// wantNotFillable requires the error to wrap ErrNotFillable
+		wantNotFillable bool
+	}{
+		{
+			name:         "within the best level",
+			depth:        testDepth,
+			side:         OrderSideBuy,
+			quoteAmount:  50,
+			wantQuantity: 50,
+			wantQuote:    50,
+			wantWorst:    1,
+			wantLevels:   1,
+		},
+		{
+			name:         "walks several levels",
+			depth:        testDepth,
+			side:         OrderSideBuy,
+			quoteAmount:  300,
+			wantQuantity: 200 + 99/1.05,
+			wantQuote:    300,
+			wantWorst:    1.05,
+			wantLevels:   3,
+		},
+		{
+			name:         "rounds down to the lot size",
+			depth:        testDepth,
+			side:         OrderSideBuy,
+			quoteAmount:  150,
+			lotSize:      1,
+			wantQuantity: 149,
+			wantQuote:    100 + 49*1.01,
+			wantWorst:    1.01,
+			wantLevels:   2,
+		},
+		{
+			name:         "sells walk the bids",
+			depth:        testDepth,
+			side:         OrderSideSell,
+			quoteAmount:  50,
+			lotSize:      10,
+			wantQuantity: 50,
+			wantQuote:    49.5,
+			wantWorst:    0.99,
+			wantLevels:   1,
+		},
+		{
+			name:         "slippage bound within reach",
+			depth:        testDepth,
+			side:         OrderSideBuy,
+			quoteAmount:  200,
+			slippage:     200,
+			wantQuantity: 100 + 100/1.01,
+			wantQuote:    200,
+			wantWorst:    1.01,
+			wantLevels:   2,
+		},
+		{
+			name:            "slippage bound excludes deeper levels",
+			depth:           testDepth,
+			side:            OrderSideBuy,
+			quoteAmount:     300,
+			slippage:        200,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:            "insufficient depth",
+			depth:           testDepth,
+			side:            OrderSideSell,
+			quoteAmount:     1000,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:            "below one lot",
+			depth:           testDepth,
+			side:            OrderSideBuy,
+			quoteAmount:     0.5,
+			lotSize:         1,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:            "empty book",
+			side:            OrderSideBuy,
+			quoteAmount:     10,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:        "non-positive amount",
+			depth:       testDepth,
+			side:        OrderSideBuy,
+			quoteAmount: 0,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate, err := tt.depth.EstimateQuoteOrder(tt.side, tt.quoteAmount, tt.slippage, tt.lotSize)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EstimateQuoteOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrNotFillable) != tt.wantNotFillable {
+				t.Errorf("EstimateQuoteOrder() error = %v, want ErrNotFillable %v", err, tt.wantNotFillable)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(estimate.Quantity-tt.wantQuantity) > 1e-9 {
+				t.Errorf("Quantity = %v, want %v", estimate.Quantity, tt.wantQuantity)
+			}
+			if math.Abs(estimate.QuoteAmount-tt.wantQuote) > 1e-9 {
+				t.Errorf("QuoteAmount = %v, want %v", estimate.QuoteAmount, tt.wantQuote)
+			}
+			if math.Abs(estimate.AveragePrice-tt.wantQuote/tt.wantQuantity) > 1e-9 {
+				t.Errorf("AveragePrice = %v, want %v", estimate.AveragePrice, tt.wantQuote/tt.wantQuantity)
+			}
+			if estimate.WorstPrice != tt.wantWorst || estimate.Levels != tt.wantLevels {
+				t.Errorf("reached %d levels down to %v, want %d down to %v", estimate.Levels, estimate.WorstPrice, tt.wantLevels, tt.wantWorst)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package deltadefi
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sidan-lab/rum"
+)
+
+// DryRunAction identifies the operation described by a dry-run report.
+type DryRunAction string
+
+const (
+	DryRunPlaceOrder      DryRunAction = "place_order"
+	DryRunCancelOrder     DryRunAction = "cancel_order"
+	DryRunCancelAllOrders DryRunAction = "cancel_all_orders"
+	DryRunDeposit         DryRunAction = "deposit"
+	DryRunWithdrawal      DryRunAction = "withdrawal"
+	DryRunTransferal      DryRunAction = "transferal"
+)
+
+// DryRunTransaction is a transaction that was built but neither signed nor submitted.
+type DryRunTransaction struct {
+	TxHex string `json:"tx_hex"`
+	// Summary is the decoded transaction, nil if it could not be decoded
+	Summary *TransactionSummary `json:"summary,omitempty"`
+	// DecodeError explains why the transaction could not be decoded
+	DecodeError string `json:"decode_error,omitempty"`
+}
+
+// DryRunReport describes what an operation would have done.
+type DryRunReport struct {
+	Action DryRunAction `json:"action"`
+	// Request is the request of the operation
+	Request any `json:"request,omitempty"`
+	// OrderID is the order placed or cancelled, if any. Building an order creates its record
+	// on the exchange; it stays in the building state and never becomes active.
+	OrderID      string              `json:"order_id,omitempty"`
+	Transactions []DryRunTransaction `json:"transactions"`
+	BuiltAt      time.Time           `json:"built_at"`
+}
+
+// DryRunError is returned by transactional methods while the client is in dry-run mode.
+// The operation was validated and built but never signed or submitted; Report describes it.
+type DryRunError struct {
+	Report *DryRunReport
+}
+
+// Error implements the error interface.
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dry run: %s built but not signed or submitted", e.Report.Action)
+}
+
+// DryRunEnabled reports whether the client runs in dry-run mode (see ApiConfig.DryRun).
+func (d *DeltaDeFi) DryRunEnabled() bool {
+	return d.dryRun
+}
+
+// DryRunPostOrder validates an order, including the dead man's switch and the pre-trade risk checks,
+// and builds its transaction without signing or submitting it. The order does not count towards
+// the order rate limit.
+// Order transactions can only be built by the exchange, so building creates an order record on the
+// server in the building state. It never becomes active, but it appears in the order history and
+// its ID is returned in the report.
+//
+// Parameters:
+//   - data: Order details including symbol, side, type, quantity, and optional price
+//
+// Returns:
+//   - *DryRunReport: The built transaction and what it does
+//   - error: nil on success, error if validation or building fails
+func (d *DeltaDeFi) DryRunPostOrder(data *BuildPlaceOrderTransactionRequest) (*DryRunReport, error) {
+	data, err := d.validateOrder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	buildRes, err := d.Order.BuildPlaceOrderTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	if buildRes.OrderID == "" {
+		return nil, fmt.Errorf("order build returned no order ID")
+	}
+	report := newDryRunReport(DryRunPlaceOrder, data, buildRes.TxHex)
+	report.OrderID = buildRes.OrderID
+	return report, nil
+}
+
+// validateOrder runs the checks of a dry-run order (the dead man's switch, automatic slippage and
+// the pre-trade risk checks) without building it, so nothing is created on the exchange. It
+// returns the request that would be placed.
+func (d *DeltaDeFi) validateOrder(data *BuildPlaceOrderTransactionRequest) (*BuildPlaceOrderTransactionRequest, error) {
+	if err := d.checkOrdersAllowed(); err != nil {
+		return nil, err
+	}
+	data, err := d.prepareOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.risk.Check(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DryRunCancelOrder builds the cancellation of an order without signing or submitting it.
+//
+// Parameters:
+//   - orderId: The ID of the order to cancel
+//
+// Returns:
+//   - *DryRunReport: The built transaction and what it does
+//   - error: nil on success, error if building fails
+func (d *DeltaDeFi) DryRunCancelOrder(orderId string) (*DryRunReport, error) {
+	if orderId == "" {
+		return nil, fmt.Errorf("order ID is required")
+	}
+	buildRes, err := d.Order.BuildCancelOrderTransaction(orderId)
+	if err != nil {
+		return nil, err
+	}
+	report := newDryRunReport(DryRunCancelOrder, nil, buildRes.TxHex)
+	report.OrderID = orderId
+	return report, nil
+}
+
+// DryRunCancelAllOrders builds the cancellation of all open orders without signing or submitting it.
+//
+// Returns:
+//   - *DryRunReport: The built transactions and what they do
+//   - error: nil on success, error if building fails
+func (d *DeltaDeFi) DryRunCancelAllOrders() (*DryRunReport, error) {
+	buildRes, err := d.Order.BuildCancelAllOrdersTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return newDryRunReport(DryRunCancelAllOrders, nil, buildRes.TxHexes...), nil
+}
+
+// DryRunDeposit validates and builds a deposit without signing or submitting it.
+//
+// Parameters:
+//   - data: Deposit amounts and input UTxOs
+//
+// Returns:
+//   - *DryRunReport: The built transaction and what it does
+//   - error: nil on success, error if validation or building fails
+func (d *DeltaDeFi) DryRunDeposit(data *BuildDepositTransactionRequest) (*DryRunReport, error) {
+	if err := validateAssetAmounts("deposit", data.DepositAmount); err != nil {
+		return nil, err
+	}
+	buildRes, err := d.Accounts.BuildDepositTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return newDryRunReport(DryRunDeposit, data, buildRes.TxHex), nil
+}
+
+// DryRunWithdrawal validates and builds a withdrawal without signing or submitting it.
+//
+// Parameters:
+//   - data: Withdrawal amounts
+//
+// Returns:
+//   - *DryRunReport: The built transaction and what it does
+//   - error: nil on success, error if validation or building fails
+func (d *DeltaDeFi) DryRunWithdrawal(data *BuildWithdrawalTransactionRequest) (*DryRunReport, error) {
+	if err := validateAssetAmounts("withdrawal", data.WithdrawalAmount); err != nil {
+		return nil, err
+	}
+	buildRes, err := d.Accounts.BuildWithdrawalTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return newDryRunReport(DryRunWithdrawal, data, buildRes.TxHex), nil
+}
+
+// DryRunTransferal validates and builds a transfer without signing or submitting it.
+//
+// Parameters:
+//   - data: Transfer amounts and destination address
+//
+// Returns:
+//   - *DryRunReport: The built transaction and what it does
+//   - error: nil on success, error if validation or building fails
+func (d *DeltaDeFi) DryRunTransferal(data *BuildTransferalTransactionRequest) (*DryRunReport, error) {
+	if data.ToAddress == "" {
+		return nil, fmt.Errorf("transferal destination address is required")
+	}
+	if err := validateAssetAmounts("transferal", data.TransferalAmount); err != nil {
+		return nil, err
+	}
+	buildRes, err := d.Accounts.BuildTransferalTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return newDryRunReport(DryRunTransferal, data, buildRes.TxHex), nil
+}
+
+// dryRunError wraps a dry-run report into a DryRunError, passing failures through.
+func dryRunError(report *DryRunReport, err error) error {
+	if err != nil {
+		return err
+	}
+	return &DryRunError{Report: report}
+}
+
+// newDryRunReport creates a report and decodes the built transactions.
+func newDryRunReport(action DryRunAction, request any, txHexes ...string) *DryRunReport {
+	report := &DryRunReport{
+		Action:       action,
+		Request:      request,
+		Transactions: make([]DryRunTransaction, 0, len(txHexes)),
+		BuiltAt:      time.Now(),
+	}
+	for _, txHex := range txHexes {
+		tx := DryRunTransaction{TxHex: txHex}
+		summary, err := DescribeTransaction(txHex)
+		if err != nil {
+			tx.DecodeError = err.Error()
+		} else {
+			tx.Summary = summary
+		}
+		report.Transactions = append(report.Transactions, tx)
+	}
+	return report
+}
+
+// validateAssetAmounts rejects empty amount lists and non-positive quantities.
+func validateAssetAmounts(operation string, assets []rum.Asset) error {
+	if len(assets) == 0 {
+		return fmt.Errorf("%s amount is required", operation)
+	}
+	for _, asset := range assets {
+		if asset.Unit == "" {
+			return fmt.Errorf("%s asset unit is required", operation)
+		}
+		quantity, err := strconv.ParseFloat(asset.Quantity, 64)
+		if err != nil || quantity <= 0 {
+			return fmt.Errorf("invalid %s quantity %q for %s", operation, asset.Quantity, asset.Unit)
+		}
+	}
+	return nil
+}
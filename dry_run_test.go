@@ -0,0 +1,49 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestDryRunPostOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		dryRun bool
+	}{
+		{name: "dry-run method"},
+		{name: "dry-run client", dryRun: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{DryRun: tt.dryRun})
+			order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5)}
+
+			var report *DryRunReport
+			if tt.dryRun {
+				_, err := d.PostOrder(order)
+				var dryRunErr *DryRunError
+				if !errors.As(err, &dryRunErr) {
+					t.Fatalf("PostOrder() error = %v, want *DryRunError", err)
+				}
+				report = dryRunErr.Report
+			} else {
+				var err error
+				if report, err = d.DryRunPostOrder(order); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if report.Action != DryRunPlaceOrder || len(report.Transactions) != 1 || report.Transactions[0].Summary == nil {
+				t.Fatalf("report = %+v", report)
+			}
+			// The exchange keeps the built order record, which never becomes active
+			if status := exchange.order(report.OrderID).Status; status != string(OrderStatusBuilding) {
+				t.Errorf("order %s status = %q, want building", report.OrderID, status)
+			}
+			if n := exchange.count(http.MethodPost, "/order/submit"); n != 0 {
+				t.Errorf("%d submissions, want none", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,802 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultExecutionPollInterval is how often executions are advanced when no interval is configured
+	DefaultExecutionPollInterval = 2 * time.Second
+	// DefaultExecutionSlices is the number of child orders of a TWAP or VWAP execution
+	DefaultExecutionSlices = 10
+	// DefaultVWAPLookbackDays is how many days of candles form the VWAP volume profile
+	DefaultVWAPLookbackDays = 7
+	// maxExecutionFailures is how many consecutive child placement failures fail an execution
+	maxExecutionFailures = 3
+)
+
+// ErrExecutionNotFound is returned when no execution exists for an ID.
+var ErrExecutionNotFound = errors.New("execution not found")
+
+// ExecutionAlgorithm selects how a parent order is sliced into child orders.
+type ExecutionAlgorithm string
+
+const (
+	// ExecutionTWAP spreads the parent evenly over the duration
+	ExecutionTWAP ExecutionAlgorithm = "twap"
+	// ExecutionVWAP spreads the parent in proportion to the historical volume at each time of day
+	ExecutionVWAP ExecutionAlgorithm = "vwap"
+	// ExecutionIceberg keeps one visible clip on the book until the parent is filled
+	ExecutionIceberg ExecutionAlgorithm = "iceberg"
+)
+
+// ExecutionState represents the state of a parent order.
+type ExecutionState string
+
+const (
+	ExecutionStateRunning   ExecutionState = "running"
+	ExecutionStatePaused    ExecutionState = "paused"
+	ExecutionStateCompleted ExecutionState = "completed"
+	// ExecutionStateExpired means the schedule ended before the parent was filled
+	ExecutionStateExpired   ExecutionState = "expired"
+	ExecutionStateCancelled ExecutionState = "cancelled"
+	ExecutionStateFailed    ExecutionState = "failed"
+)
+
+// IsTerminal reports whether the execution has ended.
+func (s ExecutionState) IsTerminal() bool {
+	switch s {
+	case ExecutionStateCompleted, ExecutionStateExpired, ExecutionStateCancelled, ExecutionStateFailed:
+		return true
+	}
+	return false
+}
+
+// ExecutionRequest describes a parent order and how to execute it.
+type ExecutionRequest struct {
+	Algorithm ExecutionAlgorithm
+	Symbol    Symbol
+	Side      OrderSide
+	// Quantity is the base quantity of the parent order
+	Quantity float64
+	// LimitPrice makes child orders limit orders at this price; without it children are market orders.
+	// Required for iceberg executions.
+	LimitPrice *float64
+	// MaxSlippageBasisPoint bounds the slippage of market children (optional)
+	MaxSlippageBasisPoint *int
+	// LotSize is the base quantity step; child quantities are rounded down to a multiple of it (optional)
+	LotSize float64
+	// Duration is the length of a TWAP or VWAP schedule
+	Duration time.Duration
+	// Slices is the number of children of a TWAP or VWAP schedule (defaults to DefaultExecutionSlices)
+	Slices int
+	// ChildTimeout is how long a limit child may rest before it is cancelled and its remainder carried
+	// into the next slice (TWAP and VWAP, defaults to the slice length)
+	ChildTimeout time.Duration
+	// VolumeInterval is the candle interval of the VWAP volume profile (defaults to Interval1h)
+	VolumeInterval Interval
+	// VolumeLookbackDays is how many days of candles form the VWAP volume profile (defaults to DefaultVWAPLookbackDays)
+	VolumeLookbackDays int
+	// VisibleQuantity is the clip size of an iceberg execution
+	VisibleQuantity float64
+}
+
+// ExecutionChild is a child order of an execution.
+type ExecutionChild struct {
+	OrderID  string
+	Quantity float64
+	// Filled is the executed base quantity
+	Filled float64
+	// QuoteAmount is the executed quote amount
+	QuoteAmount float64
+	Status      OrderStatus
+	PlacedAt    time.Time
+}
+
+// Execution is the state of a parent order.
+type Execution struct {
+	ID      string
+	Request ExecutionRequest
+	State   ExecutionState
+	// Filled is the executed base quantity across all children
+	Filled float64
+	// AveragePrice is the volume-weighted fill price across all children
+	AveragePrice float64
+	Children     []ExecutionChild
+	// Error describes the last failure
+	Error     string
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// Remaining returns the quantity still to be executed.
+func (e *Execution) Remaining() float64 {
+	return math.Max(0, e.Request.Quantity-e.Filled)
+}
+
+// Progress returns the executed fraction of the parent, between 0 and 1.
+func (e *Execution) Progress() float64 {
+	if e.Request.Quantity <= 0 {
+		return 0
+	}
+	return math.Min(1, e.Filled/e.Request.Quantity)
+}
+
+// ExecutionEngineOptions configures an ExecutionEngine.
+type ExecutionEngineOptions struct {
+	// PollInterval is how often executions are advanced (defaults to DefaultExecutionPollInterval)
+	PollInterval time.Duration
+	// OnUpdate is called whenever an execution changes (optional)
+	OnUpdate func(execution Execution)
+	// OnError is called when advancing an execution fails (optional)
+	OnError func(executionId string, err error)
+}
+
+// executionRun is the mutable state of an execution.
+type executionRun struct {
+	Execution
+
+	// schedule holds the cumulative target fraction of the parent after each slice (TWAP and VWAP)
+	schedule []float64
+	// sliceLength is the time between slices
+	sliceLength time.Duration
+	// nextSlice is the index of the next slice to place
+	nextSlice int
+	// active is the index of the resting child, -1 if none
+	active int
+	// unsettled holds the indexes of cancelled children whose final fills could not be fetched yet
+	unsettled []int
+	// failures counts consecutive child placement failures
+	failures int
+}
+
+// ExecutionEngine executes large parent orders as a series of child orders: evenly over time (TWAP),
+// following the historical volume profile (VWAP), or as a visible clip replenished after every
+// fill (iceberg). Children are placed with DeltaDeFi.PostOrder, so risk checks and the dead man's
+// switch apply to each of them. Progress is tracked by polling the child orders.
+// An ExecutionEngine is safe for concurrent use.
+type ExecutionEngine struct {
+	d    *DeltaDeFi
+	opts ExecutionEngineOptions
+
+	mu   sync.Mutex
+	runs map[string]*executionRun
+	// work serializes the network operations on each execution, which run without holding mu
+	work map[string]*sync.Mutex
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewExecutionEngine creates an execution engine. Call Start to begin advancing executions.
+//
+// Parameters:
+//   - d: The client used to place, cancel and fetch orders
+//   - opts: Engine options
+//
+// Returns:
+//   - *ExecutionEngine: The engine, not yet started
+func NewExecutionEngine(d *DeltaDeFi, opts ExecutionEngineOptions) *ExecutionEngine {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultExecutionPollInterval
+	}
+	return &ExecutionEngine{
+		d:    d,
+		opts: opts,
+		runs: make(map[string]*executionRun),
+		work: make(map[string]*sync.Mutex),
+	}
+}
+
+// Submit validates a parent order and starts executing it. The first child is placed at the next check.
+//
+// Parameters:
+//   - req: The parent order and algorithm settings
+//
+// Returns:
+//   - Execution: The new execution
+//   - error: nil on success, error if the request is invalid or the VWAP profile cannot be loaded
+func (e *ExecutionEngine) Submit(req ExecutionRequest) (Execution, error) {
+	if req.Quantity <= 0 {
+		return Execution{}, fmt.Errorf("execution quantity must be positive")
+	}
+	if req.Side != OrderSideBuy && req.Side != OrderSideSell {
+		return Execution{}, fmt.Errorf("invalid execution side %q", req.Side)
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return Execution{}, err
+	}
+	run := &executionRun{
+		Execution: Execution{
+			ID:        id,
+			Request:   req,
+			State:     ExecutionStateRunning,
+			StartedAt: time.Now(),
+		},
+		active: -1,
+	}
+
+	switch req.Algorithm {
+	case ExecutionTWAP, ExecutionVWAP:
+		if req.Duration <= 0 {
+			return Execution{}, fmt.Errorf("%s execution requires a duration", req.Algorithm)
+		}
+		slices := req.Slices
+		if slices <= 0 {
+			slices = DefaultExecutionSlices
+		}
+		run.sliceLength = req.Duration / time.Duration(slices)
+		if run.Request.ChildTimeout <= 0 {
+			run.Request.ChildTimeout = run.sliceLength
+		}
+		if req.Algorithm == ExecutionTWAP {
+			run.schedule = uniformSchedule(slices)
+		} else {
+			run.schedule, err = e.vwapSchedule(&run.Request, run.StartedAt, slices, run.sliceLength)
+			if err != nil {
+				return Execution{}, err
+			}
+		}
+	case ExecutionIceberg:
+		if req.LimitPrice == nil {
+			return Execution{}, fmt.Errorf("iceberg execution requires a limit price")
+		}
+		if req.VisibleQuantity <= 0 {
+			return Execution{}, fmt.Errorf("iceberg execution requires a visible quantity")
+		}
+	default:
+		return Execution{}, fmt.Errorf("unknown execution algorithm %q", req.Algorithm)
+	}
+
+	e.mu.Lock()
+	e.runs[id] = run
+	e.work[id] = &sync.Mutex{}
+	snapshot := run.snapshot()
+	e.mu.Unlock()
+	return snapshot, nil
+}
+
+// Pause stops an execution from placing children and cancels its resting child.
+//
+// Parameters:
+//   - id: The execution ID
+//
+// Returns:
+//   - error: ErrExecutionNotFound if the ID is unknown, other error if the execution cannot be paused
+func (e *ExecutionEngine) Pause(id string) error {
+	return e.control(id, func(run *executionRun) error {
+		if run.State != ExecutionStateRunning {
+			return fmt.Errorf("execution %s is %s", id, run.State)
+		}
+		if err := e.cancelChild(run); err != nil {
+			return err
+		}
+		run.State = ExecutionStatePaused
+		return nil
+	})
+}
+
+// Resume continues a paused execution. TWAP and VWAP slices whose time passed while paused are
+// caught up with the next child.
+//
+// Parameters:
+//   - id: The execution ID
+//
+// Returns:
+//   - error: ErrExecutionNotFound if the ID is unknown, other error if the execution is not paused
+func (e *ExecutionEngine) Resume(id string) error {
+	return e.control(id, func(run *executionRun) error {
+		if run.State != ExecutionStatePaused {
+			return fmt.Errorf("execution %s is %s", id, run.State)
+		}
+		run.State = ExecutionStateRunning
+		run.failures = 0
+		return nil
+	})
+}
+
+// Cancel ends an execution and cancels its resting child. Filled quantity is kept.
+//
+// Parameters:
+//   - id: The execution ID
+//
+// Returns:
+//   - error: ErrExecutionNotFound if the ID is unknown, other error if the resting child cannot be cancelled
+func (e *ExecutionEngine) Cancel(id string) error {
+	return e.control(id, func(run *executionRun) error {
+		if run.State.IsTerminal() {
+			return nil
+		}
+		if err := e.cancelChild(run); err != nil {
+			return err
+		}
+		run.finish(ExecutionStateCancelled)
+		if err := e.settle(run); err != nil {
+			run.Error = err.Error()
+		}
+		return nil
+	})
+}
+
+// Execution returns the state of an execution.
+//
+// Parameters:
+//   - id: The execution ID
+//
+// Returns:
+//   - Execution: The execution state
+//   - error: ErrExecutionNotFound if the ID is unknown
+func (e *ExecutionEngine) Execution(id string) (Execution, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	run, ok := e.runs[id]
+	if !ok {
+		return Execution{}, ErrExecutionNotFound
+	}
+	return run.snapshot(), nil
+}
+
+// Executions returns all executions sorted by start time.
+func (e *ExecutionEngine) Executions() []Execution {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	executions := make([]Execution, 0, len(e.runs))
+	for _, run := range e.runs {
+		executions = append(executions, run.snapshot())
+	}
+	sort.Slice(executions, func(i, j int) bool {
+		return executions[i].StartedAt.Before(executions[j].StartedAt)
+	})
+	return executions
+}
+
+// Start begins advancing executions in a background goroutine. Calling Start on a running engine has no effect.
+func (e *ExecutionEngine) Start() {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	if e.done != nil {
+		return
+	}
+	e.done = make(chan struct{})
+
+	e.wg.Add(1)
+	go e.run(e.done)
+}
+
+// Stop stops advancing executions. Resting children are left in place; call Cancel first to remove them.
+func (e *ExecutionEngine) Stop() {
+	e.runMu.Lock()
+	if e.done == nil {
+		e.runMu.Unlock()
+		return
+	}
+	close(e.done)
+	e.done = nil
+	e.runMu.Unlock()
+
+	e.wg.Wait()
+}
+
+// Check advances every running execution once: it refreshes the resting child, cancels children
+// that rested too long, and places the next child when due. It is called periodically while the
+// engine runs and can be called directly. Executions are advanced without holding the engine lock,
+// so queries are not blocked by network calls; an execution that Pause, Resume or Cancel is working
+// on is skipped until the next round.
+func (e *ExecutionEngine) Check() {
+	type runError struct {
+		id  string
+		err error
+	}
+	var (
+		updates []Execution
+		errs    []runError
+	)
+
+	e.mu.Lock()
+	ids := make([]string, 0, len(e.runs))
+	for id, run := range e.runs {
+		if run.State == ExecutionStateRunning {
+			ids = append(ids, id)
+		}
+	}
+	e.mu.Unlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		before, after, ok, err := e.modify(id, false, func(run *executionRun) error {
+			if run.State != ExecutionStateRunning {
+				return nil
+			}
+			err := e.advance(run)
+			if err != nil {
+				run.Error = err.Error()
+			}
+			return err
+		})
+		if !ok {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, runError{id: id, err: err})
+		}
+		if after.changedFrom(&before) {
+			updates = append(updates, after)
+		}
+	}
+
+	// Callbacks run without the lock so they may call back into the engine
+	if e.opts.OnError != nil {
+		for _, re := range errs {
+			e.opts.OnError(re.id, re.err)
+		}
+	}
+	if e.opts.OnUpdate != nil {
+		for _, execution := range updates {
+			e.opts.OnUpdate(execution)
+		}
+	}
+}
+
+// run calls Check periodically until done is closed.
+func (e *ExecutionEngine) run(done chan struct{}) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			e.Check()
+		}
+	}
+}
+
+// control applies a state change to an execution and reports it.
+func (e *ExecutionEngine) control(id string, apply func(run *executionRun) error) error {
+	before, after, ok, err := e.modify(id, true, apply)
+	if !ok {
+		return ErrExecutionNotFound
+	}
+	if after.changedFrom(&before) && e.opts.OnUpdate != nil {
+		e.opts.OnUpdate(after)
+	}
+	return err
+}
+
+// modify runs fn on a copy of an execution without holding e.mu, so queries and other executions
+// are not blocked by its network calls, and then stores the copy. Calls for the same execution are
+// serialized; with wait false an execution that is already being worked on is skipped.
+// It returns the execution before and after fn, and false if the execution is unknown or skipped.
+func (e *ExecutionEngine) modify(id string, wait bool, fn func(run *executionRun) error) (Execution, Execution, bool, error) {
+	e.mu.Lock()
+	work, ok := e.work[id]
+	e.mu.Unlock()
+	if !ok {
+		return Execution{}, Execution{}, false, nil
+	}
+	if wait {
+		work.Lock()
+	} else if !work.TryLock() {
+		return Execution{}, Execution{}, false, nil
+	}
+	defer work.Unlock()
+
+	e.mu.Lock()
+	run := e.runs[id]
+	working := run.clone()
+	e.mu.Unlock()
+
+	before := working.snapshot()
+	err := fn(working)
+
+	e.mu.Lock()
+	*run = *working
+	after := run.snapshot()
+	e.mu.Unlock()
+	return before, after, true, err
+}
+
+// advance moves a running execution forward. Cancelled children whose final fills are unknown are
+// refreshed first, since the next child is sized from the parent fills.
+// It works on a copy of the run obtained through modify.
+func (e *ExecutionEngine) advance(run *executionRun) error {
+	if err := e.refreshChild(run); err != nil {
+		return err
+	}
+	if err := e.settle(run); err != nil {
+		return err
+	}
+	if run.Remaining() <= quantityEpsilon {
+		if run.active < 0 {
+			run.finish(ExecutionStateCompleted)
+		}
+		return nil
+	}
+
+	if run.Request.Algorithm == ExecutionIceberg {
+		if run.active >= 0 {
+			return nil
+		}
+		return e.placeChild(run, math.Min(run.Request.VisibleQuantity, run.Remaining()))
+	}
+
+	now := time.Now()
+	if run.active >= 0 {
+		child := &run.Children[run.active]
+		if now.Sub(child.PlacedAt) < run.Request.ChildTimeout {
+			return nil
+		}
+		if err := e.cancelChild(run); err != nil {
+			return err
+		}
+		if err := e.settle(run); err != nil {
+			return err
+		}
+	}
+
+	if run.nextSlice >= len(run.schedule) {
+		run.finish(ExecutionStateExpired)
+		return nil
+	}
+	if now.Before(run.sliceTime(run.nextSlice)) {
+		return nil
+	}
+	// Catch up on slices that passed while paused or while a child was resting
+	for run.nextSlice+1 < len(run.schedule) && !now.Before(run.sliceTime(run.nextSlice+1)) {
+		run.nextSlice++
+	}
+	target := run.Request.Quantity * run.schedule[run.nextSlice]
+	run.nextSlice++
+	return e.placeChild(run, target-run.Filled)
+}
+
+// placeChild places a child order for the given quantity, rounded down to the lot size.
+// It works on a copy of the run obtained through modify.
+func (e *ExecutionEngine) placeChild(run *executionRun, quantity float64) error {
+	if run.Request.LotSize > 0 {
+		quantity = math.Floor(quantity/run.Request.LotSize+quantityEpsilon) * run.Request.LotSize
+	}
+	if quantity <= quantityEpsilon {
+		return nil
+	}
+
+	order := &BuildPlaceOrderTransactionRequest{
+		Symbol:   run.Request.Symbol,
+		Side:     run.Request.Side,
+		Quantity: quantity,
+	}
+	if run.Request.LimitPrice != nil {
+		order.Type = OrderTypeLimit
+		order.Price = FloatPtr(*run.Request.LimitPrice)
+	} else {
+		order.Type = OrderTypeMarket
+		if run.Request.MaxSlippageBasisPoint != nil {
+			order.MaxSlippageBasisPoint = IntPtr(*run.Request.MaxSlippageBasisPoint)
+			order.LimitSlippage = BoolPtr(true)
+		}
+	}
+
+	res, err := e.d.PostOrder(order)
+	if err != nil {
+		run.failures++
+		if run.failures >= maxExecutionFailures {
+			run.finish(ExecutionStateFailed)
+		}
+		return fmt.Errorf("placing child order: %w", err)
+	}
+	run.failures = 0
+
+	run.Children = append(run.Children, ExecutionChild{
+		OrderID:  res.Order.OrderID,
+		Quantity: quantity,
+		Status:   res.Order.OrderStatus(),
+		PlacedAt: time.Now(),
+	})
+	run.active = len(run.Children) - 1
+	run.applyChild(run.active, &res.Order)
+	return nil
+}
+
+// refreshChild updates the resting child from the server.
+// It works on a copy of the run obtained through modify.
+func (e *ExecutionEngine) refreshChild(run *executionRun) error {
+	if run.active < 0 {
+		return nil
+	}
+	order, err := e.d.fetchOrder(run.Children[run.active].OrderID)
+	if err != nil {
+		return err
+	}
+	run.applyChild(run.active, order)
+	return nil
+}
+
+// cancelChild cancels the resting child and records its final fills. A child that was cancelled
+// but could not be fetched afterwards is kept in run.unsettled until settle succeeds.
+// It works on a copy of the run obtained through modify.
+func (e *ExecutionEngine) cancelChild(run *executionRun) error {
+	if run.active < 0 {
+		return nil
+	}
+	index := run.active
+	orderId := run.Children[index].OrderID
+	_, cancelErr := e.d.CancelOrder(orderId)
+
+	order, err := e.d.fetchOrder(orderId)
+	if err != nil {
+		if cancelErr != nil {
+			return cancelErr
+		}
+		run.Children[index].Status = OrderStatusCancelled
+		run.unsettled = append(run.unsettled, index)
+		run.active = -1
+		return nil
+	}
+	run.applyChild(index, order)
+	if cancelErr != nil && run.active >= 0 {
+		return cancelErr
+	}
+	run.active = -1
+	return nil
+}
+
+// settle fetches the final fills of cancelled children that could not be fetched when they were
+// cancelled. It works on a copy of the run obtained through modify.
+func (e *ExecutionEngine) settle(run *executionRun) error {
+	for len(run.unsettled) > 0 {
+		index := run.unsettled[0]
+		order, err := e.d.fetchOrder(run.Children[index].OrderID)
+		if err != nil {
+			return fmt.Errorf("fetching final fills of cancelled child %s: %w", run.Children[index].OrderID, err)
+		}
+		if !order.OrderStatus().IsTerminal() {
+			return fmt.Errorf("cancelled child %s is still %s", order.OrderID, order.Status)
+		}
+		run.applyChild(index, order)
+		run.unsettled = run.unsettled[1:]
+	}
+	return nil
+}
+
+// vwapSchedule weights the slices by the average historical volume at their time of day.
+func (e *ExecutionEngine) vwapSchedule(req *ExecutionRequest, start time.Time, slices int, sliceLength time.Duration) ([]float64, error) {
+	if req.VolumeInterval == "" {
+		req.VolumeInterval = Interval1h
+	}
+	if req.VolumeLookbackDays <= 0 {
+		req.VolumeLookbackDays = DefaultVWAPLookbackDays
+	}
+	bucket, err := intervalDuration(req.VolumeInterval)
+	if err != nil {
+		return nil, err
+	}
+	if bucket >= 24*time.Hour {
+		return nil, fmt.Errorf("VWAP volume interval must be shorter than a day")
+	}
+
+	candles, err := e.d.Market.GetAggregatedPrice(&GetAggregatedPriceRequest{
+		Symbol:   req.Symbol,
+		Interval: req.VolumeInterval,
+		Start:    start.AddDate(0, 0, -req.VolumeLookbackDays).Unix(),
+		End:      start.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching volume profile: %w", err)
+	}
+
+	slotOf := func(t time.Time) int64 {
+		return t.UTC().Unix() % 86400 / int64(bucket/time.Second)
+	}
+	volume := make(map[int64]float64)
+	samples := make(map[int64]int)
+	for _, candle := range *candles {
+		slot := slotOf(timestampTime(uint64(candle.Timestamp)))
+		volume[slot] += candle.Volume
+		samples[slot]++
+	}
+
+	weights := make([]float64, slices)
+	total := 0.0
+	for i := range weights {
+		slot := slotOf(start.Add(sliceLength*time.Duration(i) + sliceLength/2))
+		if samples[slot] > 0 {
+			weights[i] = volume[slot] / float64(samples[slot])
+		}
+		total += weights[i]
+	}
+	if total <= 0 {
+		return uniformSchedule(slices), nil
+	}
+
+	schedule := make([]float64, slices)
+	cumulative := 0.0
+	for i, weight := range weights {
+		cumulative += weight / total
+		schedule[i] = cumulative
+	}
+	schedule[slices-1] = 1
+	return schedule, nil
+}
+
+// uniformSchedule returns cumulative targets spreading the parent evenly over the slices.
+func uniformSchedule(slices int) []float64 {
+	schedule := make([]float64, slices)
+	for i := range schedule {
+		schedule[i] = float64(i+1) / float64(slices)
+	}
+	return schedule
+}
+
+// sliceTime returns when a slice is due.
+func (r *executionRun) sliceTime(slice int) time.Time {
+	return r.StartedAt.Add(r.sliceLength * time.Duration(slice))
+}
+
+// applyChild records the latest state of a child and recomputes the parent fills.
+func (r *executionRun) applyChild(index int, order *OrderJSON) {
+	child := &r.Children[index]
+	child.Status = order.OrderStatus()
+	child.Filled, child.QuoteAmount, _ = executedQuote(order)
+	if index == r.active && child.Status.IsTerminal() {
+		r.active = -1
+	}
+
+	filled, quote := 0.0, 0.0
+	for _, c := range r.Children {
+		filled += c.Filled
+		quote += c.QuoteAmount
+	}
+	r.Filled = filled
+	r.AveragePrice = 0
+	if filled > quantityEpsilon {
+		r.AveragePrice = quote / filled
+	}
+}
+
+// finish ends the execution.
+func (r *executionRun) finish(state ExecutionState) {
+	r.State = state
+	r.EndedAt = time.Now()
+}
+
+// snapshot returns a copy of the execution that shares no memory with the run.
+func (r *executionRun) snapshot() Execution {
+	snapshot := r.Execution
+	snapshot.Children = append([]ExecutionChild(nil), r.Children...)
+	return snapshot
+}
+
+// clone returns a copy of the run that can be modified without affecting it. The schedule is
+// never modified, so it is shared.
+func (r *executionRun) clone() *executionRun {
+	c := *r
+	c.Execution = r.snapshot()
+	c.unsettled = append([]int(nil), r.unsettled...)
+	return &c
+}
+
+// changedFrom reports whether the execution differs from an earlier snapshot.
+func (e *Execution) changedFrom(before *Execution) bool {
+	if e.State != before.State || e.Filled != before.Filled || e.Error != before.Error || len(e.Children) != len(before.Children) {
+		return true
+	}
+	for i := range e.Children {
+		if e.Children[i] != before.Children[i] {
+			return true
+		}
+	}
+	return false
+}
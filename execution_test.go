@@ -0,0 +1,150 @@
+package deltadefi
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestExecutionEngineSettlesCancelledChildren(t *testing.T) {
+	tests := []struct {
+		name string
+		// failLookups makes the lookups of cancelled children fail for this many checks after the cancellation
+		failLookups int
+		// wantSecond is the quantity of the child placed for the second slice
+		wantSecond float64
+	}{
+		{name: "final fills fetched right away", wantSecond: 6},
+		{name: "final fills fetched late", failLookups: 2, wantSecond: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			var failing bool
+			exchange.lookup = func(id string) int {
+				if failing && exchange.orders[id].OrderStatus() == OrderStatusCancelled {
+					return http.StatusBadGateway
+				}
+				return http.StatusOK
+			}
+			// The first child fills further while it is being cancelled
+			exchange.beforeCancel = func(order *OrderJSON) error {
+				order.ExecutedQty = "4"
+				return nil
+			}
+
+			var errs []error
+			e := NewExecutionEngine(d, ExecutionEngineOptions{
+				OnError: func(id string, err error) { errs = append(errs, err) },
+			})
+			execution, err := e.Submit(ExecutionRequest{
+				Algorithm: ExecutionTWAP, Symbol: ADAUSDM, Side: OrderSideBuy, Quantity: 10,
+				LimitPrice: FloatPtr(0.5), Duration: 400 * time.Millisecond, Slices: 2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e.Check()
+			first, _ := e.Execution(execution.ID)
+			if len(first.Children) != 1 || first.Children[0].Quantity != 5 {
+				t.Fatalf("children after the first slice = %+v", first.Children)
+			}
+			exchange.update(first.Children[0].OrderID, func(order *OrderJSON) { order.ExecutedQty = "2" })
+
+			time.Sleep(210 * time.Millisecond)
+			failing = tt.failLookups > 0
+			for i := 0; i < tt.failLookups; i++ {
+				e.Check()
+				if got, _ := e.Execution(execution.ID); len(got.Children) != 1 {
+					t.Fatalf("next child placed before the cancelled child was settled: %+v", got.Children)
+				}
+			}
+			if len(errs) != tt.failLookups {
+				t.Errorf("%d errors reported, want %d", len(errs), tt.failLookups)
+			}
+			failing = false
+			e.Check()
+
+			got, _ := e.Execution(execution.ID)
+			if len(got.Children) != 2 {
+				t.Fatalf("children = %+v, want a second child", got.Children)
+			}
+			if got.Children[0].Filled != 4 || got.Children[0].Status != OrderStatusCancelled {
+				t.Errorf("cancelled child = %+v, want its final fills", got.Children[0])
+			}
+			if got.Children[1].Quantity != tt.wantSecond {
+				t.Errorf("second child quantity = %v, want %v", got.Children[1].Quantity, tt.wantSecond)
+			}
+		})
+	}
+}
+
+func TestExecutionEngineDoesNotLockDuringNetworkCalls(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	e := NewExecutionEngine(d, ExecutionEngineOptions{})
+	execution, err := e.Submit(ExecutionRequest{
+		Algorithm: ExecutionIceberg, Symbol: ADAUSDM, Side: OrderSideBuy, Quantity: 10,
+		LimitPrice: FloatPtr(0.5), VisibleQuantity: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Check()
+
+	// Execution and Executions must answer while Check waits for the server
+	var once sync.Once
+	blocked := make(chan bool, 1)
+	exchange.lookup = func(string) int {
+		once.Do(func() {
+			done := make(chan struct{})
+			go func() {
+				e.Executions()
+				if _, err := e.Execution(execution.ID); err != nil {
+					t.Error(err)
+				}
+				close(done)
+			}()
+			select {
+			case <-done:
+				blocked <- false
+			case <-time.After(2 * time.Second):
+				blocked <- true
+			}
+		})
+		return http.StatusOK
+	}
+
+	e.Check()
+	if <-blocked {
+		t.Fatal("Execution blocked while Check was fetching orders")
+	}
+}
+
+func TestExecutionEngineCancel(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	e := NewExecutionEngine(d, ExecutionEngineOptions{})
+	execution, err := e.Submit(ExecutionRequest{
+		Algorithm: ExecutionIceberg, Symbol: ADAUSDM, Side: OrderSideBuy, Quantity: 10,
+		LimitPrice: FloatPtr(0.5), VisibleQuantity: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Check()
+
+	if err := e.Cancel(execution.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := e.Execution(execution.ID)
+	if got.State != ExecutionStateCancelled || len(got.Children) != 1 {
+		t.Fatalf("execution = %+v", got)
+	}
+	if status := exchange.order(got.Children[0].OrderID).Status; status != "cancelled" {
+		t.Errorf("child status on the exchange = %s", status)
+	}
+	if err := e.Cancel("unknown"); err != ErrExecutionNotFound {
+		t.Errorf("Cancel(unknown) error = %v", err)
+	}
+}
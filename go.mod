@@ -2,7 +2,11 @@ module github.com/deltadefi-protocol/go-sdk
 
 go 1.23.1
 
-require github.com/sidan-lab/rum v0.3.1
+require (
+	github.com/sidan-lab/rum v0.3.1
+	golang.org/x/crypto v0.41.0
+	golang.org/x/term v0.34.0
+)
 
 require (
 	github.com/blockfrost/blockfrost-go v0.3.0 // indirect
@@ -10,5 +14,5 @@ require (
 	github.com/hashicorp/go-retryablehttp v0.7.8 // indirect
 	github.com/maestro-org/go-sdk v1.2.1 // indirect
 	github.com/sidan-lab/cardano-golang-signing-module v0.0.2 // indirect
-	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
 )
@@ -0,0 +1,358 @@
+package deltadefi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// DefaultKeystoreID is the keystore entry used when ApiConfig.KeystoreID is empty.
+const DefaultKeystoreID = "default"
+
+// ErrKeystoreEntryNotFound is returned when the keystore has no entry for the requested ID.
+var ErrKeystoreEntryNotFound = errors.New("keystore entry not found")
+
+const (
+	keystoreVersion   = 1
+	keystoreKDF       = "scrypt"
+	keystoreFileExt   = ".json"
+	keystoreDirMode   = 0o700
+	keystoreFileMode  = 0o600
+	keystoreSaltSize  = 32
+	keystoreKeyLength = 32
+)
+
+var keystoreIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// scryptParams holds the scrypt cost parameters stored alongside each entry.
+type scryptParams struct {
+	N int `json:"n"`
+	R int `json:"r"`
+	P int `json:"p"`
+}
+
+var defaultScryptParams = scryptParams{N: 1 << 15, R: 8, P: 1}
+
+// keystoreEntry is the on-disk format of a cached operation key.
+type keystoreEntry struct {
+	Version          int          `json:"version"`
+	ID               string       `json:"id"`
+	OperationKeyHash string       `json:"operation_key_hash"`
+	KDF              string       `json:"kdf"`
+	KDFParams        scryptParams `json:"kdf_params"`
+	Salt             string       `json:"salt"`
+	Nonce            string       `json:"nonce"`
+	Ciphertext       string       `json:"ciphertext"`
+	UpdatedAt        int64        `json:"updated_at"`
+}
+
+// Keystore caches encrypted operation keys in a local directory so that the operation
+// wallet can be loaded while the API is unavailable.
+// Each entry is identified by an ID, which allows keys of several accounts to share one directory.
+// Entries are encrypted with a key derived from the keystore password (scrypt + AES-GCM) and
+// bound to their OperationKeyHash. The directory must be 0700 and entries 0600.
+type Keystore struct {
+	dir      string
+	password SecretProvider
+}
+
+// NewKeystore opens (and creates if needed) a keystore directory.
+//
+// Parameters:
+//   - dir: Directory holding the keystore entries
+//   - password: Provider of the password protecting the entries
+//
+// Returns:
+//   - *Keystore: The opened keystore
+//   - error: nil on success, error if the directory cannot be created or has unsafe permissions
+func NewKeystore(dir string, password SecretProvider) (*Keystore, error) {
+	if password == nil {
+		return nil, fmt.Errorf("keystore password provider is required")
+	}
+	if err := os.MkdirAll(dir, keystoreDirMode); err != nil {
+		return nil, fmt.Errorf("creating keystore directory: %w", err)
+	}
+	if err := checkKeystorePermissions(dir, keystoreDirMode); err != nil {
+		return nil, err
+	}
+
+	return &Keystore{
+		dir:      dir,
+		password: password,
+	}, nil
+}
+
+// Save encrypts and stores the operation key response under the given ID, replacing any previous entry.
+//
+// Parameters:
+//   - id: The entry ID, e.g. an account name
+//   - key: The operation key response as returned by GetOperationKey
+//
+// Returns:
+//   - error: nil on success, error on failure
+func (k *Keystore) Save(id string, key *GetOperationKeyResponse) error {
+	if err := validateKeystoreID(id); err != nil {
+		return err
+	}
+	if key == nil || key.EncryptedOperationKey == "" || key.OperationKeyHash == "" {
+		return fmt.Errorf("operation key and hash are required")
+	}
+
+	salt := make([]byte, keystoreSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+
+	aead, err := k.cipher(salt, defaultScryptParams)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	ciphertext := aead.Seal(nil, nonce, []byte(key.EncryptedOperationKey), keystoreAdditionalData(id, key.OperationKeyHash))
+
+	entry := keystoreEntry{
+		Version:          keystoreVersion,
+		ID:               id,
+		OperationKeyHash: key.OperationKeyHash,
+		KDF:              keystoreKDF,
+		KDFParams:        defaultScryptParams,
+		Salt:             base64.StdEncoding.EncodeToString(salt),
+		Nonce:            base64.StdEncoding.EncodeToString(nonce),
+		Ciphertext:       base64.StdEncoding.EncodeToString(ciphertext),
+		UpdatedAt:        time.Now().Unix(),
+	}
+	content, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated entry behind
+	tmp, err := os.CreateTemp(k.dir, "."+id+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(keystoreFileMode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path(id))
+}
+
+// Load decrypts the entry stored under the given ID.
+//
+// Parameters:
+//   - id: The entry ID
+//
+// Returns:
+//   - *GetOperationKeyResponse: The cached encrypted operation key and its hash
+//   - error: ErrKeystoreEntryNotFound if there is no entry, other error on failure
+func (k *Keystore) Load(id string) (*GetOperationKeyResponse, error) {
+	entry, err := k.readEntry(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Version != keystoreVersion || entry.KDF != keystoreKDF {
+		return nil, fmt.Errorf("unsupported keystore entry version %d (%s)", entry.Version, entry.KDF)
+	}
+	if entry.ID != id {
+		return nil, fmt.Errorf("keystore entry %s belongs to %s", id, entry.ID)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(entry.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore salt: %w", err)
+	}
+	nonce, err := base64.StdEncoding.DecodeString(entry.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore nonce: %w", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(entry.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore ciphertext: %w", err)
+	}
+
+	aead, err := k.cipher(salt, entry.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("invalid keystore nonce size")
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, keystoreAdditionalData(id, entry.OperationKeyHash))
+	if err != nil {
+		return nil, fmt.Errorf("keystore decryption failed (wrong password or tampered entry): %w", err)
+	}
+
+	return &GetOperationKeyResponse{
+		EncryptedOperationKey: string(plaintext),
+		OperationKeyHash:      entry.OperationKeyHash,
+	}, nil
+}
+
+// OperationKeyHash returns the hash stored with an entry without decrypting it.
+//
+// Parameters:
+//   - id: The entry ID
+//
+// Returns:
+//   - string: The operation key hash of the entry
+//   - error: ErrKeystoreEntryNotFound if there is no entry, other error on failure
+func (k *Keystore) OperationKeyHash(id string) (string, error) {
+	entry, err := k.readEntry(id)
+	if err != nil {
+		return "", err
+	}
+	return entry.OperationKeyHash, nil
+}
+
+// Delete removes the entry stored under the given ID.
+//
+// Parameters:
+//   - id: The entry ID
+//
+// Returns:
+//   - error: ErrKeystoreEntryNotFound if there is no entry, other error on failure
+func (k *Keystore) Delete(id string) error {
+	if err := validateKeystoreID(id); err != nil {
+		return err
+	}
+	err := os.Remove(k.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrKeystoreEntryNotFound
+	}
+	return err
+}
+
+// List returns the IDs of all entries in the keystore, sorted alphabetically.
+//
+// Returns:
+//   - []string: Entry IDs
+//   - error: nil on success, error on failure
+func (k *Keystore) List() ([]string, error) {
+	files, err := os.ReadDir(k.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, keystoreFileExt) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, keystoreFileExt))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// path returns the file path of the entry with the given ID.
+func (k *Keystore) path(id string) string {
+	return filepath.Join(k.dir, id+keystoreFileExt)
+}
+
+// readEntry reads and parses an entry after checking the directory and file permissions.
+func (k *Keystore) readEntry(id string) (*keystoreEntry, error) {
+	if err := validateKeystoreID(id); err != nil {
+		return nil, err
+	}
+	if err := checkKeystorePermissions(k.dir, keystoreDirMode); err != nil {
+		return nil, err
+	}
+
+	path := k.path(id)
+	if err := checkKeystorePermissions(path, keystoreFileMode); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrKeystoreEntryNotFound
+		}
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry keystoreEntry
+	err = json.Unmarshal(content, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keystore entry %s: %w", id, err)
+	}
+	return &entry, nil
+}
+
+// cipher derives the entry key from the keystore password and returns an AES-GCM instance.
+func (k *Keystore) cipher(salt []byte, params scryptParams) (cipher.AEAD, error) {
+	password, err := k.password.Secret()
+	if err != nil {
+		return nil, fmt.Errorf("resolving keystore password: %w", err)
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password), salt, params.N, params.R, params.P, keystoreKeyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keystoreAdditionalData binds the ciphertext to the entry ID and operation key hash.
+func keystoreAdditionalData(id, operationKeyHash string) []byte {
+	return []byte(id + "\x00" + operationKeyHash)
+}
+
+// validateKeystoreID rejects IDs that could escape the keystore directory.
+func validateKeystoreID(id string) error {
+	if !keystoreIDPattern.MatchString(id) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("invalid keystore ID %q", id)
+	}
+	return nil
+}
+
+// checkKeystorePermissions ensures the path is not accessible by group or others.
+// Permission bits are not enforced on Windows.
+func checkKeystorePermissions(path string, maxMode os.FileMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if info.Mode().Perm()&^maxMode != 0 {
+		return fmt.Errorf("keystore path %s has unsafe permissions %#o, expected at most %#o", path, info.Mode().Perm(), maxMode)
+	}
+	return nil
+}
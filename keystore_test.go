@@ -0,0 +1,349 @@
+package deltadefi
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sidan-lab/rum"
+	"github.com/sidan-lab/rum/wallet"
+)
+
+const testMnemonic = "summer summer summer summer summer summer summer summer summer summer summer summer " +
+	"summer summer summer summer summer summer summer summer summer summer summer summer"
+
+func newTestKeystore(t *testing.T, password string) *Keystore {
+	t.Helper()
+	keystore, err := NewKeystore(filepath.Join(t.TempDir(), "keys"), StaticSecret(password))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keystore
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	keystore := newTestKeystore(t, "password")
+	key := &GetOperationKeyResponse{EncryptedOperationKey: `{"iv":"a","ciphertext":"b"}`, OperationKeyHash: "hash"}
+	if err := keystore.Save("account-1", key); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := keystore.Load("account-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, key) {
+		t.Errorf("Load() = %+v, want %+v", got, key)
+	}
+	hash, err := keystore.OperationKeyHash("account-1")
+	if err != nil || hash != "hash" {
+		t.Errorf("OperationKeyHash() = %q, %v", hash, err)
+	}
+
+	info, err := os.Stat(keystore.path("account-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != keystoreFileMode {
+		t.Errorf("entry mode = %#o, want %#o", info.Mode().Perm(), keystoreFileMode)
+	}
+}
+
+func TestKeystoreLoadRejects(t *testing.T) {
+	key := &GetOperationKeyResponse{EncryptedOperationKey: "encrypted", OperationKeyHash: "hash"}
+	tests := []struct {
+		name    string
+		tamper  func(t *testing.T, k *Keystore, entry *keystoreEntry)
+		wantErr string
+	}{
+		{
+			name: "wrong password",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				k.password = StaticSecret("other")
+			},
+			wantErr: "decryption failed",
+		},
+		{
+			name: "tampered hash",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				entry.OperationKeyHash = "other-hash"
+			},
+			wantErr: "decryption failed",
+		},
+		{
+			name: "tampered ciphertext",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				ciphertext, err := base64.StdEncoding.DecodeString(entry.Ciphertext)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ciphertext[0] ^= 0xff
+				entry.Ciphertext = base64.StdEncoding.EncodeToString(ciphertext)
+			},
+			wantErr: "decryption failed",
+		},
+		{
+			name: "moved entry",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				entry.ID = "other"
+			},
+			wantErr: "belongs to",
+		},
+		{
+			name: "unsupported version",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				entry.Version = 2
+			},
+			wantErr: "unsupported",
+		},
+		{
+			name: "world readable file",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				if err := os.Chmod(k.path("account"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "unsafe permissions",
+		},
+		{
+			name: "group accessible directory",
+			tamper: func(t *testing.T, k *Keystore, entry *keystoreEntry) {
+				if err := os.Chmod(k.dir, 0o750); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "unsafe permissions",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keystore := newTestKeystore(t, "password")
+			if err := keystore.Save("account", key); err != nil {
+				t.Fatal(err)
+			}
+			entry, err := keystore.readEntry("account")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tt.tamper(t, keystore, entry)
+			content, err := json.Marshal(entry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(keystore.path("account"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(keystore.path("account"), content, info.Mode().Perm()); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = keystore.Load("account")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewKeystoreRejectsUnsafeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewKeystore(dir, StaticSecret("password")); err == nil {
+		t.Error("NewKeystore() accepted a 0755 directory")
+	}
+	if _, err := NewKeystore(t.TempDir(), nil); err == nil {
+		t.Error("NewKeystore() accepted a nil password provider")
+	}
+}
+
+func TestKeystoreIDs(t *testing.T) {
+	keystore := newTestKeystore(t, "password")
+	key := &GetOperationKeyResponse{EncryptedOperationKey: "encrypted", OperationKeyHash: "hash"}
+
+	for _, id := range []string{"", "../escape", "a/b", "with space"} {
+		if err := keystore.Save(id, key); err == nil {
+			t.Errorf("Save(%q) accepted an invalid ID", id)
+		}
+	}
+	for _, id := range []string{"b", "a", "main.net_1"} {
+		if err := keystore.Save(id, key); err != nil {
+			t.Fatalf("Save(%q) error = %v", id, err)
+		}
+	}
+
+	ids, err := keystore.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "main.net_1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("List() = %v, want %v", ids, want)
+	}
+
+	if err := keystore.Delete("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := keystore.Delete("a"); !errors.Is(err, ErrKeystoreEntryNotFound) {
+		t.Errorf("Delete() of missing entry error = %v", err)
+	}
+	if _, err := keystore.Load("a"); !errors.Is(err, ErrKeystoreEntryNotFound) {
+		t.Errorf("Load() of missing entry error = %v", err)
+	}
+}
+
+func TestOperationKeyHash(t *testing.T) {
+	w, err := wallet.NewMnemonicWallet(testMnemonic, wallet.NewDerivationIndices())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := operationKeyHash(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != 56 {
+		t.Errorf("operationKeyHash() = %q, want 28 bytes hex", hash)
+	}
+	again, _ := operationKeyHash(w)
+	if hash != again {
+		t.Errorf("operationKeyHash() not deterministic: %q != %q", hash, again)
+	}
+}
+
+func TestLoadOperationKeyFromKeystore(t *testing.T) {
+	rootKey := newTestRootKey(t)
+	w, err := wallet.NewRootKeyWallet(rootKey, wallet.NewDerivationIndices())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := operationKeyHash(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := rum.EncryptWithCipher(rootKey, "passcode", 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		entry    *GetOperationKeyResponse
+		passcode string
+		wantErr  string
+	}{
+		{name: "matching cache", entry: &GetOperationKeyResponse{EncryptedOperationKey: encrypted, OperationKeyHash: hash}, passcode: "passcode"},
+		{name: "hash mismatch", entry: &GetOperationKeyResponse{EncryptedOperationKey: encrypted, OperationKeyHash: strings.Repeat("0", 56)}, passcode: "passcode", wantErr: "does not match"},
+		{name: "wrong passcode", entry: &GetOperationKeyResponse{EncryptedOperationKey: encrypted, OperationKeyHash: hash}, passcode: "wrong", wantErr: "decrypt"},
+		{name: "no cache", passcode: "passcode", wantErr: "keystore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			keystore := newTestKeystore(t, "password")
+			if tt.entry != nil {
+				if err := keystore.Save(DefaultKeystoreID, tt.entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d := NewDeltaDeFi(ApiConfig{ProvidedBaseUrl: server.URL, ApiKey: "key", Keystore: keystore})
+
+			err := d.LoadOperationKey(tt.passcode)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(strings.ToLower(err.Error()), tt.wantErr) {
+					t.Fatalf("LoadOperationKey() error = %v, want %q", err, tt.wantErr)
+				}
+				if d.GetOperationWallet() != nil {
+					t.Error("operation wallet set after a failed load")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadOperationKey() error = %v", err)
+			}
+			if d.GetOperationWallet() == nil {
+				t.Error("operation wallet not set")
+			}
+		})
+	}
+}
+
+// newTestRootKey returns a random bech32 "xprv" root key.
+func newTestRootKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 96)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	// Clamp the scalar as required for BIP32-Ed25519 extended keys
+	key[0] &= 0xf8
+	key[31] &= 0x1f
+	key[31] |= 0x40
+	return bech32Encode("xprv", key)
+}
+
+// bech32Encode encodes data with the bech32 checksum, without the BIP-173 length limit.
+func bech32Encode(hrp string, data []byte) string {
+	const charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	var values []byte
+	acc, bits := 0, 0
+	for _, b := range data {
+		acc = acc<<8 | int(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			values = append(values, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		values = append(values, byte(acc<<(5-bits)&31))
+	}
+
+	polymod := func(values []byte) int {
+		generator := []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+		chk := 1
+		for _, v := range values {
+			top := chk >> 25
+			chk = (chk&0x1ffffff)<<5 ^ int(v)
+			for i := 0; i < 5; i++ {
+				if top>>i&1 == 1 {
+					chk ^= generator[i]
+				}
+			}
+		}
+		return chk
+	}
+	var expanded []byte
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c>>5))
+	}
+	expanded = append(expanded, 0)
+	for _, c := range hrp {
+		expanded = append(expanded, byte(c&31))
+	}
+	mod := polymod(append(append(expanded, values...), 0, 0, 0, 0, 0, 0)) ^ 1
+	for i := 0; i < 6; i++ {
+		values = append(values, byte(mod>>(5*(5-i))&31))
+	}
+
+	var out strings.Builder
+	out.WriteString(hrp + "1")
+	for _, v := range values {
+		out.WriteByte(charset[v])
+	}
+	return out.String()
+}
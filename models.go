@@ -1,6 +1,13 @@
 package deltadefi
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
 // OrderStatus represents the various states an order can be in.
+// See ParseOrderStatus and OrderStatus.CanTransitionTo for the order lifecycle.
 type OrderStatus string
 
 const (
@@ -54,6 +61,58 @@ type OrderJSON struct {
 	Fills         []OrderExecutionRecordJSON `json:"fills,omitempty"` // Changed from *[]OrderExecutionRecordJSON to []OrderExecutionRecordJSON
 }
 
+// OriginalQuantity returns the original order quantity parsed from OrigQty.
+func (o *OrderJSON) OriginalQuantity() (float64, error) {
+	return parseQuantity(o.OrigQty)
+}
+
+// ExecutedQuantity returns the filled order quantity parsed from ExecutedQty.
+func (o *OrderJSON) ExecutedQuantity() (float64, error) {
+	return parseQuantity(o.ExecutedQty)
+}
+
+// RemainingQuantity returns the unfilled order quantity.
+func (o *OrderJSON) RemainingQuantity() (float64, error) {
+	original, err := o.OriginalQuantity()
+	if err != nil {
+		return 0, err
+	}
+	executed, err := o.ExecutedQuantity()
+	if err != nil {
+		return 0, err
+	}
+	if executed >= original {
+		return 0, nil
+	}
+	return original - executed, nil
+}
+
+// CreatedAt returns the creation time of the order. Timestamps are accepted in seconds or milliseconds.
+func (o *OrderJSON) CreatedAt() time.Time {
+	return timestampTime(o.CreatedTime)
+}
+
+// timestampTime converts a server timestamp in seconds or milliseconds to a time.
+func timestampTime(ts uint64) time.Time {
+	if ts >= 1e12 {
+		return time.UnixMilli(int64(ts))
+	}
+	return time.Unix(int64(ts), 0)
+}
+
+// parseQuantity parses a decimal quantity string. A missing quantity is an error rather than zero,
+// so that an incomplete order record is never mistaken for an unfilled one.
+func parseQuantity(qty string) (float64, error) {
+	if qty == "" {
+		return 0, fmt.Errorf("missing quantity")
+	}
+	value, err := strconv.ParseFloat(qty, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", qty, err)
+	}
+	return value, nil
+}
+
 // TransactionStatus represents the various states a transaction can be in.
 type TransactionStatus string
 
@@ -0,0 +1,31 @@
+package deltadefi
+
+import "testing"
+
+func TestOrderQuantities(t *testing.T) {
+	tests := []struct {
+		name          string
+		order         OrderJSON
+		wantRemaining float64
+		wantErr       bool
+	}{
+		{name: "unfilled", order: OrderJSON{OrigQty: "10", ExecutedQty: "0"}, wantRemaining: 10},
+		{name: "partially filled", order: OrderJSON{OrigQty: "10", ExecutedQty: "2.5"}, wantRemaining: 7.5},
+		{name: "filled", order: OrderJSON{OrigQty: "10", ExecutedQty: "10"}, wantRemaining: 0},
+		{name: "overfilled", order: OrderJSON{OrigQty: "10", ExecutedQty: "10.0001"}, wantRemaining: 0},
+		{name: "missing executed quantity", order: OrderJSON{OrigQty: "10"}, wantErr: true},
+		{name: "missing original quantity", order: OrderJSON{ExecutedQty: "0"}, wantErr: true},
+		{name: "invalid quantity", order: OrderJSON{OrigQty: "ten", ExecutedQty: "0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.order.RemainingQuantity()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RemainingQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantRemaining {
+				t.Errorf("RemainingQuantity() = %v, want %v", got, tt.wantRemaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,862 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultOCOPollInterval is how often OCO groups are checked for fills when no interval is configured.
+const DefaultOCOPollInterval = 2 * time.Second
+
+// ErrOCOGroupNotFound is returned when no OCO group exists for an ID.
+var ErrOCOGroupNotFound = errors.New("OCO group not found")
+
+// quantityEpsilon absorbs floating point noise when comparing order quantities.
+const quantityEpsilon = 1e-9
+
+// OCOLegState represents the state of a single OCO leg.
+type OCOLegState string
+
+const (
+	OCOLegStateActive    OCOLegState = "active"
+	OCOLegStateFilled    OCOLegState = "filled"
+	OCOLegStateCancelled OCOLegState = "cancelled"
+	OCOLegStateFailed    OCOLegState = "failed"
+)
+
+// OCOGroupState represents the state of an OCO group or bracket.
+type OCOGroupState string
+
+const (
+	// OCOGroupStateAwaitingEntry means a bracket is waiting for its entry order to complete
+	OCOGroupStateAwaitingEntry OCOGroupState = "awaiting_entry"
+	// OCOGroupStateActive means the legs are live and watched for fills
+	OCOGroupStateActive OCOGroupState = "active"
+	// OCOGroupStateDone means the group quantity was filled or every leg ended
+	OCOGroupStateDone OCOGroupState = "done"
+)
+
+// OCOLeg is one side of an OCO group: a resting order, or a stop held by the TriggerEngine.
+type OCOLeg struct {
+	// Order is the order placed for this leg; all legs of a group share the same quantity
+	Order BuildPlaceOrderTransactionRequest
+	// StopPrice turns the leg into a client-side stop trigger placing Order when reached (optional)
+	StopPrice float64
+	// OrderID is the current order of the leg (for stop legs, set once the stop fired)
+	OrderID string
+	// TriggerID is the trigger of a stop leg
+	TriggerID string
+	// Filled is the cumulative quantity filled by this leg, including orders it replaced
+	Filled float64
+	// State is the state of the leg
+	State OCOLegState
+	// Error describes the last failure of the leg
+	Error string
+
+	// replacedFilled is the quantity filled by orders of this leg that were replaced when resizing
+	replacedFilled float64
+}
+
+// isStop reports whether the leg is held as a client-side stop trigger.
+func (l *OCOLeg) isStop() bool {
+	return l.StopPrice > 0
+}
+
+// OCOGroup is a set of orders where a fill on one leg cancels (or, for partial fills, shrinks) the others.
+// A bracket is an OCO group with an entry order; its exit legs are placed once the entry completes.
+type OCOGroup struct {
+	// ID identifies the group
+	ID string
+	// State is the state of the group
+	State OCOGroupState
+	// Quantity is the total quantity shared by the legs
+	Quantity float64
+	// Entry is the entry order of a bracket, nil for plain OCO groups
+	Entry *OCOLeg
+	// Legs are the mutually exclusive legs
+	Legs []OCOLeg
+	// CreatedAt is when the group was placed
+	CreatedAt time.Time
+
+	// takeProfitPrice and stopPrice define the exits of a bracket
+	takeProfitPrice float64
+	stopPrice       float64
+}
+
+// BracketRequest describes an entry order protected by a take-profit and a stop.
+type BracketRequest struct {
+	// Entry is the entry order
+	Entry BuildPlaceOrderTransactionRequest
+	// TakeProfitPrice is the limit price of the take-profit exit
+	TakeProfitPrice float64
+	// StopPrice is the activation price of the stop exit, which is placed as a market order
+	StopPrice float64
+}
+
+// OCOManagerOptions configures an OCOManager.
+type OCOManagerOptions struct {
+	// PollInterval is how often legs are checked for fills (defaults to DefaultOCOPollInterval)
+	PollInterval time.Duration
+	// Triggers holds the stop legs; required for legs with a StopPrice and for brackets
+	Triggers *TriggerEngine
+	// OnUpdate is called whenever a group changes state (optional)
+	OnUpdate func(group OCOGroup)
+	// OnError is called when checking or adjusting a group fails (optional)
+	OnError func(groupId string, err error)
+}
+
+// OCOManager emulates one-cancels-the-other and bracket orders on top of limit and market orders.
+// Fills are detected by polling the order records of each leg. When a leg fills completely its
+// siblings are cancelled; when it fills partially the siblings are resized to the remaining quantity.
+// When a stop leg fires, its siblings are cancelled before the stop order is placed, and the stop
+// order covers only the quantity they did not fill.
+// An OCOManager is safe for concurrent use.
+type OCOManager struct {
+	d    *DeltaDeFi
+	opts OCOManagerOptions
+
+	mu     sync.Mutex
+	groups map[string]*OCOGroup
+	// work serializes the network operations on each group, which run without holding mu
+	work map[string]*sync.Mutex
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewOCOManager creates an OCO manager. Call Start to begin watching the groups.
+//
+// Parameters:
+//   - d: The client used to place, cancel and fetch orders
+//   - opts: Manager options
+//
+// Returns:
+//   - *OCOManager: The manager, not yet started
+func NewOCOManager(d *DeltaDeFi, opts OCOManagerOptions) *OCOManager {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultOCOPollInterval
+	}
+	return &OCOManager{
+		d:      d,
+		opts:   opts,
+		groups: make(map[string]*OCOGroup),
+		work:   make(map[string]*sync.Mutex),
+	}
+}
+
+// PlaceOCO places the legs of a one-cancels-the-other group.
+// If any leg cannot be placed, the legs already placed are cancelled.
+//
+// Parameters:
+//   - legs: At least two legs with the same symbol and quantity
+//
+// Returns:
+//   - OCOGroup: The placed group
+//   - error: nil on success, error on failure
+func (m *OCOManager) PlaceOCO(legs []OCOLeg) (OCOGroup, error) {
+	if len(legs) < 2 {
+		return OCOGroup{}, fmt.Errorf("an OCO group needs at least two legs")
+	}
+	quantity := legs[0].Order.Quantity
+	for _, leg := range legs {
+		if leg.Order.Symbol != legs[0].Order.Symbol {
+			return OCOGroup{}, fmt.Errorf("all OCO legs must have the same symbol")
+		}
+		if leg.Order.Quantity != quantity || quantity <= 0 {
+			return OCOGroup{}, fmt.Errorf("all OCO legs must have the same positive quantity")
+		}
+		if leg.isStop() && m.opts.Triggers == nil {
+			return OCOGroup{}, fmt.Errorf("stop legs require a TriggerEngine")
+		}
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return OCOGroup{}, err
+	}
+	group := &OCOGroup{
+		ID:        id,
+		State:     OCOGroupStateActive,
+		Quantity:  quantity,
+		Legs:      make([]OCOLeg, len(legs)),
+		CreatedAt: time.Now(),
+	}
+	copy(group.Legs, legs)
+
+	if err := m.placeLegs(group); err != nil {
+		return OCOGroup{}, err
+	}
+	return m.add(group), nil
+}
+
+// PlaceBracket places an entry order. Once the entry completes, a take-profit limit order and a
+// stop trigger are placed as an OCO group sized to the filled entry quantity.
+//
+// Parameters:
+//   - req: The entry order and exit prices
+//
+// Returns:
+//   - OCOGroup: The bracket, awaiting its entry
+//   - error: nil on success, error on failure
+func (m *OCOManager) PlaceBracket(req BracketRequest) (OCOGroup, error) {
+	if m.opts.Triggers == nil {
+		return OCOGroup{}, fmt.Errorf("brackets require a TriggerEngine")
+	}
+	if req.TakeProfitPrice <= 0 || req.StopPrice <= 0 {
+		return OCOGroup{}, fmt.Errorf("take-profit and stop prices must be positive")
+	}
+	if req.Entry.Side == OrderSideBuy && !(req.StopPrice < req.TakeProfitPrice) ||
+		req.Entry.Side == OrderSideSell && !(req.StopPrice > req.TakeProfitPrice) {
+		return OCOGroup{}, fmt.Errorf("stop price must be on the losing side of the take-profit price")
+	}
+
+	id, err := newRandomID()
+	if err != nil {
+		return OCOGroup{}, err
+	}
+
+	res, err := m.d.PostOrder(&req.Entry)
+	if err != nil {
+		return OCOGroup{}, fmt.Errorf("placing entry order: %w", err)
+	}
+
+	group := &OCOGroup{
+		ID:    id,
+		State: OCOGroupStateAwaitingEntry,
+		Entry: &OCOLeg{
+			Order:   req.Entry,
+			OrderID: res.Order.OrderID,
+			State:   OCOLegStateActive,
+		},
+		CreatedAt:       time.Now(),
+		takeProfitPrice: req.TakeProfitPrice,
+		stopPrice:       req.StopPrice,
+	}
+	return m.add(group), nil
+}
+
+// Cancel cancels every active leg (and the pending entry of a bracket) of a group.
+//
+// Parameters:
+//   - id: The group ID
+//
+// Returns:
+//   - error: ErrOCOGroupNotFound if unknown, otherwise the first cancellation error
+func (m *OCOManager) Cancel(id string) error {
+	var firstErr error
+	_, after, ok := m.modify(id, true, func(group *OCOGroup) {
+		if group.Entry != nil && group.Entry.State == OCOLegStateActive {
+			if err := m.cancelLeg(group.Entry); err != nil {
+				firstErr = err
+			}
+		}
+		for i := range group.Legs {
+			if group.Legs[i].State != OCOLegStateActive {
+				continue
+			}
+			if err := m.cancelLeg(&group.Legs[i]); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		group.State = OCOGroupStateDone
+	})
+	if !ok {
+		return ErrOCOGroupNotFound
+	}
+
+	if m.opts.OnUpdate != nil {
+		m.opts.OnUpdate(after)
+	}
+	return firstErr
+}
+
+// Group returns a group by ID.
+//
+// Parameters:
+//   - id: The group ID
+//
+// Returns:
+//   - OCOGroup: The group
+//   - error: ErrOCOGroupNotFound if unknown
+func (m *OCOManager) Group(id string) (OCOGroup, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	group, ok := m.groups[id]
+	if !ok {
+		return OCOGroup{}, ErrOCOGroupNotFound
+	}
+	return group.snapshot(), nil
+}
+
+// Groups returns all groups sorted by creation time.
+func (m *OCOManager) Groups() []OCOGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	groups := make([]OCOGroup, 0, len(m.groups))
+	for _, group := range m.groups {
+		groups = append(groups, group.snapshot())
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].CreatedAt.Before(groups[j].CreatedAt) })
+	return groups
+}
+
+// Start begins watching the groups in a background goroutine. Calling Start on a running manager has no effect.
+func (m *OCOManager) Start() {
+	m.runMu.Lock()
+	defer m.runMu.Unlock()
+	if m.done != nil {
+		return
+	}
+	m.done = make(chan struct{})
+
+	m.wg.Add(1)
+	go m.run(m.done)
+}
+
+// Stop stops watching the groups. Live legs are left in place.
+func (m *OCOManager) Stop() {
+	m.runMu.Lock()
+	if m.done == nil {
+		m.runMu.Unlock()
+		return
+	}
+	close(m.done)
+	m.done = nil
+	m.runMu.Unlock()
+
+	m.wg.Wait()
+}
+
+// Check runs one round of fill detection over every group. It is called periodically while
+// the manager runs and can be called directly, e.g. after a fill event. Groups that another
+// Check or Cancel is working on are skipped until the next round.
+func (m *OCOManager) Check() {
+	type groupError struct {
+		id  string
+		err error
+	}
+	var (
+		updates []OCOGroup
+		errs    []groupError
+	)
+
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.groups))
+	for id, group := range m.groups {
+		if group.State != OCOGroupStateDone {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.Unlock()
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		var checkErr error
+		before, after, ok := m.modify(id, false, func(group *OCOGroup) {
+			switch group.State {
+			case OCOGroupStateDone:
+			case OCOGroupStateAwaitingEntry:
+				checkErr = m.checkEntry(group)
+			default:
+				checkErr = m.checkLegs(group)
+			}
+		})
+		if !ok {
+			continue
+		}
+		if checkErr != nil {
+			errs = append(errs, groupError{id: id, err: checkErr})
+		}
+		if after.changedFrom(&before) {
+			updates = append(updates, after)
+		}
+	}
+
+	// Callbacks run without the lock so they may call back into the manager
+	if m.opts.OnError != nil {
+		for _, e := range errs {
+			m.opts.OnError(e.id, e.err)
+		}
+	}
+	if m.opts.OnUpdate != nil {
+		for _, group := range updates {
+			m.opts.OnUpdate(group)
+		}
+	}
+}
+
+// add stores a newly placed group and returns its snapshot.
+func (m *OCOManager) add(group *OCOGroup) OCOGroup {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups[group.ID] = group
+	m.work[group.ID] = &sync.Mutex{}
+	return group.snapshot()
+}
+
+// modify runs fn on a copy of a group without holding m.mu, so that Group, Groups and other
+// groups are not blocked by its network calls, and then stores the copy. Calls for the same
+// group are serialized; with wait false a group that is already being worked on is skipped.
+// It returns the group before and after fn, and false if the group is unknown or was skipped.
+func (m *OCOManager) modify(id string, wait bool, fn func(group *OCOGroup)) (OCOGroup, OCOGroup, bool) {
+	m.mu.Lock()
+	work, ok := m.work[id]
+	m.mu.Unlock()
+	if !ok {
+		return OCOGroup{}, OCOGroup{}, false
+	}
+	if wait {
+		work.Lock()
+	} else if !work.TryLock() {
+		return OCOGroup{}, OCOGroup{}, false
+	}
+	defer work.Unlock()
+
+	m.mu.Lock()
+	group := m.groups[id]
+	before := group.snapshot()
+	m.mu.Unlock()
+
+	working := before.snapshot()
+	fn(&working)
+
+	m.mu.Lock()
+	*group = working
+	after := group.snapshot()
+	m.mu.Unlock()
+	return before, after, true
+}
+
+// run calls Check periodically until done is closed.
+func (m *OCOManager) run(done chan struct{}) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// checkEntry places the exit legs of a bracket once its entry order completed.
+// It works on a copy of the group obtained through modify.
+func (m *OCOManager) checkEntry(group *OCOGroup) error {
+	entry, err := m.d.fetchOrder(group.Entry.OrderID)
+	if err != nil {
+		return err
+	}
+	filled, err := entry.ExecutedQuantity()
+	if err != nil {
+		return err
+	}
+	group.Entry.Filled = filled
+
+	if !entry.OrderStatus().IsTerminal() {
+		return nil
+	}
+	if filled <= quantityEpsilon {
+		group.Entry.State = OCOLegStateCancelled
+		group.State = OCOGroupStateDone
+		return nil
+	}
+	group.Entry.State = OCOLegStateFilled
+
+	exitSide := OrderSideSell
+	if group.Entry.Order.Side == OrderSideSell {
+		exitSide = OrderSideBuy
+	}
+	group.Quantity = filled
+	group.Legs = []OCOLeg{
+		{
+			Order: BuildPlaceOrderTransactionRequest{
+				Symbol:   group.Entry.Order.Symbol,
+				Side:     exitSide,
+				Type:     OrderTypeLimit,
+				Quantity: filled,
+				Price:    FloatPtr(group.takeProfitPrice),
+			},
+		},
+		{
+			Order: BuildPlaceOrderTransactionRequest{
+				Symbol:   group.Entry.Order.Symbol,
+				Side:     exitSide,
+				Type:     OrderTypeMarket,
+				Quantity: filled,
+			},
+			StopPrice: group.stopPrice,
+		},
+	}
+	if err := m.placeLegs(group); err != nil {
+		group.State = OCOGroupStateDone
+		return fmt.Errorf("placing bracket exits: %w", err)
+	}
+	group.State = OCOGroupStateActive
+	return nil
+}
+
+// checkLegs refreshes the fills of every leg and cancels or resizes the siblings.
+// It works on a copy of the group obtained through modify.
+func (m *OCOManager) checkLegs(group *OCOGroup) error {
+	var firstErr error
+	recordErr := func(leg *OCOLeg, err error) {
+		leg.Error = err.Error()
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	previousFilled := group.filled()
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		if leg.State != OCOLegStateActive {
+			continue
+		}
+		if err := m.refreshLeg(leg); err != nil {
+			recordErr(leg, err)
+		}
+	}
+
+	remaining := group.Quantity - group.filled()
+	if remaining <= quantityEpsilon {
+		for i := range group.Legs {
+			leg := &group.Legs[i]
+			if leg.State != OCOLegStateActive {
+				continue
+			}
+			if err := m.cancelLeg(leg); err != nil {
+				recordErr(leg, err)
+			}
+		}
+		group.State = OCOGroupStateDone
+		return firstErr
+	}
+
+	if group.filled() > previousFilled+quantityEpsilon {
+		for i := range group.Legs {
+			leg := &group.Legs[i]
+			if leg.State != OCOLegStateActive {
+				continue
+			}
+			if err := m.resizeLeg(group.ID, leg, remaining); err != nil {
+				recordErr(leg, err)
+			}
+		}
+	}
+
+	if !group.hasActiveLeg() {
+		group.State = OCOGroupStateDone
+	}
+	return firstErr
+}
+
+// placeLegs places every leg of the group, cancelling the placed ones if one fails.
+func (m *OCOManager) placeLegs(group *OCOGroup) error {
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		leg.State = OCOLegStateActive
+		if err := m.placeLeg(group.ID, leg); err != nil {
+			for j := 0; j < i; j++ {
+				if cancelErr := m.cancelLeg(&group.Legs[j]); cancelErr != nil {
+					group.Legs[j].Error = cancelErr.Error()
+				}
+			}
+			leg.State = OCOLegStateFailed
+			leg.Error = err.Error()
+			return err
+		}
+	}
+	return nil
+}
+
+// placeLeg places the order or registers the stop trigger of a leg of the group.
+func (m *OCOManager) placeLeg(groupID string, leg *OCOLeg) error {
+	if leg.isStop() {
+		trigger, err := m.opts.Triggers.addWithHook(Trigger{
+			Type:         TriggerTypeStop,
+			TriggerPrice: leg.StopPrice,
+			Order:        leg.Order,
+		}, func(trigger Trigger) (float64, error) {
+			return m.beforeStop(groupID, trigger.ID)
+		})
+		if err != nil {
+			return err
+		}
+		leg.TriggerID = trigger.ID
+		return nil
+	}
+
+	res, err := m.d.PostOrder(&leg.Order)
+	if err != nil {
+		return err
+	}
+	leg.OrderID = res.Order.OrderID
+	return nil
+}
+
+// beforeStop runs when a stop leg of the group fires, before its order is placed. It cancels the
+// other legs, so that e.g. the take-profit of a bracket cannot fill as well, and returns the
+// quantity the stop order has to cover, zero if nothing is left.
+func (m *OCOManager) beforeStop(groupID, triggerID string) (float64, error) {
+	var (
+		quantity float64
+		stopErr  error
+	)
+	before, after, ok := m.modify(groupID, true, func(group *OCOGroup) {
+		quantity, stopErr = m.clearForStop(group, triggerID)
+	})
+	if !ok {
+		return 0, ErrOCOGroupNotFound
+	}
+	if m.opts.OnUpdate != nil && after.changedFrom(&before) {
+		m.opts.OnUpdate(after)
+	}
+	return quantity, stopErr
+}
+
+// clearForStop cancels the siblings of the stop leg with the given trigger and sizes the stop order
+// to what they did not fill. It works on a copy of the group obtained through modify.
+func (m *OCOManager) clearForStop(group *OCOGroup, triggerID string) (float64, error) {
+	stop := -1
+	for i := range group.Legs {
+		if group.Legs[i].TriggerID == triggerID {
+			stop = i
+		}
+	}
+	if stop < 0 {
+		return 0, nil
+	}
+	if group.State == OCOGroupStateDone || group.Legs[stop].State != OCOLegStateActive {
+		group.Legs[stop].State = OCOLegStateCancelled
+		return 0, nil
+	}
+
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		if i == stop || leg.State != OCOLegStateActive {
+			continue
+		}
+		err := m.cancelLeg(leg)
+		if err == nil {
+			continue
+		}
+		if leg.isStop() && leg.OrderID == "" {
+			// A sibling stop firing at the same time finds its leg cancelled and places nothing
+			leg.State = OCOLegStateCancelled
+			continue
+		}
+		// The cancellation may have failed because the order filled in the meantime
+		if refreshErr := m.refreshLeg(leg); refreshErr != nil || leg.State == OCOLegStateActive {
+			leg.Error = err.Error()
+			return 0, fmt.Errorf("cancelling leg before the stop: %w", err)
+		}
+	}
+
+	// Take the final fills of the cancelled siblings into account
+	for i := range group.Legs {
+		leg := &group.Legs[i]
+		if i == stop || leg.State != OCOLegStateCancelled || leg.OrderID == "" {
+			continue
+		}
+		if err := m.refreshLeg(leg); err != nil {
+			leg.Error = err.Error()
+			return 0, err
+		}
+	}
+
+	stopLeg := &group.Legs[stop]
+	remaining := group.Quantity - group.filled()
+	if remaining <= quantityEpsilon {
+		stopLeg.State = OCOLegStateCancelled
+		if !group.hasActiveLeg() {
+			group.State = OCOGroupStateDone
+		}
+		return 0, nil
+	}
+	stopLeg.Order.Quantity = remaining
+	return remaining, nil
+}
+
+// refreshLeg updates the cumulative fill and state of a leg.
+func (m *OCOManager) refreshLeg(leg *OCOLeg) error {
+	if leg.isStop() && leg.OrderID == "" {
+		trigger, err := m.opts.Triggers.Trigger(leg.TriggerID)
+		if err != nil {
+			return err
+		}
+		switch trigger.State {
+		case TriggerStateFired:
+			leg.OrderID = trigger.OrderID
+		case TriggerStateFailed:
+			leg.State = OCOLegStateFailed
+			leg.Error = trigger.Error
+			return nil
+		case TriggerStateCancelled:
+			leg.State = OCOLegStateCancelled
+			return nil
+		default:
+			return nil
+		}
+	}
+
+	order, err := m.d.fetchOrder(leg.OrderID)
+	if err != nil {
+		return err
+	}
+	executed, err := order.ExecutedQuantity()
+	if err != nil {
+		return err
+	}
+	leg.Filled = leg.replacedFilled + executed
+
+	switch order.OrderStatus() {
+	case OrderStatusClosed:
+		leg.State = OCOLegStateFilled
+	case OrderStatusCancelled:
+		leg.State = OCOLegStateCancelled
+	case OrderStatusFailed:
+		leg.State = OCOLegStateFailed
+	}
+	return nil
+}
+
+// cancelLeg cancels the live order or pending trigger of a leg.
+func (m *OCOManager) cancelLeg(leg *OCOLeg) error {
+	if leg.isStop() && leg.OrderID == "" {
+		if leg.TriggerID == "" {
+			leg.State = OCOLegStateCancelled
+			return nil
+		}
+		err := m.opts.Triggers.Cancel(leg.TriggerID)
+		if err == nil {
+			leg.State = OCOLegStateCancelled
+			return nil
+		}
+		// The stop may have fired in the meantime; cancel its order instead
+		trigger, fetchErr := m.opts.Triggers.Trigger(leg.TriggerID)
+		if fetchErr != nil || trigger.OrderID == "" {
+			return err
+		}
+		leg.OrderID = trigger.OrderID
+	}
+	if leg.OrderID == "" {
+		leg.State = OCOLegStateCancelled
+		return nil
+	}
+
+	if _, err := m.d.CancelOrder(leg.OrderID); err != nil {
+		return err
+	}
+	leg.State = OCOLegStateCancelled
+	return nil
+}
+
+// resizeLeg shrinks the outstanding quantity of a leg of the group to remaining.
+func (m *OCOManager) resizeLeg(groupID string, leg *OCOLeg, remaining float64) error {
+	if leg.isStop() && leg.OrderID == "" {
+		if leg.Order.Quantity <= remaining+quantityEpsilon {
+			return nil
+		}
+		if err := m.opts.Triggers.Cancel(leg.TriggerID); err != nil {
+			return err
+		}
+		leg.Order.Quantity = remaining
+		return m.placeLeg(groupID, leg)
+	}
+
+	order, err := m.d.fetchOrder(leg.OrderID)
+	if err != nil {
+		return err
+	}
+	outstanding, err := order.RemainingQuantity()
+	if err != nil {
+		return err
+	}
+	if outstanding <= remaining+quantityEpsilon {
+		return nil
+	}
+	executed, err := order.ExecutedQuantity()
+	if err != nil {
+		return err
+	}
+
+	// ReplaceOrder re-places the requested quantity minus what the original already executed
+	request := leg.Order
+	request.Quantity = remaining + executed
+	result, err := m.d.ReplaceOrder(leg.OrderID, &request)
+	leg.replacedFilled += result.FilledQuantity
+	switch result.Outcome {
+	case ReplaceOutcomeReplaced:
+		leg.OrderID = result.Replacement.Order.OrderID
+		leg.Order.Quantity = remaining
+		return nil
+	case ReplaceOutcomeOriginalFilled, ReplaceOutcomeCancelledNoRemainder:
+		leg.OrderID = ""
+		leg.Filled = leg.replacedFilled
+		leg.State = OCOLegStateFilled
+		return nil
+	case ReplaceOutcomeCancelOnly:
+		leg.OrderID = ""
+		leg.Filled = leg.replacedFilled
+		leg.State = OCOLegStateFailed
+	}
+	return err
+}
+
+// filled returns the quantity filled across all legs.
+func (g *OCOGroup) filled() float64 {
+	total := 0.0
+	for _, leg := range g.Legs {
+		total += leg.Filled
+	}
+	return total
+}
+
+// hasActiveLeg reports whether any leg is still live.
+func (g *OCOGroup) hasActiveLeg() bool {
+	for _, leg := range g.Legs {
+		if leg.State == OCOLegStateActive {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot returns a deep copy of the group.
+func (g *OCOGroup) snapshot() OCOGroup {
+	snapshot := *g
+	snapshot.Legs = make([]OCOLeg, len(g.Legs))
+	copy(snapshot.Legs, g.Legs)
+	if g.Entry != nil {
+		entry := *g.Entry
+		snapshot.Entry = &entry
+	}
+	return snapshot
+}
+
+// changedFrom reports whether the state, fills or orders of the group differ from before.
+func (g *OCOGroup) changedFrom(before *OCOGroup) bool {
+	if g.State != before.State || len(g.Legs) != len(before.Legs) {
+		return true
+	}
+	if g.Entry != nil && before.Entry != nil && (g.Entry.State != before.Entry.State || g.Entry.Filled != before.Entry.Filled) {
+		return true
+	}
+	for i := range g.Legs {
+		if g.Legs[i].State != before.Legs[i].State || g.Legs[i].Filled != before.Legs[i].Filled || g.Legs[i].OrderID != before.Legs[i].OrderID {
+			return true
+		}
+	}
+	return false
+}
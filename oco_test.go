@@ -0,0 +1,273 @@
+package deltadefi
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// placeTestOCO places a buy and a sell limit leg of quantity 10.
+func placeTestOCO(t *testing.T, m *OCOManager) OCOGroup {
+	t.Helper()
+	group, err := m.PlaceOCO([]OCOLeg{
+		{Order: BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.4)}},
+		{Order: BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideSell, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.6)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return group
+}
+
+func TestOCOManagerCheck(t *testing.T) {
+	tests := []struct {
+		name         string
+		fill         func(order *OrderJSON)
+		lookupStatus int
+		wantState    OCOGroupState
+		wantLegs     []OCOLegState
+		wantQuantity float64
+		wantUpdate   bool
+		wantErr      bool
+	}{
+		{
+			name:      "no fill",
+			wantState: OCOGroupStateActive,
+			wantLegs:  []OCOLegState{OCOLegStateActive, OCOLegStateActive},
+		},
+		{
+			name:       "full fill cancels the sibling",
+			fill:       func(order *OrderJSON) { order.Status, order.ExecutedQty = "closed", "10" },
+			wantState:  OCOGroupStateDone,
+			wantLegs:   []OCOLegState{OCOLegStateFilled, OCOLegStateCancelled},
+			wantUpdate: true,
+		},
+		{
+			name:         "partial fill resizes the sibling",
+			fill:         func(order *OrderJSON) { order.ExecutedQty = "4" },
+			wantState:    OCOGroupStateActive,
+			wantLegs:     []OCOLegState{OCOLegStateActive, OCOLegStateActive},
+			wantQuantity: 6,
+			wantUpdate:   true,
+		},
+		{
+			name:         "lookup failure is reported",
+			lookupStatus: http.StatusBadGateway,
+			wantState:    OCOGroupStateActive,
+			wantLegs:     []OCOLegState{OCOLegStateActive, OCOLegStateActive},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			var (
+				updates []OCOGroup
+				errs    []error
+			)
+			m := NewOCOManager(d, OCOManagerOptions{
+				OnUpdate: func(group OCOGroup) { updates = append(updates, group) },
+				OnError:  func(id string, err error) { errs = append(errs, err) },
+			})
+			group := placeTestOCO(t, m)
+			if tt.fill != nil {
+				exchange.update(group.Legs[0].OrderID, tt.fill)
+			}
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+
+			m.Check()
+
+			got, err := m.Group(group.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.State != tt.wantState {
+				t.Errorf("State = %s, want %s", got.State, tt.wantState)
+			}
+			for i, want := range tt.wantLegs {
+				if got.Legs[i].State != want {
+					t.Errorf("leg %d State = %s, want %s", i, got.Legs[i].State, want)
+				}
+			}
+			if tt.wantQuantity > 0 {
+				sibling := exchange.order(got.Legs[1].OrderID)
+				if quantity, _ := sibling.RemainingQuantity(); quantity != tt.wantQuantity {
+					t.Errorf("sibling remaining = %v, want %v", quantity, tt.wantQuantity)
+				}
+				if got.Legs[1].OrderID == group.Legs[1].OrderID {
+					t.Error("sibling was not replaced")
+				}
+			}
+			if (len(updates) > 0) != tt.wantUpdate {
+				t.Errorf("updates = %d, want update %v", len(updates), tt.wantUpdate)
+			}
+			if (len(errs) > 0) != tt.wantErr {
+				t.Errorf("errors = %v, want error %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOCOManagerDoesNotLockDuringNetworkCalls(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	m := NewOCOManager(d, OCOManagerOptions{})
+	group := placeTestOCO(t, m)
+
+	// Group and Groups must answer while Check waits for the server
+	var once sync.Once
+	blocked := make(chan bool, 1)
+	exchange.lookup = func(string) int {
+		once.Do(func() {
+			done := make(chan struct{})
+			go func() {
+				m.Groups()
+				if _, err := m.Group(group.ID); err != nil {
+					t.Error(err)
+				}
+				close(done)
+			}()
+			select {
+			case <-done:
+				blocked <- false
+			case <-time.After(2 * time.Second):
+				blocked <- true
+			}
+		})
+		return http.StatusOK
+	}
+
+	m.Check()
+	if <-blocked {
+		t.Fatal("Group blocked while Check was fetching orders")
+	}
+}
+
+func TestOCOManagerCancel(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	m := NewOCOManager(d, OCOManagerOptions{})
+	group := placeTestOCO(t, m)
+
+	if err := m.Cancel(group.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Group(group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != OCOGroupStateDone {
+		t.Errorf("State = %s, want done", got.State)
+	}
+	for _, leg := range got.Legs {
+		if leg.State != OCOLegStateCancelled || exchange.order(leg.OrderID).Status != "cancelled" {
+			t.Errorf("leg %s not cancelled", leg.OrderID)
+		}
+	}
+	if err := m.Cancel("unknown"); err != ErrOCOGroupNotFound {
+		t.Errorf("Cancel(unknown) error = %v", err)
+	}
+}
+
+func TestBracketStopCancelsTakeProfit(t *testing.T) {
+	tests := []struct {
+		name string
+		// fill changes the take-profit order before the stop fires
+		fill          func(order *OrderJSON)
+		wantStop      float64
+		wantTPStatus  string
+		wantTPState   OCOLegState
+		wantGroupDone bool
+	}{
+		{
+			name:         "untouched take-profit",
+			wantStop:     10,
+			wantTPStatus: "cancelled",
+			wantTPState:  OCOLegStateCancelled,
+		},
+		{
+			name:         "partially filled take-profit",
+			fill:         func(order *OrderJSON) { order.ExecutedQty = "4" },
+			wantStop:     6,
+			wantTPStatus: "cancelled",
+			wantTPState:  OCOLegStateCancelled,
+		},
+		{
+			name:          "filled take-profit",
+			fill:          func(order *OrderJSON) { order.Status, order.ExecutedQty = "closed", "10" },
+			wantTPStatus:  "closed",
+			wantTPState:   OCOLegStateFilled,
+			wantGroupDone: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			engine, err := NewTriggerEngine(d, TriggerEngineOptions{PollInterval: time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine.Start()
+			defer engine.Stop()
+			m := NewOCOManager(d, OCOManagerOptions{Triggers: engine})
+
+			bracket, err := m.PlaceBracket(BracketRequest{
+				Entry:           BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5)},
+				TakeProfitPrice: 0.6,
+				StopPrice:       0.4,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			exchange.update(bracket.Entry.OrderID, func(order *OrderJSON) { order.Status, order.ExecutedQty = "closed", "10" })
+			m.Check()
+			active, err := m.Group(bracket.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if active.State != OCOGroupStateActive {
+				t.Fatalf("State = %s, want active", active.State)
+			}
+			takeProfit, stop := active.Legs[0], active.Legs[1]
+			if tt.fill != nil {
+				exchange.update(takeProfit.OrderID, tt.fill)
+			}
+
+			engine.OnPrice(ADAUSDM, 0.4)
+			var trigger Trigger
+			waitFor(t, func() bool {
+				trigger, _ = engine.Trigger(stop.TriggerID)
+				return trigger.State != TriggerStateFiring
+			})
+
+			if status := exchange.order(takeProfit.OrderID).Status; status != tt.wantTPStatus {
+				t.Errorf("take-profit status = %s, want %s", status, tt.wantTPStatus)
+			}
+			if tt.wantStop == 0 {
+				if trigger.State != TriggerStateCancelled || trigger.OrderID != "" {
+					t.Errorf("stop trigger = %s with order %q, want cancelled without an order", trigger.State, trigger.OrderID)
+				}
+			} else {
+				if trigger.State != TriggerStateFired {
+					t.Fatalf("stop trigger = %s (error %q), want fired", trigger.State, trigger.Error)
+				}
+				placed := exchange.order(trigger.OrderID)
+				if quantity, _ := placed.OriginalQuantity(); quantity != tt.wantStop {
+					t.Errorf("stop order quantity = %v, want %v", quantity, tt.wantStop)
+				}
+			}
+
+			got, err := m.Group(bracket.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Legs[0].State != tt.wantTPState {
+				t.Errorf("take-profit leg = %s, want %s", got.Legs[0].State, tt.wantTPState)
+			}
+			if (got.State == OCOGroupStateDone) != tt.wantGroupDone {
+				t.Errorf("group State = %s, want done %v", got.State, tt.wantGroupDone)
+			}
+		})
+	}
+}
@@ -0,0 +1,557 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OrderFilter selects orders by symbol, side, price range, age and client tag. Zero-valued fields match everything.
+type OrderFilter struct {
+	// Symbol restricts the selection to one trading pair
+	Symbol Symbol
+	// Side restricts the selection to buy or sell orders
+	Side OrderSide
+	// MinPrice is the inclusive lower price bound
+	MinPrice *float64
+	// MaxPrice is the inclusive upper price bound
+	MaxPrice *float64
+	// MinAge selects orders created at least this long ago
+	MinAge time.Duration
+	// MaxAge selects orders created at most this long ago
+	MaxAge time.Duration
+	// ClientTag selects orders placed with a client order ID starting with this prefix
+	ClientTag string
+}
+
+// Matches reports whether the order satisfies the filter. A nil filter matches every order.
+// Orders do not carry their client order ID, so a ClientTag is resolved through clientOrderIDs.
+//
+// Parameters:
+//   - order: The order to test
+//   - clientOrderIDs: Client order IDs keyed by server order ID, as returned by DeltaDeFi.ClientOrderIDs
+//     (only needed when the filter has a ClientTag)
+//
+// Returns:
+//   - bool: true if the order satisfies every criterion of the filter
+func (f *OrderFilter) Matches(order *OrderJSON, clientOrderIDs map[string]string) bool {
+	return f.matches(order, clientOrderIDs, time.Now())
+}
+
+// usesClientTag reports whether evaluating the filter needs the client order IDs.
+func (f *OrderFilter) usesClientTag() bool {
+	return f != nil && f.ClientTag != ""
+}
+
+// isEmpty reports whether the filter matches every order.
+func (f *OrderFilter) isEmpty() bool {
+	return f == nil || *f == (OrderFilter{})
+}
+
+// matches evaluates the filter with the client order IDs keyed by server order ID.
+func (f *OrderFilter) matches(order *OrderJSON, clientOrderIDs map[string]string, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+	if f.Symbol != "" && order.Symbol != f.Symbol {
+		return false
+	}
+	if f.Side != "" && order.Side != f.Side {
+		return false
+	}
+	if f.MinPrice != nil && order.Price < *f.MinPrice {
+		return false
+	}
+	if f.MaxPrice != nil && order.Price > *f.MaxPrice {
+		return false
+	}
+	if f.MinAge > 0 || f.MaxAge > 0 {
+		age := now.Sub(order.CreatedAt())
+		if f.MinAge > 0 && age < f.MinAge {
+			return false
+		}
+		if f.MaxAge > 0 && age > f.MaxAge {
+			return false
+		}
+	}
+	if f.ClientTag != "" && !strings.HasPrefix(clientOrderIDs[order.OrderID], f.ClientTag) {
+		return false
+	}
+	return true
+}
+
+// OrderDrift describes an order whose local state differs from the server.
+type OrderDrift struct {
+	Local  OrderJSON
+	Remote OrderJSON
+}
+
+// OrderDriftReport lists the differences found by OrderManager.Reconcile.
+type OrderDriftReport struct {
+	// Missing orders were tracked locally but the server does not know them
+	Missing []OrderJSON
+	// Finished orders were tracked as live but were filled, cancelled or failed on the server
+	// without a local update; they hold the server state
+	Finished []OrderJSON
+	// Unverified orders were not among the open orders and could not be looked up; they are kept
+	Unverified []OrderJSON
+	// Unexpected orders are open on the server but were not tracked locally
+	Unexpected []OrderJSON
+	// Changed orders are live on both sides but differ in status or executed quantity
+	Changed []OrderDrift
+	// CheckedAt is when the server state was fetched
+	CheckedAt time.Time
+}
+
+// HasDrift reports whether any difference was found.
+func (r *OrderDriftReport) HasDrift() bool {
+	return len(r.Missing) > 0 || len(r.Finished) > 0 || len(r.Unverified) > 0 ||
+		len(r.Unexpected) > 0 || len(r.Changed) > 0
+}
+
+// OrderManager maintains an authoritative local view of the account's open orders.
+// It is seeded from the server with Sync, updated by the orders it places and cancels and by
+// the fills and order updates passed to it, and periodically reconciled against the server.
+// An OrderManager is safe for concurrent use.
+type OrderManager struct {
+	d *DeltaDeFi
+
+	mu     sync.RWMutex
+	orders map[string]OrderJSON
+	// fills holds the execution IDs already applied per order, to ignore duplicate fill events
+	fills map[string]map[string]struct{}
+	// version counts local changes and touched holds the version of the last local change per
+	// order ID, so Reconcile does not overwrite changes made while it was talking to the server
+	version uint64
+	touched map[string]uint64
+}
+
+// NewOrderManager creates an empty order manager. Call Sync to seed it from the server.
+//
+// Parameters:
+//   - d: The client used to place, cancel and fetch orders
+//
+// Returns:
+//   - *OrderManager: A new order manager
+func NewOrderManager(d *DeltaDeFi) *OrderManager {
+	return &OrderManager{
+		d:       d,
+		orders:  make(map[string]OrderJSON),
+		fills:   make(map[string]map[string]struct{}),
+		touched: make(map[string]uint64),
+	}
+}
+
+// Sync replaces the local view with the open orders reported by the server.
+//
+// Returns:
+//   - error: nil on success, error on failure (the local view is unchanged)
+func (m *OrderManager) Sync() error {
+	remote, err := m.d.fetchOpenOrders("")
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.orders = make(map[string]OrderJSON, len(remote))
+	for _, order := range remote {
+		m.orders[order.OrderID] = order
+	}
+	m.touched = make(map[string]uint64)
+	m.pruneFills()
+	return nil
+}
+
+// PostOrder places an order with DeltaDeFi.PostOrder and tracks it.
+//
+// Parameters:
+//   - data: Order details including symbol, side, type, quantity, and optional price
+//
+// Returns:
+//   - *SubmitPlaceOrderTransactionResponse: Order details and transaction info
+//   - error: nil on success, error on failure
+func (m *OrderManager) PostOrder(data *BuildPlaceOrderTransactionRequest) (*SubmitPlaceOrderTransactionResponse, error) {
+	res, err := m.d.PostOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	m.track(res.Order)
+	return res, nil
+}
+
+// PostOrderWithClientID places an order with DeltaDeFi.PostOrderWithClientID and tracks it.
+//
+// Parameters:
+//   - clientOrderID: The caller-supplied unique ID of the order
+//   - data: Order details including symbol, side, type, quantity, and optional price
+//
+// Returns:
+//   - *SubmitPlaceOrderTransactionResponse: The placed order, or the existing order for a duplicate
+//   - error: nil on success, error on failure
+func (m *OrderManager) PostOrderWithClientID(clientOrderID string, data *BuildPlaceOrderTransactionRequest) (*SubmitPlaceOrderTransactionResponse, error) {
+	res, err := m.d.PostOrderWithClientID(clientOrderID, data)
+	if err != nil {
+		return nil, err
+	}
+	m.track(res.Order)
+	return res, nil
+}
+
+// CancelOrder cancels an order with DeltaDeFi.CancelOrder and stops tracking it.
+//
+// Parameters:
+//   - orderId: The ID of the order to cancel
+//
+// Returns:
+//   - *SubmitCancelOrderTransactionResponse: Transaction hash of the cancellation
+//   - error: nil on success, error on failure
+func (m *OrderManager) CancelOrder(orderId string) (*SubmitCancelOrderTransactionResponse, error) {
+	res, err := m.d.CancelOrder(orderId)
+	if err != nil {
+		return nil, err
+	}
+	m.remove(orderId)
+	return res, nil
+}
+
+// Update applies an order update, e.g. from GetOrderRecord or a stream.
+// Terminal orders are removed from the view.
+//
+// Parameters:
+//   - order: The latest state of the order
+//
+// Returns:
+//   - error: *OrderStatusTransitionError if the status change is impossible (the update is not applied)
+func (m *OrderManager) Update(order OrderJSON) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.orders[order.OrderID]; ok {
+		if err := ValidateOrderStatusTransition(order.OrderID, current.OrderStatus(), order.OrderStatus()); err != nil {
+			return err
+		}
+	}
+	m.apply(order)
+	return nil
+}
+
+// ApplyFill applies a fill event to a tracked order. Fills already applied (same ExecutionID) are ignored.
+// The ExecutedQty of a filling record is the quantity of that one execution, not the cumulative
+// executed quantity of the order, so it is added to the order's ExecutedQty. A fill that would
+// execute more than the order quantity is rejected, which also catches cumulative quantities.
+// An order whose executed quantity reaches its original quantity is removed from the view.
+//
+// Parameters:
+//   - fill: The fill record
+//
+// Returns:
+//   - error: nil on success, error if the order is unknown, the quantities cannot be parsed or the fill overfills the order
+func (m *OrderManager) ApplyFill(fill OrderFillingRecordJSON) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, ok := m.orders[fill.OrderID]
+	if !ok {
+		return fmt.Errorf("order %s is not tracked", fill.OrderID)
+	}
+	if fill.ExecutionID != "" {
+		if _, seen := m.fills[fill.OrderID][fill.ExecutionID]; seen {
+			return nil
+		}
+	}
+
+	filled, err := parseQuantity(fill.ExecutedQty)
+	if err != nil {
+		return err
+	}
+	executed, err := order.ExecutedQuantity()
+	if err != nil {
+		return err
+	}
+	original, err := order.OriginalQuantity()
+	if err != nil {
+		return err
+	}
+
+	executed += filled
+	if executed > original+quantityEpsilon {
+		return fmt.Errorf("fill %s executes %v of order %s, above its quantity %v", fill.ExecutionID, executed, fill.OrderID, original)
+	}
+	order.ExecutedQty = strconv.FormatFloat(executed, 'f', -1, 64)
+	order.ExecutedPrice = fill.ExecutedPrice
+	if fill.CreatedTime > order.UpdateTime {
+		order.UpdateTime = fill.CreatedTime
+	}
+	if executed >= original {
+		order.Status = string(OrderStatusClosed)
+	}
+
+	if fill.ExecutionID != "" {
+		if m.fills[fill.OrderID] == nil {
+			m.fills[fill.OrderID] = make(map[string]struct{})
+		}
+		m.fills[fill.OrderID][fill.ExecutionID] = struct{}{}
+	}
+	m.apply(order)
+	return nil
+}
+
+// Reconcile compares the local view with the server, reports the differences and merges the
+// server state into the view. Tracked orders missing from the open orders are looked up one by
+// one before they are reported: they may still be pending or may have just finished. Orders
+// changed locally while Reconcile runs keep their local state.
+//
+// Returns:
+//   - *OrderDriftReport: The differences found
+//   - error: nil on success, error if the open orders cannot be fetched (the local view is unchanged)
+func (m *OrderManager) Reconcile() (*OrderDriftReport, error) {
+	m.mu.RLock()
+	start := m.version
+	m.mu.RUnlock()
+
+	remote, err := m.d.fetchOpenOrders("")
+	if err != nil {
+		return nil, err
+	}
+	remoteByID := make(map[string]OrderJSON, len(remote))
+	for _, order := range remote {
+		remoteByID[order.OrderID] = order
+	}
+
+	m.mu.RLock()
+	var missing []string
+	for id := range m.orders {
+		if _, ok := remoteByID[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Strings(missing)
+
+	type lookup struct {
+		order *OrderJSON
+		err   error
+	}
+	lookups := make(map[string]lookup, len(missing))
+	for _, id := range missing {
+		order, err := m.d.fetchOrder(id)
+		lookups[id] = lookup{order: order, err: err}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &OrderDriftReport{CheckedAt: time.Now()}
+	for _, order := range remote {
+		if m.touched[order.OrderID] > start {
+			continue
+		}
+		local, ok := m.orders[order.OrderID]
+		switch {
+		case !ok:
+			report.Unexpected = append(report.Unexpected, order)
+		case orderDiffers(local, order):
+			report.Changed = append(report.Changed, OrderDrift{Local: local, Remote: order})
+		}
+		m.orders[order.OrderID] = order
+	}
+	for _, id := range missing {
+		local, ok := m.orders[id]
+		if !ok || m.touched[id] > start {
+			continue
+		}
+		result := lookups[id]
+		switch {
+		case errors.Is(result.err, ErrOrderNotFound):
+			report.Missing = append(report.Missing, local)
+			m.drop(id)
+		case result.err != nil:
+			report.Unverified = append(report.Unverified, local)
+		case result.order.OrderStatus().IsTerminal():
+			report.Finished = append(report.Finished, *result.order)
+			m.drop(id)
+		default:
+			// Still live on the server but not listed as open, e.g. processing
+			if orderDiffers(local, *result.order) {
+				report.Changed = append(report.Changed, OrderDrift{Local: local, Remote: *result.order})
+			}
+			m.orders[id] = *result.order
+		}
+	}
+	sortOrders(report.Missing)
+	sortOrders(report.Finished)
+	sortOrders(report.Unverified)
+	sortOrders(report.Unexpected)
+
+	// Local changes up to start are now reconciled
+	for id, version := range m.touched {
+		if version <= start {
+			delete(m.touched, id)
+		}
+	}
+	m.pruneFills()
+	return report, nil
+}
+
+// orderDiffers reports whether two states of an order differ in status or executed quantity.
+func orderDiffers(local, remote OrderJSON) bool {
+	return local.Status != remote.Status || local.ExecutedQty != remote.ExecutedQty
+}
+
+// StartReconciliation runs Reconcile periodically in a background goroutine.
+//
+// Parameters:
+//   - interval: The time between reconciliations
+//   - onDrift: Called with every report that contains drift (optional)
+//   - onError: Called when a reconciliation fails (optional)
+//
+// Returns:
+//   - func(): Stops the reconciliation; safe to call more than once
+//   - error: nil on success, error if the interval is invalid
+func (m *OrderManager) StartReconciliation(interval time.Duration, onDrift func(*OrderDriftReport), onError func(error)) (func(), error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("reconciliation interval must be positive")
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				report, err := m.Reconcile()
+				if err != nil {
+					if onError != nil {
+						onError(err)
+					}
+					continue
+				}
+				if report.HasDrift() && onDrift != nil {
+					onDrift(report)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}, nil
+}
+
+// Order returns a tracked open order.
+//
+// Parameters:
+//   - orderId: The ID of the order
+//
+// Returns:
+//   - OrderJSON: The local state of the order
+//   - bool: false if the order is not tracked
+func (m *OrderManager) Order(orderId string) (OrderJSON, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, ok := m.orders[orderId]
+	return order, ok
+}
+
+// Orders returns the tracked open orders matching the filter, sorted by creation time.
+//
+// Parameters:
+//   - filter: The selection criteria (optional, nil returns every order)
+//
+// Returns:
+//   - []OrderJSON: The matching orders
+//   - error: nil on success, error if a client tag is given and the client order store cannot be read
+func (m *OrderManager) Orders(filter *OrderFilter) ([]OrderJSON, error) {
+	var clientOrderIDs map[string]string
+	if filter.usesClientTag() {
+		var err error
+		clientOrderIDs, err = m.d.ClientOrderIDs()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	orders := make([]OrderJSON, 0, len(m.orders))
+	for _, order := range m.orders {
+		if filter.matches(&order, clientOrderIDs, now) {
+			orders = append(orders, order)
+		}
+	}
+	sortOrders(orders)
+	return orders, nil
+}
+
+// track adds a newly placed order to the view unless it is already terminal.
+func (m *OrderManager) track(order OrderJSON) {
+	if order.OrderID == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apply(order)
+}
+
+// remove drops an order from the view.
+func (m *OrderManager) remove(orderId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.touch(orderId)
+	m.drop(orderId)
+}
+
+// apply stores the order, or drops it when it is terminal. The caller must hold m.mu.
+func (m *OrderManager) apply(order OrderJSON) {
+	m.touch(order.OrderID)
+	if order.OrderStatus().IsTerminal() {
+		m.drop(order.OrderID)
+		return
+	}
+	m.orders[order.OrderID] = order
+}
+
+// drop removes an order and its applied fills. The caller must hold m.mu.
+func (m *OrderManager) drop(orderId string) {
+	delete(m.orders, orderId)
+	delete(m.fills, orderId)
+}
+
+// touch records a local change of an order. The caller must hold m.mu.
+func (m *OrderManager) touch(orderId string) {
+	m.version++
+	m.touched[orderId] = m.version
+}
+
+// pruneFills forgets applied fills of orders that are no longer tracked. The caller must hold m.mu.
+func (m *OrderManager) pruneFills() {
+	for id := range m.fills {
+		if _, ok := m.orders[id]; !ok {
+			delete(m.fills, id)
+		}
+	}
+}
+
+// sortOrders orders by creation time, then order ID.
+func sortOrders(orders []OrderJSON) {
+	sort.Slice(orders, func(i, j int) bool {
+		if orders[i].CreatedTime != orders[j].CreatedTime {
+			return orders[i].CreatedTime < orders[j].CreatedTime
+		}
+		return orders[i].OrderID < orders[j].OrderID
+	})
+}
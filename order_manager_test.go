@@ -0,0 +1,263 @@
+package deltadefi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestOrderManagerReconcile(t *testing.T) {
+	tests := []struct {
+		name string
+		// local is the tracked state, remote the server state (nil if the server does not know the order)
+		local        *OrderJSON
+		remote       *OrderJSON
+		lookupStatus int
+		wantDrift    func(r *OrderDriftReport) int
+		wantTracked  bool
+		wantExecuted string
+	}{
+		{
+			name:         "in sync",
+			local:        &OrderJSON{Status: "open", ExecutedQty: "0"},
+			remote:       &OrderJSON{Status: "open", ExecutedQty: "0"},
+			wantTracked:  true,
+			wantExecuted: "0",
+		},
+		{
+			name:         "changed",
+			local:        &OrderJSON{Status: "open", ExecutedQty: "0"},
+			remote:       &OrderJSON{Status: "open", ExecutedQty: "3"},
+			wantDrift:    func(r *OrderDriftReport) int { return len(r.Changed) },
+			wantTracked:  true,
+			wantExecuted: "3",
+		},
+		{
+			name:        "unexpected",
+			remote:      &OrderJSON{Status: "open", ExecutedQty: "0"},
+			wantDrift:   func(r *OrderDriftReport) int { return len(r.Unexpected) },
+			wantTracked: true,
+		},
+		{
+			name:      "finished on the server",
+			local:     &OrderJSON{Status: "open", ExecutedQty: "0"},
+			remote:    &OrderJSON{Status: "closed", ExecutedQty: "10"},
+			wantDrift: func(r *OrderDriftReport) int { return len(r.Finished) },
+		},
+		{
+			name:      "unknown to the server",
+			local:     &OrderJSON{Status: "open", ExecutedQty: "0"},
+			wantDrift: func(r *OrderDriftReport) int { return len(r.Missing) },
+		},
+		{
+			name:         "pending is kept",
+			local:        &OrderJSON{Status: "processing", ExecutedQty: "0"},
+			remote:       &OrderJSON{Status: "processing", ExecutedQty: "0"},
+			wantTracked:  true,
+			wantExecuted: "0",
+		},
+		{
+			name:         "lookup failure is kept",
+			local:        &OrderJSON{Status: "processing", ExecutedQty: "0"},
+			remote:       &OrderJSON{Status: "processing", ExecutedQty: "0"},
+			lookupStatus: http.StatusServiceUnavailable,
+			wantDrift:    func(r *OrderDriftReport) int { return len(r.Unverified) },
+			wantTracked:  true,
+			wantExecuted: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			m := NewOrderManager(d)
+			if tt.local != nil {
+				local := *tt.local
+				local.OrderID, local.OrigQty = "order", "10"
+				m.track(local)
+			}
+			if tt.remote != nil {
+				remote := *tt.remote
+				remote.OrderID, remote.OrigQty = "order", "10"
+				exchange.addOrder(remote)
+			}
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+
+			report, err := m.Reconcile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantDrift == nil {
+				if report.HasDrift() {
+					t.Errorf("unexpected drift %+v", report)
+				}
+			} else if n := tt.wantDrift(report); n != 1 || !report.HasDrift() {
+				t.Errorf("drift %+v, want one entry in the expected list", report)
+			}
+
+			order, tracked := m.Order("order")
+			if tracked != tt.wantTracked {
+				t.Fatalf("tracked = %v, want %v", tracked, tt.wantTracked)
+			}
+			if tt.wantExecuted != "" && order.ExecutedQty != tt.wantExecuted {
+				t.Errorf("ExecutedQty = %s, want %s", order.ExecutedQty, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+func TestOrderManagerReconcileKeepsConcurrentChanges(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	m := NewOrderManager(d)
+	m.track(OrderJSON{OrderID: "a", Status: "open", OrigQty: "10", ExecutedQty: "0"})
+	m.track(OrderJSON{OrderID: "b", Status: "processing", OrigQty: "10", ExecutedQty: "0"})
+	m.track(OrderJSON{OrderID: "c", Status: "open", OrigQty: "10", ExecutedQty: "0"})
+	exchange.addOrder(OrderJSON{OrderID: "a", Status: "open", OrigQty: "10", ExecutedQty: "5"})
+	exchange.addOrder(OrderJSON{OrderID: "b", Status: "processing", OrigQty: "10", ExecutedQty: "0"})
+	exchange.addOrder(OrderJSON{OrderID: "c", Status: "open", OrigQty: "10", ExecutedQty: "0"})
+
+	// While Reconcile looks up b, newer fills of a arrive and c is cancelled locally
+	exchange.lookup = func(id string) int {
+		if id == "b" {
+			if err := m.Update(OrderJSON{OrderID: "a", Status: "open", OrigQty: "10", ExecutedQty: "7"}); err != nil {
+				t.Error(err)
+			}
+			m.remove("c")
+		}
+		return http.StatusOK
+	}
+
+	report, err := m.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.HasDrift() {
+		t.Errorf("unexpected drift %+v", report)
+	}
+	if order, _ := m.Order("a"); order.ExecutedQty != "7" {
+		t.Errorf("a ExecutedQty = %s, want the local 7", order.ExecutedQty)
+	}
+	if _, ok := m.Order("c"); ok {
+		t.Error("c was re-added by Reconcile")
+	}
+
+	// The next reconciliation adopts the server state again
+	exchange.lookup = nil
+	report, err = m.Reconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changed) != 1 || len(report.Unexpected) != 1 {
+		t.Errorf("second reconcile = %+v, want a changed and c unexpected", report)
+	}
+}
+
+func TestOrderManagerApplyFill(t *testing.T) {
+	tests := []struct {
+		name         string
+		fills        []OrderFillingRecordJSON
+		wantErr      bool
+		wantTracked  bool
+		wantExecuted string
+	}{
+		{
+			name:         "per-execution quantities add up",
+			fills:        []OrderFillingRecordJSON{{ExecutionID: "1", ExecutedQty: "2"}, {ExecutionID: "2", ExecutedQty: "3"}},
+			wantTracked:  true,
+			wantExecuted: "5",
+		},
+		{
+			name:         "duplicate execution ignored",
+			fills:        []OrderFillingRecordJSON{{ExecutionID: "1", ExecutedQty: "2"}, {ExecutionID: "1", ExecutedQty: "2"}},
+			wantTracked:  true,
+			wantExecuted: "2",
+		},
+		{
+			name:  "full fill closes the order",
+			fills: []OrderFillingRecordJSON{{ExecutionID: "1", ExecutedQty: "4"}, {ExecutionID: "2", ExecutedQty: "6"}},
+		},
+		{
+			name:         "overfill rejected",
+			fills:        []OrderFillingRecordJSON{{ExecutionID: "1", ExecutedQty: "6"}, {ExecutionID: "2", ExecutedQty: "8"}},
+			wantErr:      true,
+			wantTracked:  true,
+			wantExecuted: "6",
+		},
+		{
+			name:         "unknown order",
+			fills:        []OrderFillingRecordJSON{{OrderID: "other", ExecutionID: "1", ExecutedQty: "1"}},
+			wantErr:      true,
+			wantTracked:  true,
+			wantExecuted: "0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewOrderManager(nil)
+			m.track(OrderJSON{OrderID: "order", Status: "open", OrigQty: "10", ExecutedQty: "0"})
+
+			var err error
+			for _, fill := range tt.fills {
+				if fill.OrderID == "" {
+					fill.OrderID = "order"
+				}
+				if err = m.ApplyFill(fill); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyFill() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			order, tracked := m.Order("order")
+			if tracked != tt.wantTracked {
+				t.Fatalf("tracked = %v, want %v", tracked, tt.wantTracked)
+			}
+			if tracked && order.ExecutedQty != tt.wantExecuted {
+				t.Errorf("ExecutedQty = %s, want %s", order.ExecutedQty, tt.wantExecuted)
+			}
+		})
+	}
+}
+
+func TestOrderManagerOrders(t *testing.T) {
+	d := NewDeltaDeFi(ApiConfig{ApiKey: "key"})
+	if err := d.clientOrders.Put(&ClientOrderRecord{ClientOrderID: "grid-1", OrderID: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	m := NewOrderManager(d)
+	m.track(OrderJSON{OrderID: "a", Status: "open", Symbol: ADAUSDM, Side: OrderSideBuy, Price: 0.4, CreatedTime: 1})
+	m.track(OrderJSON{OrderID: "b", Status: "open", Symbol: ADAUSDM, Side: OrderSideBuy, Price: 0.5, CreatedTime: 2})
+	m.track(OrderJSON{OrderID: "c", Status: "open", Symbol: ADAUSDM, Side: OrderSideSell, Price: 0.6, CreatedTime: 3})
+
+	tests := []struct {
+		name   string
+		filter *OrderFilter
+		want   []string
+	}{
+		{name: "nil filter", want: []string{"a", "b", "c"}},
+		{name: "side", filter: &OrderFilter{Side: OrderSideBuy}, want: []string{"a", "b"}},
+		{name: "price range", filter: &OrderFilter{MinPrice: FloatPtr(0.45), MaxPrice: FloatPtr(0.6)}, want: []string{"b", "c"}},
+		{name: "client tag", filter: &OrderFilter{ClientTag: "grid-"}, want: []string{"b"}},
+		{name: "no match", filter: &OrderFilter{Symbol: "OTHER"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, err := m.Orders(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(orders))
+			for _, order := range orders {
+				got = append(got, order.OrderID)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Orders() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Orders() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
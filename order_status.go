@@ -0,0 +1,107 @@
+package deltadefi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// orderStatusTransitions lists the statuses each known status can move to.
+// Staying in the same status is always allowed and terminal statuses have no successors.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusBuilding:   {OrderStatusProcessing, OrderStatusOpen, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusProcessing: {OrderStatusOpen, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusOpen:       {OrderStatusProcessing, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+	OrderStatusClosed:     {},
+	OrderStatusFailed:     {},
+	OrderStatusCancelled:  {},
+}
+
+// ParseOrderStatus converts a status string reported by the server into an OrderStatus.
+// Unknown values are preserved rather than rejected, so newer server statuses do not break
+// callers; the returned bool reports whether the status is one the SDK knows.
+//
+// Parameters:
+//   - status: The raw status string, e.g. "open"
+//
+// Returns:
+//   - OrderStatus: The parsed status (normalized to lower case)
+//   - bool: true if the status is known
+func ParseOrderStatus(status string) (OrderStatus, bool) {
+	parsed := OrderStatus(strings.ToLower(strings.TrimSpace(status)))
+	return parsed, parsed.IsKnown()
+}
+
+// IsKnown reports whether the status is one of the OrderStatus constants.
+func (s OrderStatus) IsKnown() bool {
+	_, ok := orderStatusTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether the order can no longer change (closed, cancelled or failed).
+func (s OrderStatus) IsTerminal() bool {
+	switch s {
+	case OrderStatusClosed, OrderStatusCancelled, OrderStatusFailed:
+		return true
+	}
+	return false
+}
+
+// IsOpen reports whether the order is resting on the order book.
+func (s OrderStatus) IsOpen() bool {
+	return s == OrderStatusOpen
+}
+
+// IsPending reports whether the order is still being built or processed.
+func (s OrderStatus) IsPending() bool {
+	return s == OrderStatusBuilding || s == OrderStatusProcessing
+}
+
+// CanTransitionTo reports whether an order may move from this status to next.
+// Transitions involving unknown statuses are allowed, since the SDK cannot judge them.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	if s == next || !s.IsKnown() || !next.IsKnown() {
+		return true
+	}
+	for _, allowed := range orderStatusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatusTransitionError is returned when an order is observed moving between two
+// statuses that the order lifecycle does not allow, e.g. from cancelled back to open.
+type OrderStatusTransitionError struct {
+	OrderID string
+	From    OrderStatus
+	To      OrderStatus
+}
+
+// Error implements the error interface.
+func (e *OrderStatusTransitionError) Error() string {
+	return fmt.Sprintf("order %s: impossible status transition from %s to %s", e.OrderID, e.From, e.To)
+}
+
+// ValidateOrderStatusTransition checks an observed status change of an order.
+//
+// Parameters:
+//   - orderId: The ID of the order, used in the error
+//   - from: The previously observed status
+//   - to: The newly observed status
+//
+// Returns:
+//   - error: nil if the transition is possible, *OrderStatusTransitionError otherwise
+func ValidateOrderStatusTransition(orderId string, from, to OrderStatus) error {
+	if from.CanTransitionTo(to) {
+		return nil
+	}
+	return &OrderStatusTransitionError{OrderID: orderId, From: from, To: to}
+}
+
+// OrderStatus returns the typed status of the order.
+// Status is kept as a plain string so unknown server values round-trip unchanged.
+func (o *OrderJSON) OrderStatus() OrderStatus {
+	status, _ := ParseOrderStatus(o.Status)
+	return status
+}
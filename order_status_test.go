@@ -0,0 +1,101 @@
+package deltadefi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOrderStatus(t *testing.T) {
+	tests := []struct {
+		status    string
+		want      OrderStatus
+		wantKnown bool
+		terminal  bool
+		open      bool
+		pending   bool
+	}{
+		{status: "building", want: OrderStatusBuilding, wantKnown: true, pending: true},
+		{status: "processing", want: OrderStatusProcessing, wantKnown: true, pending: true},
+		{status: "open", want: OrderStatusOpen, wantKnown: true, open: true},
+		{status: "closed", want: OrderStatusClosed, wantKnown: true, terminal: true},
+		{status: "failed", want: OrderStatusFailed, wantKnown: true, terminal: true},
+		{status: "cancelled", want: OrderStatusCancelled, wantKnown: true, terminal: true},
+		{status: " OPEN ", want: OrderStatusOpen, wantKnown: true, open: true},
+		{status: "partially_filled", want: OrderStatus("partially_filled")},
+		{status: "", want: OrderStatus("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			got, known := ParseOrderStatus(tt.status)
+			if got != tt.want || known != tt.wantKnown {
+				t.Fatalf("ParseOrderStatus(%q) = %q, %v, want %q, %v", tt.status, got, known, tt.want, tt.wantKnown)
+			}
+			if got.IsTerminal() != tt.terminal {
+				t.Errorf("IsTerminal() = %v, want %v", got.IsTerminal(), tt.terminal)
+			}
+			if got.IsOpen() != tt.open {
+				t.Errorf("IsOpen() = %v, want %v", got.IsOpen(), tt.open)
+			}
+			if got.IsPending() != tt.pending {
+				t.Errorf("IsPending() = %v, want %v", got.IsPending(), tt.pending)
+			}
+			if order := (&OrderJSON{Status: tt.status}); order.OrderStatus() != tt.want {
+				t.Errorf("OrderStatus() = %q, want %q", order.OrderStatus(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	const unknown = OrderStatus("partially_filled")
+	statuses := []OrderStatus{
+		OrderStatusBuilding, OrderStatusProcessing, OrderStatusOpen,
+		OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled, unknown,
+	}
+	// allowed lists every possible move between distinct known statuses
+	allowed := map[OrderStatus][]OrderStatus{
+		OrderStatusBuilding:   {OrderStatusProcessing, OrderStatusOpen, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+		OrderStatusProcessing: {OrderStatusOpen, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+		OrderStatusOpen:       {OrderStatusProcessing, OrderStatusClosed, OrderStatusFailed, OrderStatusCancelled},
+	}
+	isAllowed := func(from, to OrderStatus) bool {
+		// Staying put is always possible and unknown statuses cannot be judged
+		if from == to || from == unknown || to == unknown {
+			return true
+		}
+		for _, next := range allowed[from] {
+			if next == to {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(string(from)+"->"+string(to), func(t *testing.T) {
+				want := isAllowed(from, to)
+				if got := from.CanTransitionTo(to); got != want {
+					t.Fatalf("CanTransitionTo() = %v, want %v", got, want)
+				}
+				err := ValidateOrderStatusTransition("order", from, to)
+				if want {
+					if err != nil {
+						t.Errorf("ValidateOrderStatusTransition() error = %v", err)
+					}
+					return
+				}
+				var transitionErr *OrderStatusTransitionError
+				if !errors.As(err, &transitionErr) {
+					t.Fatalf("ValidateOrderStatusTransition() error = %v, want *OrderStatusTransitionError", err)
+				}
+				if transitionErr.OrderID != "order" || transitionErr.From != from || transitionErr.To != to {
+					t.Errorf("error = %+v", transitionErr)
+				}
+				if !from.IsTerminal() && to != OrderStatusBuilding {
+					t.Errorf("%s -> %s is forbidden, but only terminal statuses and rebuilding are", from, to)
+				}
+			})
+		}
+	}
+}
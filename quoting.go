@@ -0,0 +1,613 @@
+package deltadefi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultQuoteRefreshInterval is how often the quoting engine re-evaluates its quotes
+	DefaultQuoteRefreshInterval = 2 * time.Second
+	// DefaultQuoteSizeTolerance is the default fraction of its size a resting quote may lose to fills before it is topped up
+	DefaultQuoteSizeTolerance = 0.5
+)
+
+// QuotingConfig describes the two-sided ladder maintained by a QuotingEngine.
+// Prices are placed around a reference price, by default the mid of the order book.
+// When the inventory is above TargetInventory the whole ladder is shifted down by up to
+// SkewBasisPoints, making the asks more likely to fill, and vice versa. No quote is placed that
+// could take the inventory beyond MaxInventory.
+type QuotingConfig struct {
+	Symbol Symbol
+	// Levels is the number of quotes on each side (defaults to 1)
+	Levels int
+	// SpreadBasisPoints is the distance between the best bid and the best ask
+	SpreadBasisPoints float64
+	// LevelSpacingBasisPoints is the distance between consecutive levels of a side
+	LevelSpacingBasisPoints float64
+	// Size is the base quantity of the first level of each side
+	Size float64
+	// SizeMultiplier scales the size of each further level (defaults to 1)
+	SizeMultiplier float64
+	// TickSize is the price step; bids are rounded down and asks up to a multiple of it (optional)
+	TickSize float64
+	// LotSize is the base quantity step; sizes are rounded down to a multiple of it (optional)
+	LotSize float64
+	// TargetInventory is the base balance the engine steers towards
+	TargetInventory float64
+	// MaxInventory is the largest distance from TargetInventory the quotes may reach (zero disables the limit and skew)
+	MaxInventory float64
+	// SkewBasisPoints is the ladder shift at MaxInventory, proportional to the inventory in between
+	SkewBasisPoints float64
+	// RefreshThresholdBasisPoints keeps a resting quote while its price is within this distance of the desired price
+	RefreshThresholdBasisPoints float64
+	// SizeTolerance is the fraction of its desired size a resting quote may differ by before it is
+	// replaced (defaults to DefaultQuoteSizeTolerance)
+	SizeTolerance float64
+	// PostOnly places the quotes as post-only orders
+	PostOnly bool
+	// ReferencePrice overrides the reference price source (optional)
+	ReferencePrice func(symbol Symbol) (float64, error)
+}
+
+// withDefaults returns the config with unset fields defaulted.
+func (c QuotingConfig) withDefaults() QuotingConfig {
+	if c.Levels <= 0 {
+		c.Levels = 1
+	}
+	if c.SizeMultiplier <= 0 {
+		c.SizeMultiplier = 1
+	}
+	if c.SizeTolerance <= 0 {
+		c.SizeTolerance = DefaultQuoteSizeTolerance
+	}
+	return c
+}
+
+// validate checks the config.
+func (c QuotingConfig) validate() error {
+	if c.Symbol == "" {
+		return errors.New("quoting symbol is required")
+	}
+	if c.Size <= 0 {
+		return errors.New("quote size must be positive")
+	}
+	if c.SpreadBasisPoints <= 0 {
+		return errors.New("quote spread must be positive")
+	}
+	if c.LevelSpacingBasisPoints < 0 || c.MaxInventory < 0 || c.SkewBasisPoints < 0 ||
+		c.RefreshThresholdBasisPoints < 0 || c.TickSize < 0 || c.LotSize < 0 {
+		return errors.New("quoting settings must not be negative")
+	}
+	return nil
+}
+
+// Quote is a resting order of the ladder.
+type Quote struct {
+	OrderID  string
+	Side     OrderSide
+	Level    int
+	Price    float64
+	Quantity float64
+	// Remaining is the unfilled quantity
+	Remaining float64
+}
+
+// QuoteUpdate describes one refresh of the ladder.
+type QuoteUpdate struct {
+	ReferencePrice float64
+	// Inventory is the base balance minus TargetInventory
+	Inventory float64
+	// Bids and Asks are the resting quotes after the refresh, best first
+	Bids []Quote
+	Asks []Quote
+	// Kept, Placed and Cancelled count the quotes left alone, placed and cancelled by the refresh
+	Kept      int
+	Placed    int
+	Cancelled int
+	Time      time.Time
+}
+
+// QuotingEngineOptions configures a QuotingEngine.
+type QuotingEngineOptions struct {
+	// RefreshInterval is how often the quotes are re-evaluated (defaults to DefaultQuoteRefreshInterval)
+	RefreshInterval time.Duration
+	// CancelOnStop cancels the resting quotes when the engine stops
+	CancelOnStop bool
+	// OnUpdate is called after every refresh (optional)
+	OnUpdate func(update QuoteUpdate)
+	// OnError is called when a refresh fails (optional)
+	OnError func(err error)
+}
+
+// desiredQuote is a quote the ladder should contain.
+type desiredQuote struct {
+	side     OrderSide
+	level    int
+	price    float64
+	quantity float64
+}
+
+// QuotingEngine keeps a two-sided ladder of limit orders around a reference price. Every refresh
+// it computes the desired ladder and only cancels and replaces the quotes that drifted beyond the
+// refresh thresholds; cancellations are submitted before the new quotes, which are placed as a batch.
+// When a cancellation fails, the quote keeps resting and no new quote is placed on its side until a
+// later refresh cancels it, so the ladder never doubles its exposure.
+// A QuotingEngine is safe for concurrent use.
+type QuotingEngine struct {
+	d    *DeltaDeFi
+	opts QuotingEngineOptions
+
+	mu     sync.Mutex
+	config QuotingConfig
+	quotes map[string]*Quote
+
+	// refreshLock serializes refreshes and cancellations, which call the server without holding mu
+	refreshLock sync.Mutex
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewQuotingEngine creates a quoting engine.
+//
+// Parameters:
+//   - d: The client used to read the market and place quotes
+//   - config: The ladder to maintain
+//   - opts: Engine options
+//
+// Returns:
+//   - *QuotingEngine: The engine, not yet started
+//   - error: nil on success, error if the config is invalid
+func NewQuotingEngine(d *DeltaDeFi, config QuotingConfig, opts QuotingEngineOptions) (*QuotingEngine, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultQuoteRefreshInterval
+	}
+	return &QuotingEngine{
+		d:      d,
+		opts:   opts,
+		config: config.withDefaults(),
+		quotes: make(map[string]*Quote),
+	}, nil
+}
+
+// SetConfig replaces the ladder configuration; the next refresh moves the quotes to it.
+// The symbol cannot be changed.
+//
+// Parameters:
+//   - config: The new configuration
+//
+// Returns:
+//   - error: nil on success, error if the config is invalid
+func (e *QuotingEngine) SetConfig(config QuotingConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if config.Symbol != e.config.Symbol {
+		return fmt.Errorf("cannot change quoting symbol from %s to %s", e.config.Symbol, config.Symbol)
+	}
+	e.config = config.withDefaults()
+	return nil
+}
+
+// Config returns the current ladder configuration.
+func (e *QuotingEngine) Config() QuotingConfig {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.config
+}
+
+// Quotes returns the resting quotes known to the engine, bids then asks, best first.
+func (e *QuotingEngine) Quotes() []Quote {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	bids, asks := e.sortedQuotes()
+	return append(bids, asks...)
+}
+
+// Start begins refreshing the quotes in a background goroutine. Calling Start on a running engine has no effect.
+func (e *QuotingEngine) Start() {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	if e.done != nil {
+		return
+	}
+	e.done = make(chan struct{})
+
+	e.wg.Add(1)
+	go e.run(e.done)
+}
+
+// Stop stops refreshing the quotes and, with CancelOnStop, cancels them. It waits for a refresh
+// in progress to finish, so it must not be called from ReferencePrice, OnUpdate or OnError.
+func (e *QuotingEngine) Stop() {
+	e.runMu.Lock()
+	if e.done == nil {
+		e.runMu.Unlock()
+		return
+	}
+	close(e.done)
+	e.done = nil
+	e.runMu.Unlock()
+
+	e.wg.Wait()
+	if e.opts.CancelOnStop {
+		if err := e.CancelQuotes(); err != nil && e.opts.OnError != nil {
+			e.opts.OnError(err)
+		}
+	}
+}
+
+// CancelQuotes cancels every resting quote. A running engine places new quotes on its next refresh.
+//
+// Returns:
+//   - error: nil on success, otherwise the first cancellation error (failed quotes stay tracked)
+func (e *QuotingEngine) CancelQuotes() error {
+	e.refreshLock.Lock()
+	defer e.refreshLock.Unlock()
+
+	e.mu.Lock()
+	ids := make([]string, 0, len(e.quotes))
+	for id := range e.quotes {
+		ids = append(ids, id)
+	}
+	e.mu.Unlock()
+	sort.Strings(ids)
+
+	var firstErr error
+	for _, id := range ids {
+		if _, err := e.d.CancelOrder(id); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("cancelling quote %s: %w", id, err)
+			}
+			continue
+		}
+		e.mu.Lock()
+		delete(e.quotes, id)
+		e.mu.Unlock()
+	}
+	return firstErr
+}
+
+// Refresh re-evaluates the ladder once: it syncs the resting quotes with the open orders, computes
+// the desired quotes from the reference price and inventory, cancels the quotes that drifted and
+// places the missing ones. It is called periodically while the engine runs and can be called directly.
+// It works on a copy of the quotes, so Quotes and Config answer while it waits for the server.
+//
+// Returns:
+//   - error: nil on success, otherwise the first error (the other quotes are still refreshed)
+func (e *QuotingEngine) Refresh() error {
+	e.refreshLock.Lock()
+	e.mu.Lock()
+	config := e.config
+	quotes := make(map[string]*Quote, len(e.quotes))
+	for id, quote := range e.quotes {
+		copied := *quote
+		quotes[id] = &copied
+	}
+	e.mu.Unlock()
+
+	update, err := e.refresh(config, quotes)
+
+	e.mu.Lock()
+	e.quotes = quotes
+	if update != nil {
+		update.Bids, update.Asks = e.sortedQuotes()
+	}
+	e.mu.Unlock()
+	e.refreshLock.Unlock()
+
+	// Callbacks run without the lock so they may call back into the engine
+	if update != nil && e.opts.OnUpdate != nil {
+		e.opts.OnUpdate(*update)
+	}
+	return err
+}
+
+// run calls Refresh periodically until done is closed.
+func (e *QuotingEngine) run(done chan struct{}) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Refresh(); err != nil && e.opts.OnError != nil {
+			e.opts.OnError(err)
+		}
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh performs one refresh on quotes, a copy of the tracked quotes. The caller must hold
+// e.refreshLock but not e.mu.
+func (e *QuotingEngine) refresh(config QuotingConfig, quotes map[string]*Quote) (*QuoteUpdate, error) {
+	if err := e.syncQuotes(config.Symbol, quotes); err != nil {
+		return nil, err
+	}
+
+	reference, err := e.referencePrice(config)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := e.baseBalance(config.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	inventory := balance - config.TargetInventory
+	desired := config.ladder(reference, inventory, balance)
+
+	// Keep the resting quote closest to each desired quote when it is close enough
+	update := &QuoteUpdate{ReferencePrice: reference, Inventory: inventory, Time: time.Now()}
+	kept := make(map[string]bool)
+	var missing []desiredQuote
+	for _, want := range desired {
+		var best *Quote
+		bestDistance := math.Inf(1)
+		for id, quote := range quotes {
+			if kept[id] || quote.Side != want.side {
+				continue
+			}
+			distance := math.Abs(quote.Price-want.price) / want.price * 10000
+			if distance > config.RefreshThresholdBasisPoints+quantityEpsilon ||
+				math.Abs(quote.Remaining-want.quantity) > config.SizeTolerance*want.quantity+quantityEpsilon {
+				continue
+			}
+			if distance < bestDistance {
+				best, bestDistance = quote, distance
+			}
+		}
+		if best != nil {
+			kept[best.OrderID] = true
+			best.Level = want.level
+			update.Kept++
+			continue
+		}
+		missing = append(missing, want)
+	}
+
+	var firstErr error
+	// A quote that could not be cancelled still rests, so its side gets no replacement
+	blocked := make(map[OrderSide]bool)
+	for id, quote := range quotes {
+		if kept[id] {
+			continue
+		}
+		if _, err := e.d.CancelOrder(id); err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("cancelling quote %s: %w", id, err)
+			}
+			blocked[quote.Side] = true
+			continue
+		}
+		delete(quotes, id)
+		update.Cancelled++
+	}
+	if len(blocked) > 0 {
+		placeable := missing[:0]
+		for _, want := range missing {
+			if !blocked[want.side] {
+				placeable = append(placeable, want)
+			}
+		}
+		missing = placeable
+	}
+
+	if len(missing) > 0 {
+		requests := make([]*BuildPlaceOrderTransactionRequest, len(missing))
+		for i, want := range missing {
+			requests[i] = &BuildPlaceOrderTransactionRequest{
+				Symbol:   config.Symbol,
+				Side:     want.side,
+				Type:     OrderTypeLimit,
+				Quantity: want.quantity,
+				Price:    FloatPtr(want.price),
+				PostOnly: config.PostOnly,
+			}
+		}
+		results, err := e.d.PostOrders(requests, nil)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("placing quotes: %w", err)
+		}
+		for i, result := range results {
+			if result.Response == nil {
+				continue
+			}
+			want := missing[i]
+			quotes[result.Response.Order.OrderID] = &Quote{
+				OrderID:   result.Response.Order.OrderID,
+				Side:      want.side,
+				Level:     want.level,
+				Price:     want.price,
+				Quantity:  want.quantity,
+				Remaining: want.quantity,
+			}
+			update.Placed++
+		}
+	}
+
+	return update, firstErr
+}
+
+// syncQuotes updates the remaining quantity of the quotes. Quotes missing from the open orders are
+// looked up: they are forgotten once terminal or unknown to the server, and kept while pending.
+func (e *QuotingEngine) syncQuotes(symbol Symbol, quotes map[string]*Quote) error {
+	if len(quotes) == 0 {
+		return nil
+	}
+	open, err := e.d.fetchOpenOrders(symbol)
+	if err != nil {
+		return fmt.Errorf("fetching open orders: %w", err)
+	}
+	remaining := make(map[string]float64, len(open))
+	for _, order := range open {
+		quantity, err := order.RemainingQuantity()
+		if err != nil {
+			return err
+		}
+		remaining[order.OrderID] = quantity
+	}
+	for id, quote := range quotes {
+		if quantity, ok := remaining[id]; ok {
+			quote.Remaining = quantity
+			continue
+		}
+		order, err := e.d.fetchOrder(id)
+		if errors.Is(err, ErrOrderNotFound) {
+			delete(quotes, id)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("looking up quote %s: %w", id, err)
+		}
+		if OrderIsTerminal(order) {
+			delete(quotes, id)
+			continue
+		}
+		if quantity, err := order.RemainingQuantity(); err == nil {
+			quote.Remaining = quantity
+		}
+	}
+	return nil
+}
+
+// referencePrice returns the configured reference price, the book mid, or the market price when
+// one side of the book is empty.
+func (e *QuotingEngine) referencePrice(config QuotingConfig) (float64, error) {
+	if config.ReferencePrice != nil {
+		price, err := config.ReferencePrice(config.Symbol)
+		if err != nil {
+			return 0, fmt.Errorf("fetching reference price: %w", err)
+		}
+		if price <= 0 {
+			return 0, fmt.Errorf("invalid reference price %g", price)
+		}
+		return price, nil
+	}
+
+	depth, err := e.d.Market.GetMarketDepth(string(config.Symbol))
+	if err != nil {
+		return 0, fmt.Errorf("fetching market depth: %w", err)
+	}
+	bids, asks := depth.levels(OrderSideSell), depth.levels(OrderSideBuy)
+	if len(bids) > 0 && len(asks) > 0 {
+		return (bids[0].Price + asks[0].Price) / 2, nil
+	}
+	market, err := e.d.Market.GetMarketPrice(string(config.Symbol))
+	if err != nil {
+		return 0, fmt.Errorf("fetching market price: %w", err)
+	}
+	if market.Price <= 0 {
+		return 0, fmt.Errorf("no market price for %s", config.Symbol)
+	}
+	return market.Price, nil
+}
+
+// baseBalance returns the free plus locked balance of the base asset.
+func (e *QuotingEngine) baseBalance(symbol Symbol) (float64, error) {
+	assets, err := e.d.risk.limits.Load().assets(symbol)
+	if err != nil {
+		return 0, err
+	}
+	balances, err := e.d.Accounts.GetAccountBalance()
+	if err != nil {
+		return 0, fmt.Errorf("fetching balances: %w", err)
+	}
+	balance := findAssetBalance(*balances, assets.Base)
+	return balance.Free + balance.Locked, nil
+}
+
+// sortedQuotes returns the tracked quotes split by side, best price first. The caller must hold e.mu.
+func (e *QuotingEngine) sortedQuotes() (bids, asks []Quote) {
+	for _, quote := range e.quotes {
+		if quote.Side == OrderSideBuy {
+			bids = append(bids, *quote)
+		} else {
+			asks = append(asks, *quote)
+		}
+	}
+	sort.Slice(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	sort.Slice(asks, func(i, j int) bool { return asks[i].Price < asks[j].Price })
+	return bids, asks
+}
+
+// ladder computes the desired quotes. Bids stop where they could push the inventory above
+// MaxInventory, and asks where they could push it below -MaxInventory or sell more than the balance.
+func (c QuotingConfig) ladder(reference, inventory, balance float64) []desiredQuote {
+	center := reference
+	bidRoom, askRoom := math.Inf(1), balance
+	if c.MaxInventory > 0 {
+		skew := math.Max(-1, math.Min(1, inventory/c.MaxInventory))
+		center = reference * (1 - skew*c.SkewBasisPoints/10000)
+		bidRoom = c.MaxInventory - inventory
+		askRoom = math.Min(askRoom, c.MaxInventory+inventory)
+	}
+
+	var quotes []desiredQuote
+	for _, side := range []OrderSide{OrderSideBuy, OrderSideSell} {
+		room := bidRoom
+		if side == OrderSideSell {
+			room = askRoom
+		}
+		size := c.Size
+		for level := 0; level < c.Levels; level++ {
+			offset := (c.SpreadBasisPoints/2 + float64(level)*c.LevelSpacingBasisPoints) / 10000
+			quote := desiredQuote{side: side, level: level}
+			if side == OrderSideBuy {
+				quote.price = floorToStep(center*(1-offset), c.TickSize)
+			} else {
+				quote.price = ceilToStep(center*(1+offset), c.TickSize)
+			}
+			quote.quantity = floorToStep(math.Min(size, room), c.LotSize)
+			size *= c.SizeMultiplier
+			if quote.price <= 0 || quote.quantity <= quantityEpsilon {
+				break
+			}
+			room -= quote.quantity
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes
+}
+
+// floorToStep rounds a value down to a multiple of step; a zero step leaves the value unchanged.
+func floorToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return stepMultiple(math.Floor(value/step+quantityEpsilon), step)
+}
+
+// ceilToStep rounds a value up to a multiple of step; a zero step leaves the value unchanged.
+func ceilToStep(value, step float64) float64 {
+	if step <= 0 {
+		return value
+	}
+	return stepMultiple(math.Ceil(value/step-quantityEpsilon), step)
+}
+
+// stepMultiple returns n times step, dividing by the inverse of decimal steps such as 0.0001 so
+// the result carries no binary rounding noise.
+func stepMultiple(n, step float64) float64 {
+	inverse := math.Round(1 / step)
+	if inverse > 1 && math.Abs(1/step-inverse) < quantityEpsilon {
+		return n / inverse
+	}
+	return n * step
+}
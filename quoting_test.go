@@ -0,0 +1,196 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestQuotingEngine returns an engine quoting one level of 10 on each side around the price
+// returned by reference, and runs its first refresh.
+func newTestQuotingEngine(t *testing.T, reference func() float64) (*QuotingEngine, *mockExchange) {
+	t.Helper()
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.balances = []AssetBalance{{Asset: "ADA", Free: 100}}
+	e, err := NewQuotingEngine(d, QuotingConfig{
+		Symbol:            ADAUSDM,
+		SpreadBasisPoints: 100,
+		Size:              10,
+		ReferencePrice:    func(Symbol) (float64, error) { return reference(), nil },
+	}, QuotingEngineOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if quotes := e.Quotes(); len(quotes) != 2 {
+		t.Fatalf("quotes = %+v, want a bid and an ask", quotes)
+	}
+	return e, exchange
+}
+
+func TestQuotingEngineSyncQuotes(t *testing.T) {
+	tests := []struct {
+		name string
+		// change modifies the bid on the exchange before the second refresh
+		change       func(exchange *mockExchange, id string)
+		wantErr      bool
+		wantReplaced bool
+	}{
+		{name: "open quote is kept"},
+		{
+			name: "filled quote is replaced",
+			change: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) { order.Status, order.ExecutedQty = "closed", "10" })
+			},
+			wantReplaced: true,
+		},
+		{
+			name: "cancelled quote is replaced",
+			change: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) { order.Status = "cancelled" })
+			},
+			wantReplaced: true,
+		},
+		{
+			name: "pending quote is kept",
+			change: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) { order.Status = "processing" })
+			},
+		},
+		{
+			name: "unknown quote is replaced",
+			change: func(exchange *mockExchange, id string) {
+				exchange.mu.Lock()
+				delete(exchange.orders, id)
+				exchange.mu.Unlock()
+			},
+			wantReplaced: true,
+		},
+		{
+			name: "lookup failure keeps the quote",
+			change: func(exchange *mockExchange, id string) {
+				exchange.update(id, func(order *OrderJSON) { order.Status = "processing" })
+				exchange.lookup = func(string) int { return http.StatusBadGateway }
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, exchange := newTestQuotingEngine(t, func() float64 { return 0.5 })
+			bid := e.Quotes()[0]
+			if tt.change != nil {
+				tt.change(exchange, bid.OrderID)
+			}
+
+			if err := e.Refresh(); (err != nil) != tt.wantErr {
+				t.Fatalf("Refresh() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			quotes := e.Quotes()
+			if len(quotes) != 2 || quotes[0].Side != OrderSideBuy {
+				t.Fatalf("quotes = %+v, want a bid and an ask", quotes)
+			}
+			if replaced := quotes[0].OrderID != bid.OrderID; replaced != tt.wantReplaced {
+				t.Errorf("bid %s, replaced %v, want replaced %v", quotes[0].OrderID, replaced, tt.wantReplaced)
+			}
+			wantPlaced := int64(2)
+			if tt.wantReplaced {
+				wantPlaced = 3
+			}
+			if placed := exchange.placed.Load(); placed != wantPlaced {
+				t.Errorf("placed %d orders, want %d", placed, wantPlaced)
+			}
+		})
+	}
+}
+
+func TestQuotingEngineFailedCancelBlocksReplacement(t *testing.T) {
+	var mu sync.Mutex
+	price := 0.5
+	e, exchange := newTestQuotingEngine(t, func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return price
+	})
+	bid := e.Quotes()[0]
+
+	// The ladder moves, but the bid cannot be cancelled
+	mu.Lock()
+	price = 0.6
+	mu.Unlock()
+	exchange.beforeCancel = func(order *OrderJSON) error {
+		if order.OrderID == bid.OrderID {
+			return errors.New("cancel rejected")
+		}
+		return nil
+	}
+
+	if err := e.Refresh(); err == nil {
+		t.Fatal("Refresh() succeeded despite the failed cancel")
+	}
+	var bids, asks int
+	for _, quote := range e.Quotes() {
+		if quote.Side == OrderSideBuy {
+			bids++
+			if quote.OrderID != bid.OrderID {
+				t.Errorf("bid %s placed while %s could not be cancelled", quote.OrderID, bid.OrderID)
+			}
+		} else {
+			asks++
+			if quote.Price < 0.6 {
+				t.Errorf("ask at %v was not moved", quote.Price)
+			}
+		}
+	}
+	if bids != 1 || asks != 1 {
+		t.Errorf("%d bids and %d asks, want one of each", bids, asks)
+	}
+
+	// Once the cancel succeeds, the bid is replaced
+	exchange.beforeCancel = nil
+	if err := e.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if quote := e.Quotes()[0]; quote.OrderID == bid.OrderID || quote.Price < 0.59 {
+		t.Errorf("bid = %+v, want it replaced near 0.6", quote)
+	}
+}
+
+func TestQuotingEngineDoesNotLockDuringNetworkCalls(t *testing.T) {
+	var (
+		e       *QuotingEngine
+		once    sync.Once
+		blocked = make(chan bool, 1)
+	)
+	e, _ = newTestQuotingEngine(t, func() float64 {
+		if e != nil {
+			// Quotes and Config must answer while Refresh waits for the server
+			once.Do(func() {
+				done := make(chan struct{})
+				go func() {
+					e.Quotes()
+					e.Config()
+					close(done)
+				}()
+				select {
+				case <-done:
+					blocked <- false
+				case <-time.After(2 * time.Second):
+					blocked <- true
+				}
+			})
+		}
+		return 0.5
+	})
+
+	if err := e.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if <-blocked {
+		t.Fatal("Quotes blocked while Refresh was fetching prices")
+	}
+}
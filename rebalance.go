@@ -0,0 +1,423 @@
+package deltadefi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRebalanceQuoteAsset is the asset portfolios are valued and traded in
+	DefaultRebalanceQuoteAsset = "USDM"
+	// DefaultRebalanceSettleTimeout bounds the wait for each rebalancing order to complete
+	DefaultRebalanceSettleTimeout = time.Minute
+)
+
+// RebalanceRequest describes a target allocation of the account.
+type RebalanceRequest struct {
+	// Targets maps assets to their target share of the portfolio value; weights must sum to 1.
+	// Assets not listed are left untouched and excluded from the portfolio value.
+	Targets map[string]float64
+	// QuoteAsset is the asset the portfolio is valued in and every trade goes through
+	// (defaults to DefaultRebalanceQuoteAsset)
+	QuoteAsset string
+	// ThresholdBasisPoints skips assets whose weight is within this distance of the target
+	ThresholdBasisPoints int
+	// MinOrderQuote skips trades worth less than this quote amount
+	MinOrderQuote float64
+	// LotSizes are the base quantity steps per symbol; quantities are rounded down to a multiple (optional)
+	LotSizes map[Symbol]float64
+	// MinQuantities are the minimum base quantities per symbol; smaller trades are skipped (optional)
+	MinQuantities map[Symbol]float64
+	// MaxSlippageBasisPoint bounds the slippage of each order (optional)
+	MaxSlippageBasisPoint int
+	// DryRun builds the orders without submitting them (implied when the client runs in dry-run mode)
+	DryRun bool
+	// SettleTimeout bounds the wait for each order to complete (defaults to DefaultRebalanceSettleTimeout)
+	SettleTimeout time.Duration
+}
+
+// RebalanceHolding is the position of one asset in the portfolio.
+type RebalanceHolding struct {
+	Asset string
+	// Quantity is the free plus locked balance
+	Quantity float64
+	// Price is the asset price in the quote asset
+	Price        float64
+	Value        float64
+	Weight       float64
+	TargetWeight float64
+}
+
+// Deviation returns how far the weight is from the target, in basis points (positive when overweight).
+func (h RebalanceHolding) Deviation() float64 {
+	return (h.Weight - h.TargetWeight) * 10000
+}
+
+// RebalanceTrade is an order needed to reach the target allocation.
+type RebalanceTrade struct {
+	Symbol   Symbol
+	Side     OrderSide
+	Asset    string
+	Quantity float64
+	// Price is the market price the trade was planned at
+	Price float64
+	// SkipReason explains why the trade is not placed, empty if it is
+	SkipReason string
+
+	// OrderID is the ID of the order placed for the trade
+	OrderID string
+	// ExecutedQuantity and ExecutedQuote are what the order actually traded
+	ExecutedQuantity float64
+	ExecutedQuote    float64
+	// DryRunOrder is the validated order in dry-run mode; it is never sent to the exchange
+	DryRunOrder *BuildPlaceOrderTransactionRequest
+	// Err is the error that prevented or interrupted the trade
+	Err error
+}
+
+// RebalancePlan is the valuation of the portfolio and the trades that reach the target allocation.
+type RebalancePlan struct {
+	QuoteAsset string
+	TotalValue float64
+	Holdings   []RebalanceHolding
+	// Trades lists sells before buys, including trades skipped by the thresholds
+	Trades []RebalanceTrade
+}
+
+// RebalanceReport is the outcome of a rebalance.
+type RebalanceReport struct {
+	DryRun bool
+	Plan   *RebalancePlan
+	// Trades are the trades of the plan with their results
+	Trades []RebalanceTrade
+	// Achieved are the holdings after rebalancing; in dry-run mode they are projected from the plan
+	Achieved []RebalanceHolding
+}
+
+// PlanRebalance values the portfolio at current market prices and computes the trades that bring
+// it to the target weights, without placing any order.
+//
+// Parameters:
+//   - req: The target allocation and trade constraints
+//
+// Returns:
+//   - *RebalancePlan: The valuation and trades
+//   - error: nil on success, error if the request is invalid or data cannot be fetched
+func (d *DeltaDeFi) PlanRebalance(req *RebalanceRequest) (*RebalancePlan, error) {
+	quote, err := validateRebalanceRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	balances, err := d.Accounts.GetAccountBalance()
+	if err != nil {
+		return nil, fmt.Errorf("fetching balances: %w", err)
+	}
+	holdings, symbols, err := d.rebalanceHoldings(*balances, req.Targets, quote)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &RebalancePlan{QuoteAsset: quote, Holdings: holdings}
+	for _, holding := range holdings {
+		plan.TotalValue += holding.Value
+	}
+	if plan.TotalValue <= 0 {
+		return nil, fmt.Errorf("portfolio has no value")
+	}
+
+	for _, holding := range holdings {
+		if strings.EqualFold(holding.Asset, quote) {
+			continue
+		}
+		symbol := symbols[holding.Asset]
+		delta := holding.TargetWeight*plan.TotalValue - holding.Value
+		trade := RebalanceTrade{
+			Symbol:   symbol,
+			Side:     OrderSideBuy,
+			Asset:    holding.Asset,
+			Quantity: math.Abs(delta) / holding.Price,
+			Price:    holding.Price,
+		}
+		if delta < 0 {
+			trade.Side = OrderSideSell
+			if free := findAssetBalance(*balances, holding.Asset).Free; trade.Quantity > free {
+				trade.Quantity = free
+			}
+		}
+		if lot := req.LotSizes[symbol]; lot > 0 {
+			trade.Quantity = math.Floor(trade.Quantity/lot+quantityEpsilon) * lot
+		}
+
+		switch {
+		case math.Abs(holding.Deviation()) < float64(req.ThresholdBasisPoints):
+			trade.SkipReason = fmt.Sprintf("weight within %d bps of target", req.ThresholdBasisPoints)
+		case trade.Quantity <= quantityEpsilon:
+			trade.SkipReason = "quantity rounds to zero"
+		case trade.Quantity < req.MinQuantities[symbol]-quantityEpsilon:
+			trade.SkipReason = fmt.Sprintf("quantity below minimum %g", req.MinQuantities[symbol])
+		case trade.Quantity*trade.Price < req.MinOrderQuote:
+			trade.SkipReason = fmt.Sprintf("worth less than minimum order %g %s", req.MinOrderQuote, quote)
+		}
+		plan.Trades = append(plan.Trades, trade)
+	}
+
+	// Sells first, so their proceeds can pay for the buys
+	sort.SliceStable(plan.Trades, func(i, j int) bool {
+		return plan.Trades[i].Side == OrderSideSell && plan.Trades[j].Side == OrderSideBuy
+	})
+	return plan, nil
+}
+
+// Rebalance brings the portfolio to the target weights with market orders: sells are placed
+// first and each order is awaited before the next, and buys are scaled down when the sells did
+// not raise enough quote currency. In dry-run mode the orders are only validated locally, so
+// nothing is created on the exchange, and the achieved weights are projected from the plan.
+//
+// Parameters:
+//   - ctx: Context bounding the whole rebalance
+//   - req: The target allocation and trade constraints
+//
+// Returns:
+//   - *RebalanceReport: The plan, trade results and achieved weights
+//   - error: nil when all trades succeeded, otherwise the first trade error (the report is still returned)
+func (d *DeltaDeFi) Rebalance(ctx context.Context, req *RebalanceRequest) (*RebalanceReport, error) {
+	plan, err := d.PlanRebalance(req)
+	if err != nil {
+		return nil, err
+	}
+	settle := req.SettleTimeout
+	if settle <= 0 {
+		settle = DefaultRebalanceSettleTimeout
+	}
+	report := &RebalanceReport{
+		DryRun: req.DryRun || d.DryRunEnabled(),
+		Plan:   plan,
+		Trades: append([]RebalanceTrade(nil), plan.Trades...),
+	}
+
+	var firstErr error
+	scaled := false
+	for i := range report.Trades {
+		trade := &report.Trades[i]
+		if trade.Side == OrderSideBuy && !scaled && !report.DryRun {
+			scaled = true
+			if err := d.scaleRebalanceBuys(report.Trades[i:], plan.QuoteAsset, req); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		if trade.SkipReason != "" {
+			continue
+		}
+
+		if trade.Err = ctx.Err(); trade.Err == nil {
+			d.executeRebalanceTrade(ctx, trade, req, report.DryRun, settle)
+		}
+		if trade.Err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s %s: %w", trade.Side, trade.Symbol, trade.Err)
+		}
+	}
+
+	if report.DryRun {
+		report.Achieved = projectRebalance(plan)
+		return report, firstErr
+	}
+	balances, err := d.Accounts.GetAccountBalance()
+	if err == nil {
+		report.Achieved, _, err = d.rebalanceHoldings(*balances, req.Targets, plan.QuoteAsset)
+	}
+	if err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("valuing rebalanced portfolio: %w", err)
+	}
+	return report, firstErr
+}
+
+// executeRebalanceTrade places one trade and records its result.
+func (d *DeltaDeFi) executeRebalanceTrade(ctx context.Context, trade *RebalanceTrade, req *RebalanceRequest, dryRun bool, settle time.Duration) {
+	order := &BuildPlaceOrderTransactionRequest{
+		Symbol:   trade.Symbol,
+		Side:     trade.Side,
+		Type:     OrderTypeMarket,
+		Quantity: trade.Quantity,
+	}
+	if req.MaxSlippageBasisPoint > 0 {
+		order.MaxSlippageBasisPoint = IntPtr(req.MaxSlippageBasisPoint)
+		order.LimitSlippage = BoolPtr(true)
+	}
+
+	if dryRun {
+		// Building the order would create its record on the exchange, so only validate it
+		trade.DryRunOrder, trade.Err = d.validateOrder(order)
+		return
+	}
+
+	res, err := d.PostOrder(order)
+	if err != nil {
+		trade.Err = err
+		return
+	}
+	trade.OrderID = res.Order.OrderID
+
+	waitCtx, cancel := context.WithTimeout(ctx, settle)
+	defer cancel()
+	final, err := d.WaitForOrder(waitCtx, trade.OrderID, OrderIsTerminal)
+	if final != nil {
+		trade.ExecutedQuantity, trade.ExecutedQuote, _ = executedQuote(final)
+	}
+	if err != nil {
+		trade.Err = fmt.Errorf("waiting for order %s: %w", trade.OrderID, err)
+	}
+}
+
+// scaleRebalanceBuys shrinks the remaining buys proportionally when the free quote balance cannot
+// pay for all of them.
+func (d *DeltaDeFi) scaleRebalanceBuys(trades []RebalanceTrade, quote string, req *RebalanceRequest) error {
+	balances, err := d.Accounts.GetAccountBalance()
+	if err != nil {
+		return fmt.Errorf("fetching balances: %w", err)
+	}
+	free := findAssetBalance(*balances, quote).Free
+
+	slippage := 1 + float64(req.MaxSlippageBasisPoint)/10000
+	var needed float64
+	for _, trade := range trades {
+		if trade.Side == OrderSideBuy && trade.SkipReason == "" {
+			needed += trade.Quantity * trade.Price * slippage
+		}
+	}
+	if needed <= free || needed <= 0 {
+		return nil
+	}
+
+	ratio := free / needed
+	for i := range trades {
+		trade := &trades[i]
+		if trade.Side != OrderSideBuy || trade.SkipReason != "" {
+			continue
+		}
+		trade.Quantity *= ratio
+		if lot := req.LotSizes[trade.Symbol]; lot > 0 {
+			trade.Quantity = math.Floor(trade.Quantity/lot+quantityEpsilon) * lot
+		}
+		if trade.Quantity <= quantityEpsilon || trade.Quantity < req.MinQuantities[trade.Symbol]-quantityEpsilon ||
+			trade.Quantity*trade.Price < req.MinOrderQuote {
+			trade.SkipReason = fmt.Sprintf("insufficient %s balance after sells", quote)
+		}
+	}
+	return nil
+}
+
+// rebalanceHoldings values the target assets of the balances at current market prices. It also returns the
+// trading pair of each non-quote asset.
+func (d *DeltaDeFi) rebalanceHoldings(balances []AssetBalance, targets map[string]float64, quote string) ([]RebalanceHolding, map[string]Symbol, error) {
+	limits := d.risk.limits.Load()
+
+	assets := make([]string, 0, len(targets))
+	for asset := range targets {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+
+	symbols := make(map[string]Symbol)
+	holdings := make([]RebalanceHolding, 0, len(assets))
+	var total float64
+	for _, asset := range assets {
+		balance := findAssetBalance(balances, asset)
+		holding := RebalanceHolding{
+			Asset:        asset,
+			Quantity:     balance.Free + balance.Locked,
+			Price:        1,
+			TargetWeight: targets[asset],
+		}
+		if !strings.EqualFold(asset, quote) {
+			symbol, err := limits.symbol(asset, quote)
+			if err != nil {
+				return nil, nil, err
+			}
+			market, err := d.Market.GetMarketPrice(string(symbol))
+			if err != nil {
+				return nil, nil, fmt.Errorf("fetching %s price: %w", symbol, err)
+			}
+			if market.Price <= 0 {
+				return nil, nil, fmt.Errorf("no market price for %s", symbol)
+			}
+			symbols[asset] = symbol
+			holding.Price = market.Price
+		}
+		holding.Value = holding.Quantity * holding.Price
+		total += holding.Value
+		holdings = append(holdings, holding)
+	}
+	setRebalanceWeights(holdings, total)
+	return holdings, symbols, nil
+}
+
+// projectRebalance applies the planned trades to the holdings at the planned prices.
+func projectRebalance(plan *RebalancePlan) []RebalanceHolding {
+	holdings := append([]RebalanceHolding(nil), plan.Holdings...)
+	index := make(map[string]int, len(holdings))
+	for i, holding := range holdings {
+		index[strings.ToUpper(holding.Asset)] = i
+	}
+
+	for _, trade := range plan.Trades {
+		if trade.SkipReason != "" {
+			continue
+		}
+		sign := 1.0
+		if trade.Side == OrderSideSell {
+			sign = -1
+		}
+		if i, ok := index[strings.ToUpper(trade.Asset)]; ok {
+			holdings[i].Quantity += sign * trade.Quantity
+		}
+		if i, ok := index[strings.ToUpper(plan.QuoteAsset)]; ok {
+			holdings[i].Quantity -= sign * trade.Quantity * trade.Price
+		}
+	}
+
+	var total float64
+	for i := range holdings {
+		holdings[i].Value = holdings[i].Quantity * holdings[i].Price
+		total += holdings[i].Value
+	}
+	setRebalanceWeights(holdings, total)
+	return holdings
+}
+
+// setRebalanceWeights sets the weight of each holding from the total value.
+func setRebalanceWeights(holdings []RebalanceHolding, total float64) {
+	for i := range holdings {
+		holdings[i].Weight = 0
+		if total > 0 {
+			holdings[i].Weight = holdings[i].Value / total
+		}
+	}
+}
+
+// validateRebalanceRequest checks the target weights and returns the quote asset.
+func validateRebalanceRequest(req *RebalanceRequest) (string, error) {
+	if len(req.Targets) == 0 {
+		return "", errors.New("rebalance targets are required")
+	}
+	var sum float64
+	for asset, weight := range req.Targets {
+		if weight < 0 {
+			return "", fmt.Errorf("negative target weight for %s", asset)
+		}
+		sum += weight
+	}
+	if math.Abs(sum-1) > 1e-6 {
+		return "", fmt.Errorf("target weights sum to %g, not 1", sum)
+	}
+
+	quote := req.QuoteAsset
+	if quote == "" {
+		quote = DefaultRebalanceQuoteAsset
+	}
+	return quote, nil
+}
@@ -0,0 +1,131 @@
+package deltadefi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRebalanceDryRun(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       ApiConfig
+		req       RebalanceRequest
+		limits    *RiskLimits
+		wantSkip  bool
+		wantErr   bool
+		wantOrder bool
+	}{
+		{
+			name:      "request dry run",
+			req:       RebalanceRequest{DryRun: true},
+			wantOrder: true,
+		},
+		{
+			name:      "client dry run",
+			cfg:       ApiConfig{DryRun: true},
+			wantOrder: true,
+		},
+		{
+			name:     "below minimum quantity",
+			req:      RebalanceRequest{DryRun: true, MinQuantities: map[Symbol]float64{ADAUSDM: 60}},
+			wantSkip: true,
+		},
+		{
+			name:      "at minimum quantity",
+			req:       RebalanceRequest{DryRun: true, MinQuantities: map[Symbol]float64{ADAUSDM: 50}},
+			wantOrder: true,
+		},
+		{
+			name:    "risk checks apply",
+			req:     RebalanceRequest{DryRun: true},
+			limits:  &RiskLimits{MaxOrderNotional: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, tt.cfg)
+			exchange.price = 0.5
+			exchange.balances = []AssetBalance{{Asset: "ADA", Free: 100}, {Asset: "USDM"}}
+			if tt.limits != nil {
+				d.Risk().SetLimits(tt.limits)
+			}
+			req := tt.req
+			req.Targets = map[string]float64{"ADA": 0.5, "USDM": 0.5}
+
+			report, err := d.Rebalance(context.Background(), &req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Rebalance() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if report == nil || !report.DryRun || len(report.Trades) != 1 {
+				t.Fatalf("report = %+v, want one dry-run trade", report)
+			}
+			if n := exchange.count(http.MethodPost, "/order/build"); n != 0 {
+				t.Errorf("built %d orders on the exchange", n)
+			}
+
+			trade := report.Trades[0]
+			if trade.Side != OrderSideSell || trade.Quantity != 50 {
+				t.Errorf("trade = %s %v, want sell 50", trade.Side, trade.Quantity)
+			}
+			if (trade.SkipReason != "") != tt.wantSkip {
+				t.Errorf("SkipReason = %q, want skip %v", trade.SkipReason, tt.wantSkip)
+			}
+			if (trade.DryRunOrder != nil) != tt.wantOrder {
+				t.Errorf("DryRunOrder = %+v, want order %v", trade.DryRunOrder, tt.wantOrder)
+			}
+			if trade.OrderID != "" {
+				t.Errorf("OrderID = %s, want none", trade.OrderID)
+			}
+			if tt.wantOrder {
+				for _, holding := range report.Achieved {
+					if holding.Weight < 0.49 || holding.Weight > 0.51 {
+						t.Errorf("projected %s weight = %v, want 0.5", holding.Asset, holding.Weight)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPlanRebalanceMinQuantities(t *testing.T) {
+	tests := []struct {
+		name         string
+		minQuantity  float64
+		lotSize      float64
+		wantQuantity float64
+		wantSkip     bool
+	}{
+		{name: "no minimum", wantQuantity: 50},
+		{name: "above minimum", minQuantity: 40, wantQuantity: 50},
+		{name: "below minimum", minQuantity: 51, wantQuantity: 50, wantSkip: true},
+		{name: "rounded below minimum", minQuantity: 45, lotSize: 30, wantQuantity: 30, wantSkip: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.price = 0.5
+			exchange.balances = []AssetBalance{{Asset: "ADA", Free: 100}, {Asset: "USDM"}}
+
+			plan, err := d.PlanRebalance(&RebalanceRequest{
+				Targets:       map[string]float64{"ADA": 0.5, "USDM": 0.5},
+				LotSizes:      map[Symbol]float64{ADAUSDM: tt.lotSize},
+				MinQuantities: map[Symbol]float64{ADAUSDM: tt.minQuantity},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plan.Trades) != 1 {
+				t.Fatalf("trades = %+v, want one", plan.Trades)
+			}
+			trade := plan.Trades[0]
+			if trade.Quantity != tt.wantQuantity {
+				t.Errorf("Quantity = %v, want %v", trade.Quantity, tt.wantQuantity)
+			}
+			if (trade.SkipReason != "") != tt.wantSkip {
+				t.Errorf("SkipReason = %q, want skip %v", trade.SkipReason, tt.wantSkip)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package deltadefi
 import "github.com/sidan-lab/rum"
 
 // SignInRequest contains credentials for user authentication.
+// The SDK offers no sign-in call, as the endpoint is not part of the documented API.
 type SignInRequest struct {
 	WalletAddress string `json:"wallet_address"`
 	AuthKey       string `json:"auth_key"`
@@ -0,0 +1,495 @@
+package deltadefi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RiskCheck identifies a pre-trade risk check.
+type RiskCheck string
+
+const (
+	// RiskCheckOrderNotional limits the quote value of a single order
+	RiskCheckOrderNotional RiskCheck = "order_notional"
+	// RiskCheckPosition limits the holdings of a base asset after the order fills
+	RiskCheckPosition RiskCheck = "position"
+	// RiskCheckOpenOrders limits the number of open orders
+	RiskCheckOpenOrders RiskCheck = "open_orders"
+	// RiskCheckOrderRate limits the number of orders per minute
+	RiskCheckOrderRate RiskCheck = "order_rate"
+	// RiskCheckPriceBand limits the deviation of a limit price from the market price
+	RiskCheckPriceBand RiskCheck = "price_band"
+	// RiskCheckBalance requires enough free balance to pay for the order
+	RiskCheckBalance RiskCheck = "balance"
+)
+
+// RiskError is returned when an order violates a pre-trade risk limit. Nothing was placed.
+type RiskError struct {
+	Check RiskCheck
+	// Limit is the configured limit
+	Limit float64
+	// Value is the value the order would have reached
+	Value float64
+	// Asset is the asset the check applies to, if any
+	Asset string
+}
+
+// Error implements the error interface.
+func (e *RiskError) Error() string {
+	if e.Asset != "" {
+		return fmt.Sprintf("risk check %s failed for %s: %g exceeds limit %g", e.Check, e.Asset, e.Value, e.Limit)
+	}
+	return fmt.Sprintf("risk check %s failed: %g exceeds limit %g", e.Check, e.Value, e.Limit)
+}
+
+// SymbolAssets names the base and quote assets of a trading pair as they appear in account balances.
+type SymbolAssets struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"`
+}
+
+// defaultSymbolAssets holds the assets of the supported trading pairs.
+var defaultSymbolAssets = map[Symbol]SymbolAssets{
+	ADAUSDM: {Base: "ADA", Quote: "USDM"},
+}
+
+// RiskLimits configures the pre-trade checks. Zero values disable a check.
+type RiskLimits struct {
+	// MaxOrderNotional is the largest quote value (quantity times price) of a single order
+	MaxOrderNotional float64 `json:"max_order_notional,omitempty"`
+	// MaxPosition is the largest holding per base asset, counting free and locked balance plus open buy orders
+	MaxPosition map[string]float64 `json:"max_position,omitempty"`
+	// MaxOpenOrders is the largest number of open orders across all symbols
+	MaxOpenOrders int `json:"max_open_orders,omitempty"`
+	// MaxOrdersPerMinute is the largest number of orders accepted in any 60 second window
+	MaxOrdersPerMinute int `json:"max_orders_per_minute,omitempty"`
+	// PriceBandBasisPoints is the largest deviation of a limit price from the market price
+	PriceBandBasisPoints int `json:"price_band_basis_points,omitempty"`
+	// CheckBalance requires enough free balance to pay for the order
+	CheckBalance bool `json:"check_balance,omitempty"`
+	// Assets overrides the base and quote assets of trading pairs (defaults cover the supported pairs)
+	Assets map[Symbol]SymbolAssets `json:"assets,omitempty"`
+}
+
+// LoadRiskLimitsFile reads risk limits from a JSON file, e.g. to reload them with RiskEngine.SetLimits.
+//
+// Parameters:
+//   - path: The JSON file
+//
+// Returns:
+//   - *RiskLimits: The parsed limits
+//   - error: nil on success, error if the file cannot be read or parsed
+func LoadRiskLimitsFile(path string) (*RiskLimits, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var limits RiskLimits
+	err = json.Unmarshal(content, &limits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid risk limits %s: %w", path, err)
+	}
+	return &limits, nil
+}
+
+// assets returns the base and quote assets of a trading pair.
+func (l *RiskLimits) assets(symbol Symbol) (SymbolAssets, error) {
+	if assets, ok := l.Assets[symbol]; ok {
+		return assets, nil
+	}
+	if assets, ok := defaultSymbolAssets[symbol]; ok {
+		return assets, nil
+	}
+	return SymbolAssets{}, fmt.Errorf("unknown assets for symbol %s", symbol)
+}
+
+// symbol returns the trading pair of a base and quote asset.
+func (l *RiskLimits) symbol(base, quote string) (Symbol, error) {
+	for _, known := range []map[Symbol]SymbolAssets{l.Assets, defaultSymbolAssets} {
+		for symbol, assets := range known {
+			if strings.EqualFold(assets.Base, base) && strings.EqualFold(assets.Quote, quote) {
+				return symbol, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no trading pair for %s/%s", base, quote)
+}
+
+// RiskEngine applies pre-trade risk checks to every order placed through the client.
+// Limits can be replaced at any time with SetLimits; orders checked afterwards use the new limits.
+// Orders are reserved while they are built and submitted, so concurrent placements count towards
+// each other's open order, position, balance and rate limits.
+// A RiskEngine is safe for concurrent use.
+type RiskEngine struct {
+	d      *DeltaDeFi
+	limits atomic.Pointer[RiskLimits]
+
+	mu sync.Mutex
+	// accepted holds the acceptance times within the last minute, oldest first
+	accepted []time.Time
+	// reservations holds orders being placed, and placed orders that evaluations which started
+	// before their submission completed may not have seen on the server yet
+	reservations map[*riskReservation]struct{}
+	// seq counts completed submissions
+	seq uint64
+	// evaluating counts the running evaluations by the seq they started at
+	evaluating map[uint64]int
+}
+
+// riskReservation is an order that passed the checks and is being placed.
+type riskReservation struct {
+	e *RiskEngine
+
+	symbol     Symbol
+	side       OrderSide
+	quantity   float64
+	cost       float64
+	baseAsset  string
+	quoteAsset string
+
+	// committedSeq is the seq at which the submission completed, 0 while it is in flight
+	committedSeq uint64
+	done         bool
+}
+
+// newRiskEngine creates a risk engine with the given limits (nil disables every check).
+func newRiskEngine(d *DeltaDeFi, limits *RiskLimits) *RiskEngine {
+	e := &RiskEngine{
+		d:            d,
+		reservations: make(map[*riskReservation]struct{}),
+		evaluating:   make(map[uint64]int),
+	}
+	e.SetLimits(limits)
+	return e
+}
+
+// Risk returns the pre-trade risk engine of the client.
+func (d *DeltaDeFi) Risk() *RiskEngine {
+	return d.risk
+}
+
+// SetLimits replaces the risk limits. Nil disables every check.
+// The maps of the limits are shared, not copied; do not modify them afterwards.
+//
+// Parameters:
+//   - limits: The new limits
+func (e *RiskEngine) SetLimits(limits *RiskLimits) {
+	if limits == nil {
+		limits = &RiskLimits{}
+	}
+	copied := *limits
+	e.limits.Store(&copied)
+}
+
+// Limits returns the current risk limits.
+func (e *RiskEngine) Limits() RiskLimits {
+	return *e.limits.Load()
+}
+
+// Check runs the configured pre-trade checks against an order, taking orders that are being placed
+// into account. The order is not counted towards the order rate; PostOrder and the other placement
+// methods reserve and count orders automatically.
+//
+// Parameters:
+//   - data: The order to check
+//
+// Returns:
+//   - error: *RiskError if a limit is violated, other error if the data needed for a check cannot be fetched
+func (e *RiskEngine) Check(data *BuildPlaceOrderTransactionRequest) error {
+	_, err := e.evaluate(data, false)
+	return err
+}
+
+// reserve runs the pre-trade checks and reserves the order, so concurrent placements see it.
+// The caller must commit the reservation once the order was submitted and release it otherwise.
+func (e *RiskEngine) reserve(data *BuildPlaceOrderTransactionRequest) (*riskReservation, error) {
+	return e.evaluate(data, true)
+}
+
+// evaluate runs the pre-trade checks. Server data is fetched without holding e.mu; the limits are
+// then evaluated against it together with the reservations under e.mu, and the order is reserved
+// when reserve is set.
+func (e *RiskEngine) evaluate(data *BuildPlaceOrderTransactionRequest, reserve bool) (*riskReservation, error) {
+	limits := e.limits.Load()
+
+	e.mu.Lock()
+	start := e.seq
+	e.evaluating[start]++
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.evaluating[start]--; e.evaluating[start] == 0 {
+			delete(e.evaluating, start)
+		}
+		e.pruneReservations()
+	}()
+
+	var price float64
+	needPrice := limits.MaxOrderNotional > 0 || limits.PriceBandBasisPoints > 0 || limits.CheckBalance
+	if needPrice {
+		var err error
+		price, err = e.orderPrice(data, limits)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if limits.MaxOrderNotional > 0 {
+		notional := data.Quantity * price
+		if notional > limits.MaxOrderNotional {
+			return nil, &RiskError{Check: RiskCheckOrderNotional, Limit: limits.MaxOrderNotional, Value: notional}
+		}
+	}
+
+	var open []OrderJSON
+	if limits.MaxOpenOrders > 0 || len(limits.MaxPosition) > 0 {
+		var err error
+		open, err = e.d.fetchOpenOrders("")
+		if err != nil {
+			return nil, fmt.Errorf("fetching open orders for risk checks: %w", err)
+		}
+	}
+
+	// Reservations record the assets even when no balance check needs them, for later evaluations
+	needBalances := len(limits.MaxPosition) > 0 || limits.CheckBalance
+	assets, err := limits.assets(data.Symbol)
+	if err != nil && needBalances {
+		return nil, err
+	}
+	var balances []AssetBalance
+	if needBalances {
+		res, err := e.d.Accounts.GetAccountBalance()
+		if err != nil {
+			return nil, fmt.Errorf("fetching balances for risk checks: %w", err)
+		}
+		balances = *res
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var inFlight []*riskReservation
+	for r := range e.reservations {
+		// Submissions completed before the evaluation started are part of the fetched server state
+		if r.committedSeq == 0 || r.committedSeq > start {
+			inFlight = append(inFlight, r)
+		}
+	}
+
+	if limits.MaxOpenOrders > 0 && len(open)+len(inFlight)+1 > limits.MaxOpenOrders {
+		value := float64(len(open) + len(inFlight) + 1)
+		return nil, &RiskError{Check: RiskCheckOpenOrders, Limit: float64(limits.MaxOpenOrders), Value: value}
+	}
+
+	if needBalances {
+		if err := checkBalances(data, limits, assets, balances, price, open, inFlight); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.checkRate(limits); err != nil {
+		return nil, err
+	}
+
+	if !reserve {
+		return nil, nil
+	}
+	if price == 0 && data.Price != nil {
+		price = *data.Price
+	}
+	r := &riskReservation{
+		e:          e,
+		symbol:     data.Symbol,
+		side:       data.Side,
+		quantity:   data.Quantity,
+		cost:       data.Quantity * price,
+		baseAsset:  assets.Base,
+		quoteAsset: assets.Quote,
+	}
+	e.reservations[r] = struct{}{}
+	return r, nil
+}
+
+// commit counts the reserved order as accepted after it was submitted.
+func (r *riskReservation) commit() {
+	e := r.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+	e.seq++
+	r.committedSeq = e.seq
+	e.accepted = append(e.accepted, time.Now())
+	e.pruneReservations()
+}
+
+// release drops the reservation of an order that was not placed. It has no effect after commit.
+func (r *riskReservation) release() {
+	e := r.e
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if r.done {
+		return
+	}
+	r.done = true
+	delete(e.reservations, r)
+}
+
+// pruneReservations forgets placed orders that every running evaluation sees on the server.
+// The caller must hold e.mu.
+func (e *RiskEngine) pruneReservations() {
+	oldest := e.seq
+	for start := range e.evaluating {
+		if start < oldest {
+			oldest = start
+		}
+	}
+	for r := range e.reservations {
+		if r.committedSeq != 0 && r.committedSeq <= oldest {
+			delete(e.reservations, r)
+		}
+	}
+}
+
+// orderPrice returns the limit price of the order, or the market price (worst case within the
+// slippage bound) for market orders. Limit prices are also checked against the price band.
+func (e *RiskEngine) orderPrice(data *BuildPlaceOrderTransactionRequest, limits *RiskLimits) (float64, error) {
+	if data.Price != nil && limits.PriceBandBasisPoints <= 0 {
+		return *data.Price, nil
+	}
+
+	market, err := e.d.Market.GetMarketPrice(string(data.Symbol))
+	if err != nil {
+		return 0, fmt.Errorf("fetching market price for risk checks: %w", err)
+	}
+	if market.Price <= 0 {
+		return 0, fmt.Errorf("no market price for %s", data.Symbol)
+	}
+
+	if data.Price != nil {
+		deviation := math.Abs(*data.Price-market.Price) / market.Price * 10000
+		if deviation > float64(limits.PriceBandBasisPoints) {
+			return 0, &RiskError{Check: RiskCheckPriceBand, Limit: float64(limits.PriceBandBasisPoints), Value: math.Round(deviation)}
+		}
+		return *data.Price, nil
+	}
+
+	if data.MaxSlippageBasisPoint != nil {
+		slippage := float64(*data.MaxSlippageBasisPoint) / 10000
+		if data.Side == OrderSideBuy {
+			return market.Price * (1 + slippage), nil
+		}
+		return market.Price * (1 - slippage), nil
+	}
+	return market.Price, nil
+}
+
+// checkBalances enforces the position limit of the base asset and the free balance needed by the
+// order, counting the reserved orders on top of the server state.
+func checkBalances(data *BuildPlaceOrderTransactionRequest, limits *RiskLimits, assets SymbolAssets, balances []AssetBalance, price float64, open []OrderJSON, inFlight []*riskReservation) error {
+	base := findAssetBalance(balances, assets.Base)
+	quote := findAssetBalance(balances, assets.Quote)
+
+	if maxPosition, ok := lookupAssetLimit(limits.MaxPosition, assets.Base); ok && data.Side == OrderSideBuy {
+		position := base.Free + base.Locked + data.Quantity
+		for i := range open {
+			if open[i].Symbol != data.Symbol || open[i].Side != OrderSideBuy {
+				continue
+			}
+			remaining, err := open[i].RemainingQuantity()
+			if err != nil {
+				return err
+			}
+			position += remaining
+		}
+		for _, r := range inFlight {
+			if r.side == OrderSideBuy && strings.EqualFold(r.baseAsset, assets.Base) {
+				position += r.quantity
+			}
+		}
+		if position > maxPosition {
+			return &RiskError{Check: RiskCheckPosition, Limit: maxPosition, Value: position, Asset: assets.Base}
+		}
+	}
+
+	if limits.CheckBalance {
+		if data.Side == OrderSideBuy {
+			free := quote.Free
+			for _, r := range inFlight {
+				if r.side == OrderSideBuy && strings.EqualFold(r.quoteAsset, assets.Quote) {
+					free -= r.cost
+				}
+			}
+			cost := data.Quantity * price
+			if cost > free {
+				return &RiskError{Check: RiskCheckBalance, Limit: free, Value: cost, Asset: assets.Quote}
+			}
+		} else {
+			free := base.Free
+			for _, r := range inFlight {
+				if r.side == OrderSideSell && strings.EqualFold(r.baseAsset, assets.Base) {
+					free -= r.quantity
+				}
+			}
+			if data.Quantity > free {
+				return &RiskError{Check: RiskCheckBalance, Limit: free, Value: data.Quantity, Asset: assets.Base}
+			}
+		}
+	}
+	return nil
+}
+
+// checkRate enforces the order rate limit, counting accepted orders and orders being placed.
+// The caller must hold e.mu.
+func (e *RiskEngine) checkRate(limits *RiskLimits) error {
+	now := time.Now()
+	cutoff := now.Add(-time.Minute)
+	i := 0
+	for i < len(e.accepted) && !e.accepted[i].After(cutoff) {
+		i++
+	}
+	e.accepted = e.accepted[i:]
+
+	if limits.MaxOrdersPerMinute <= 0 {
+		return nil
+	}
+	count := len(e.accepted) + 1
+	for r := range e.reservations {
+		if r.committedSeq == 0 {
+			count++
+		}
+	}
+	if count > limits.MaxOrdersPerMinute {
+		return &RiskError{Check: RiskCheckOrderRate, Limit: float64(limits.MaxOrdersPerMinute), Value: float64(count)}
+	}
+	return nil
+}
+
+// findAssetBalance returns the balance of an asset, matched case-insensitively. Missing assets have zero balance.
+func findAssetBalance(balances []AssetBalance, asset string) AssetBalance {
+	for _, balance := range balances {
+		if strings.EqualFold(balance.Asset, asset) {
+			return balance
+		}
+	}
+	return AssetBalance{Asset: asset}
+}
+
+// lookupAssetLimit returns the limit configured for an asset, matched case-insensitively.
+func lookupAssetLimit(limits map[string]float64, asset string) (float64, bool) {
+	for name, limit := range limits {
+		if strings.EqualFold(name, asset) {
+			return limit, true
+		}
+	}
+	return 0, false
+}
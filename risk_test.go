@@ -0,0 +1,161 @@
+package deltadefi
+
+import (
+	"errors"
+	"testing"
+)
+
+// riskCheckOf returns the check of a *RiskError, or "" for nil and other errors.
+func riskCheckOf(t *testing.T, err error) RiskCheck {
+	t.Helper()
+	if err == nil {
+		return ""
+	}
+	var riskErr *RiskError
+	if !errors.As(err, &riskErr) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return riskErr.Check
+}
+
+// newRiskTestClient returns a client with one open buy order of 10 ADA at 0.5 and the given limits.
+func newRiskTestClient(t *testing.T, limits *RiskLimits) (*DeltaDeFi, *mockExchange) {
+	t.Helper()
+	d, exchange := newMockClient(t, ApiConfig{RiskLimits: limits})
+	exchange.price = 0.5
+	exchange.balances = []AssetBalance{{Asset: "ADA", Free: 100, Locked: 10}, {Asset: "USDM", Free: 50}}
+	exchange.addOrder(OrderJSON{OrderID: "open", Status: "open", Symbol: ADAUSDM, Side: OrderSideBuy, OrigQty: "10", ExecutedQty: "0"})
+	return d, exchange
+}
+
+func TestRiskEngineCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits RiskLimits
+		side   OrderSide
+		price  float64
+		want   RiskCheck
+	}{
+		{name: "no limits", side: OrderSideBuy, price: 0.5},
+		{name: "notional", limits: RiskLimits{MaxOrderNotional: 9}, side: OrderSideBuy, price: 0.5, want: RiskCheckOrderNotional},
+		{name: "notional within limit", limits: RiskLimits{MaxOrderNotional: 10}, side: OrderSideBuy, price: 0.5},
+		{name: "open orders", limits: RiskLimits{MaxOpenOrders: 1}, side: OrderSideBuy, price: 0.5, want: RiskCheckOpenOrders},
+		// 100 free + 10 locked + 10 open + 20 ordered
+		{name: "position", limits: RiskLimits{MaxPosition: map[string]float64{"ada": 139}}, side: OrderSideBuy, price: 0.5, want: RiskCheckPosition},
+		{name: "position within limit", limits: RiskLimits{MaxPosition: map[string]float64{"ADA": 140}}, side: OrderSideBuy, price: 0.5},
+		{name: "sells do not add to the position", limits: RiskLimits{MaxPosition: map[string]float64{"ADA": 100}}, side: OrderSideSell, price: 0.5},
+		{name: "quote balance", limits: RiskLimits{CheckBalance: true}, side: OrderSideBuy, price: 3, want: RiskCheckBalance},
+		{name: "base balance", limits: RiskLimits{CheckBalance: true}, side: OrderSideSell, price: 0.5},
+		{name: "price band", limits: RiskLimits{PriceBandBasisPoints: 500}, side: OrderSideBuy, price: 0.6, want: RiskCheckPriceBand},
+		{name: "price within band", limits: RiskLimits{PriceBandBasisPoints: 500}, side: OrderSideBuy, price: 0.52},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newRiskTestClient(t, &tt.limits)
+			err := d.Risk().Check(&BuildPlaceOrderTransactionRequest{
+				Symbol: ADAUSDM, Side: tt.side, Type: OrderTypeLimit, Quantity: 20, Price: FloatPtr(tt.price),
+			})
+			if got := riskCheckOf(t, err); got != tt.want {
+				t.Errorf("Check() = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRiskEngineReservations(t *testing.T) {
+	order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 20, Price: FloatPtr(0.5)}
+	tests := []struct {
+		name   string
+		limits RiskLimits
+		want   RiskCheck
+	}{
+		{name: "open orders", limits: RiskLimits{MaxOpenOrders: 2}, want: RiskCheckOpenOrders},
+		{name: "position", limits: RiskLimits{MaxPosition: map[string]float64{"ADA": 150}}, want: RiskCheckPosition},
+		// 50 USDM free, 10 reserved, 45 checked
+		{name: "balance", limits: RiskLimits{CheckBalance: true}, want: RiskCheckBalance},
+		{name: "rate", limits: RiskLimits{MaxOrdersPerMinute: 1}, want: RiskCheckOrderRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, _ := newRiskTestClient(t, &tt.limits)
+			e := d.Risk()
+			large := *order
+			if tt.limits.CheckBalance {
+				large.Quantity = 90
+			}
+
+			// A check does not reserve anything
+			if err := e.Check(&large); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if err := e.Check(&large); err != nil {
+				t.Fatalf("repeated Check() error = %v", err)
+			}
+
+			reservation, err := e.reserve(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := riskCheckOf(t, e.Check(&large)); got != tt.want {
+				t.Errorf("Check() with an order in flight failed %q, want %q", got, tt.want)
+			}
+
+			reservation.release()
+			if err := e.Check(&large); err != nil {
+				t.Errorf("Check() after release error = %v", err)
+			}
+			reservation.commit()
+			if err := e.Check(&large); err != nil {
+				t.Errorf("commit after release counted the order: %v", err)
+			}
+		})
+	}
+}
+
+func TestRiskEngineCommit(t *testing.T) {
+	order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 20, Price: FloatPtr(0.5)}
+	d, _ := newRiskTestClient(t, &RiskLimits{MaxOpenOrders: 2, MaxOrdersPerMinute: 2})
+	e := d.Risk()
+
+	reservation, err := e.reserve(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.commit()
+	reservation.release()
+
+	// The submitted order now counts through the server state and the rate window only
+	if len(e.reservations) != 0 {
+		t.Errorf("%d reservations kept after commit", len(e.reservations))
+	}
+	if err := e.Check(order); err != nil {
+		t.Errorf("Check() error = %v", err)
+	}
+	reservation, err = e.reserve(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.commit()
+	if got := riskCheckOf(t, e.Check(order)); got != RiskCheckOrderRate {
+		t.Errorf("Check() after two orders failed %q, want the order rate", got)
+	}
+}
+
+func TestPostOrderReleasesRiskReservation(t *testing.T) {
+	d, exchange := newRiskTestClient(t, &RiskLimits{MaxOrdersPerMinute: 1})
+	order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 20, Price: FloatPtr(0.5)}
+
+	exchange.beforePlace = func(*BuildPlaceOrderTransactionRequest) error { return errors.New("rejected") }
+	if _, err := d.PostOrder(order); err == nil {
+		t.Fatal("PostOrder() succeeded despite a rejected build")
+	}
+	exchange.beforePlace = nil
+
+	if _, err := d.PostOrder(order); err != nil {
+		t.Fatalf("PostOrder() after a failed placement error = %v", err)
+	}
+	_, err := d.PostOrder(order)
+	if got := riskCheckOf(t, err); got != RiskCheckOrderRate {
+		t.Errorf("third PostOrder() error = %v, want the order rate", err)
+	}
+}
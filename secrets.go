@@ -0,0 +1,222 @@
+package deltadefi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// SecretProvider supplies a secret value such as the API key or the operation passcode.
+// Providers are resolved lazily, the first time the SDK actually needs the value.
+type SecretProvider interface {
+	// Secret returns the secret value, or an error if it cannot be obtained.
+	Secret() (string, error)
+}
+
+// StaticSecret is a SecretProvider that always returns the wrapped value.
+type StaticSecret string
+
+// Secret returns the wrapped value.
+func (s StaticSecret) Secret() (string, error) {
+	if s == "" {
+		return "", fmt.Errorf("static secret is empty")
+	}
+	return string(s), nil
+}
+
+// EnvSecretProvider reads a secret from an environment variable.
+type EnvSecretProvider struct {
+	// Name is the environment variable name, e.g. "DELTADEFI_API_KEY"
+	Name string
+}
+
+// NewEnvSecretProvider creates a provider reading the given environment variable.
+func NewEnvSecretProvider(name string) *EnvSecretProvider {
+	return &EnvSecretProvider{Name: name}
+}
+
+// Secret returns the value of the environment variable.
+func (p *EnvSecretProvider) Secret() (string, error) {
+	value, ok := os.LookupEnv(p.Name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", p.Name)
+	}
+	return value, nil
+}
+
+// FileSecretProvider reads a secret from a file, as mounted by Docker or Kubernetes secrets.
+// Leading and trailing whitespace (including the trailing newline) is stripped.
+type FileSecretProvider struct {
+	// Path is the path of the secret file, e.g. "/run/secrets/deltadefi_api_key"
+	Path string
+}
+
+// NewFileSecretProvider creates a provider reading the given file.
+func NewFileSecretProvider(path string) *FileSecretProvider {
+	return &FileSecretProvider{Path: path}
+}
+
+// Secret returns the trimmed content of the file.
+func (p *FileSecretProvider) Secret() (string, error) {
+	content, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", fmt.Errorf("reading secret file: %w", err)
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "" {
+		return "", fmt.Errorf("secret file %s is empty", p.Path)
+	}
+	return value, nil
+}
+
+// PromptSecretProvider asks for a secret interactively.
+// When Input is a terminal the typed characters are not echoed.
+type PromptSecretProvider struct {
+	// Prompt is the text written before reading the secret
+	Prompt string
+	// Input is where the secret is read from (defaults to os.Stdin)
+	Input *os.File
+	// Output is where the prompt is written (defaults to os.Stderr)
+	Output io.Writer
+}
+
+// NewPromptSecretProvider creates a provider prompting on the controlling terminal.
+func NewPromptSecretProvider(prompt string) *PromptSecretProvider {
+	return &PromptSecretProvider{Prompt: prompt}
+}
+
+// Secret writes the prompt and reads a single line.
+func (p *PromptSecretProvider) Secret() (string, error) {
+	input := p.Input
+	if input == nil {
+		input = os.Stdin
+	}
+	output := p.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	fmt.Fprint(output, p.Prompt)
+
+	var value string
+	if term.IsTerminal(int(input.Fd())) {
+		line, err := term.ReadPassword(int(input.Fd()))
+		fmt.Fprintln(output)
+		if err != nil {
+			return "", fmt.Errorf("reading secret from terminal: %w", err)
+		}
+		value = string(line)
+	} else {
+		line, err := bufio.NewReader(input).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("reading secret: %w", err)
+		}
+		value = line
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("no secret entered")
+	}
+	return value, nil
+}
+
+// VaultSecretProvider reads a secret from an HTTP secret store implementing the
+// Vault KV API (version 1 or 2). Any local stand-in serving the same JSON shape works.
+type VaultSecretProvider struct {
+	// Address is the secret store base URL, e.g. "http://127.0.0.1:8200"
+	Address string
+	// Token is sent in the X-Vault-Token header (optional)
+	Token string
+	// Path is the secret path below /v1/, e.g. "secret/data/deltadefi"
+	Path string
+	// Key is the field of the secret to return, e.g. "api_key"
+	Key string
+	// HTTPClient is the HTTP client used for the lookup (optional)
+	HTTPClient *http.Client
+}
+
+// NewVaultSecretProvider creates a provider reading key from the secret stored at path.
+func NewVaultSecretProvider(address, token, path, key string) *VaultSecretProvider {
+	return &VaultSecretProvider{
+		Address: address,
+		Token:   token,
+		Path:    path,
+		Key:     key,
+	}
+}
+
+// vaultSecretResponse covers both KV v1 ({"data": {...}}) and KV v2 ({"data": {"data": {...}}}) responses.
+type vaultSecretResponse struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+// Secret fetches the secret from the store and returns the configured field.
+func (p *VaultSecretProvider) Secret() (string, error) {
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	url := strings.TrimRight(p.Address, "/") + "/v1/" + strings.TrimLeft(p.Path, "/")
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Token != "" {
+		req.Header.Set("X-Vault-Token", p.Token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("secret store error: status code: %d", resp.StatusCode)
+	}
+
+	var secretResponse vaultSecretResponse
+	err = json.Unmarshal(bodyBytes, &secretResponse)
+	if err != nil {
+		return "", err
+	}
+
+	data := secretResponse.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	value, ok := data[p.Key].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("secret %s has no field %s", p.Path, p.Key)
+	}
+	return value, nil
+}
+
+// resolveSecret returns value when set, otherwise resolves it from provider.
+func resolveSecret(value string, provider SecretProvider, name string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	if provider == nil {
+		return "", fmt.Errorf("%s is not configured", name)
+	}
+	secret, err := provider.Secret()
+	if err != nil {
+		return "", fmt.Errorf("resolving %s: %w", name, err)
+	}
+	return secret, nil
+}
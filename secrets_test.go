@@ -0,0 +1,270 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// secretFunc adapts a function to the SecretProvider interface.
+type secretFunc func() (string, error)
+
+func (f secretFunc) Secret() (string, error) { return f() }
+
+func TestStaticSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  StaticSecret
+		want    string
+		wantErr bool
+	}{
+		{name: "value", secret: "key", want: "key"},
+		{name: "empty", secret: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.secret.Secret()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   *string
+		want    string
+		wantErr bool
+	}{
+		{name: "set", value: strPtr("env-key"), want: "env-key"},
+		{name: "empty", value: strPtr(""), wantErr: true},
+		{name: "unset", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const variable = "DELTADEFI_TEST_SECRET"
+			if tt.value != nil {
+				t.Setenv(variable, *tt.value)
+			} else {
+				t.Setenv(variable, "")
+				os.Unsetenv(variable)
+			}
+
+			got, err := NewEnvSecretProvider(variable).Secret()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    string
+		wantErr bool
+	}{
+		{name: "trims newline", content: strPtr("file-key\n"), want: "file-key"},
+		{name: "trims whitespace", content: strPtr("  file-key \r\n"), want: "file-key"},
+		{name: "blank", content: strPtr(" \n"), wantErr: true},
+		{name: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secret")
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := NewFileSecretProvider(path).Secret()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPromptSecretProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "line", input: "typed-key\nignored\n", want: "typed-key"},
+		{name: "no trailing newline", input: "typed-key", want: "typed-key"},
+		{name: "empty", input: "\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, []byte(tt.input), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			input, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer input.Close()
+
+			var output strings.Builder
+			provider := &PromptSecretProvider{Prompt: "Passcode: ", Input: input, Output: &output}
+			got, err := provider.Secret()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+			if output.String() != "Passcode: " {
+				t.Errorf("prompt = %q", output.String())
+			}
+		})
+	}
+}
+
+func TestVaultSecretProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "kv v1", status: 200, body: `{"data":{"api_key":"v1-key"}}`, key: "api_key", want: "v1-key"},
+		{name: "kv v2", status: 200, body: `{"data":{"data":{"api_key":"v2-key"},"metadata":{}}}`, key: "api_key", want: "v2-key"},
+		{name: "missing field", status: 200, body: `{"data":{"other":"x"}}`, key: "api_key", wantErr: true},
+		{name: "non-string field", status: 200, body: `{"data":{"api_key":42}}`, key: "api_key", wantErr: true},
+		{name: "forbidden", status: 403, body: `{"errors":["permission denied"]}`, key: "api_key", wantErr: true},
+		{name: "invalid json", status: 200, body: `not json`, key: "api_key", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/secret/data/deltadefi" {
+					t.Errorf("path = %s", r.URL.Path)
+				}
+				if r.Header.Get("X-Vault-Token") != "token" {
+					t.Errorf("token header = %q", r.Header.Get("X-Vault-Token"))
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			provider := NewVaultSecretProvider(server.URL+"/", "token", "/secret/data/deltadefi", tt.key)
+			got, err := provider.Secret()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Secret() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	providerErr := errors.New("unavailable")
+	tests := []struct {
+		name     string
+		value    string
+		provider SecretProvider
+		want     string
+		wantErr  error
+	}{
+		{name: "explicit value wins", value: "explicit", provider: StaticSecret("provided"), want: "explicit"},
+		{name: "provider used when value empty", provider: StaticSecret("provided"), want: "provided"},
+		{name: "provider error wrapped", provider: secretFunc(func() (string, error) { return "", providerErr }), wantErr: providerErr},
+		{name: "nothing configured", wantErr: errors.New("not configured")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSecret(tt.value, tt.provider, "API key")
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("resolveSecret() succeeded, want error")
+				}
+				if errors.Is(tt.wantErr, providerErr) && !errors.Is(err, providerErr) {
+					t.Errorf("resolveSecret() error = %v, want wrapped %v", err, providerErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveSecret() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveSecret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveSecretDoesNotCallProviderWhenValueSet(t *testing.T) {
+	called := false
+	provider := secretFunc(func() (string, error) {
+		called = true
+		return "provided", nil
+	})
+	if _, err := resolveSecret("explicit", provider, "API key"); err != nil {
+		t.Fatal(err)
+	}
+	if called {
+		t.Error("provider was called although a value was set")
+	}
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestClientResolvesApiKeyProviderOnce(t *testing.T) {
+	var headers []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Get("X-API-KEY"))
+		w.Write([]byte(`{"price":1}`))
+	}))
+	defer server.Close()
+
+	calls := 0
+	d := NewDeltaDeFi(ApiConfig{
+		ProvidedBaseUrl: server.URL,
+		ApiKeyProvider: secretFunc(func() (string, error) {
+			calls++
+			return "provided-key", nil
+		}),
+	})
+	if calls != 0 {
+		t.Fatalf("provider resolved eagerly")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := d.Market.GetMarketPrice(string(ADAUSDM)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("provider called %d times, want 1", calls)
+	}
+	for _, header := range headers {
+		if header != "provided-key" {
+			t.Errorf("X-API-KEY = %q, want provided-key", header)
+		}
+	}
+}
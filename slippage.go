@@ -0,0 +1,256 @@
+package deltadefi
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultSlippageInterval is the candle interval used to measure volatility
+	DefaultSlippageInterval = Interval5m
+	// DefaultSlippageLookback is the number of candles used to measure volatility
+	DefaultSlippageLookback = 12
+	// DefaultMaxSlippageBasisPoints caps calibrated slippage bounds
+	DefaultMaxSlippageBasisPoints = 300
+)
+
+// SlippageCalibration configures how slippage bounds are derived from the market.
+// The bound is the price impact of the order on the current book, plus a volatility allowance
+// from recent candles, plus a fixed buffer, clamped to [MinBasisPoints, MaxBasisPoints].
+type SlippageCalibration struct {
+	// Interval is the candle interval used to measure volatility (defaults to DefaultSlippageInterval)
+	Interval Interval
+	// Lookback is the number of candles used to measure volatility (defaults to DefaultSlippageLookback)
+	Lookback int
+	// VolatilityMultiplier scales the standard deviation of candle returns (defaults to 1)
+	VolatilityMultiplier float64
+	// BufferBasisPoints is added to every bound
+	BufferBasisPoints int
+	// MinBasisPoints is the smallest bound
+	MinBasisPoints int
+	// MaxBasisPoints caps the bound (defaults to DefaultMaxSlippageBasisPoints)
+	MaxBasisPoints int
+}
+
+// SlippageEstimate explains a calibrated slippage bound.
+type SlippageEstimate struct {
+	// ReferencePrice is the mid price (or best price when one side of the book is empty)
+	ReferencePrice float64
+	// ImpactBasisPoints is the distance from the reference price to the worst level the order reaches
+	ImpactBasisPoints float64
+	// VolatilityBasisPoints is the volatility allowance
+	VolatilityBasisPoints float64
+	// BasisPoints is the resulting bound
+	BasisPoints int
+	// Capped reports whether the bound was limited by MaxBasisPoints
+	Capped bool
+}
+
+// withDefaults returns the calibration with unset fields defaulted.
+func (c SlippageCalibration) withDefaults() SlippageCalibration {
+	if c.Interval == "" {
+		c.Interval = DefaultSlippageInterval
+	}
+	if c.Lookback <= 0 {
+		c.Lookback = DefaultSlippageLookback
+	}
+	if c.VolatilityMultiplier <= 0 {
+		c.VolatilityMultiplier = 1
+	}
+	if c.MaxBasisPoints <= 0 {
+		c.MaxBasisPoints = DefaultMaxSlippageBasisPoints
+	}
+	return c
+}
+
+// CalibrateSlippage computes a slippage bound for an order from the current order book and
+// recent volatility, without modifying the order.
+//
+// Parameters:
+//   - data: The order to calibrate (symbol, side and quantity are used)
+//   - cal: Calibration settings (optional, nil uses the defaults)
+//
+// Returns:
+//   - *SlippageEstimate: The bound and how it was derived
+//   - error: ErrNotFillable if the book cannot absorb the quantity, other error on failure
+func (d *DeltaDeFi) CalibrateSlippage(data *BuildPlaceOrderTransactionRequest, cal *SlippageCalibration) (*SlippageEstimate, error) {
+	var settings SlippageCalibration
+	if cal != nil {
+		settings = *cal
+	}
+	settings = settings.withDefaults()
+
+	depth, err := d.Market.GetMarketDepth(string(data.Symbol))
+	if err != nil {
+		return nil, fmt.Errorf("fetching market depth: %w", err)
+	}
+	estimate, err := depth.priceImpact(data.Side, data.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	volatility, err := d.candleVolatility(data.Symbol, settings.Interval, settings.Lookback)
+	if err != nil {
+		return nil, err
+	}
+	estimate.VolatilityBasisPoints = settings.VolatilityMultiplier * volatility * 10000
+
+	bound := int(math.Ceil(estimate.ImpactBasisPoints + estimate.VolatilityBasisPoints - quantityEpsilon))
+	bound += settings.BufferBasisPoints
+	if bound < settings.MinBasisPoints {
+		bound = settings.MinBasisPoints
+	}
+	if bound < 1 {
+		bound = 1
+	}
+	if bound > settings.MaxBasisPoints {
+		bound = settings.MaxBasisPoints
+		estimate.Capped = true
+	}
+	estimate.BasisPoints = bound
+	return estimate, nil
+}
+
+// ApplyAutoSlippage calibrates a slippage bound for a market order and attaches it to the request,
+// setting MaxSlippageBasisPoint and enabling LimitSlippage unless the caller already set it.
+//
+// Parameters:
+//   - data: The market order to update
+//   - cal: Calibration settings (optional, nil uses the defaults)
+//
+// Returns:
+//   - *SlippageEstimate: The bound and how it was derived
+//   - error: nil on success, error on failure (the request is unchanged)
+func (d *DeltaDeFi) ApplyAutoSlippage(data *BuildPlaceOrderTransactionRequest, cal *SlippageCalibration) (*SlippageEstimate, error) {
+	if data.Type != OrderTypeMarket {
+		return nil, fmt.Errorf("slippage calibration applies to market orders only")
+	}
+	estimate, err := d.CalibrateSlippage(data, cal)
+	if err != nil {
+		return nil, err
+	}
+	data.MaxSlippageBasisPoint = IntPtr(estimate.BasisPoints)
+	if data.LimitSlippage == nil {
+		data.LimitSlippage = BoolPtr(true)
+	}
+	return estimate, nil
+}
+
+// prepareOrder returns the request to place. When ApiConfig.AutoSlippage is set, market orders
+// without a slippage bound are calibrated on a copy, so the caller's request is never modified
+// and can be reused or shared between goroutines.
+func (d *DeltaDeFi) prepareOrder(data *BuildPlaceOrderTransactionRequest) (*BuildPlaceOrderTransactionRequest, error) {
+	if d.autoSlippage == nil || data.Type != OrderTypeMarket || data.MaxSlippageBasisPoint != nil {
+		return data, nil
+	}
+	prepared := *data
+	_, err := d.ApplyAutoSlippage(&prepared, d.autoSlippage)
+	if err != nil {
+		return nil, fmt.Errorf("calibrating slippage: %w", err)
+	}
+	return &prepared, nil
+}
+
+// priceImpact walks the book for a base quantity and measures the distance of the worst level
+// reached from the reference price.
+func (d *GetMarketDepthResponse) priceImpact(side OrderSide, quantity float64) (*SlippageEstimate, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	levels := d.levels(side)
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("%w: the book is empty", ErrNotFillable)
+	}
+
+	reference := levels[0].Price
+	opposite := d.levels(oppositeSide(side))
+	if len(opposite) > 0 {
+		reference = (levels[0].Price + opposite[0].Price) / 2
+	}
+
+	left := quantity
+	worst := levels[0].Price
+	for _, level := range levels {
+		if left <= quantityEpsilon {
+			break
+		}
+		worst = level.Price
+		left -= level.Quantity
+	}
+	if left > quantityEpsilon {
+		return nil, fmt.Errorf("%w: %g of %g available", ErrNotFillable, quantity-left, quantity)
+	}
+
+	return &SlippageEstimate{
+		ReferencePrice:    reference,
+		ImpactBasisPoints: math.Abs(worst-reference) / reference * 10000,
+	}, nil
+}
+
+// candleVolatility returns the standard deviation of the close-to-close log returns of the
+// most recent candles, or zero when there are too few candles.
+func (d *DeltaDeFi) candleVolatility(symbol Symbol, interval Interval, lookback int) (float64, error) {
+	length, err := intervalDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	end := time.Now()
+	candles, err := d.Market.GetAggregatedPrice(&GetAggregatedPriceRequest{
+		Symbol:   symbol,
+		Interval: interval,
+		Start:    end.Add(-length * time.Duration(lookback+1)).Unix(),
+		End:      end.Unix(),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetching candles: %w", err)
+	}
+
+	var returns []float64
+	for i := 1; i < len(*candles); i++ {
+		previous, current := (*candles)[i-1].Close, (*candles)[i].Close
+		if previous > 0 && current > 0 {
+			returns = append(returns, math.Log(current/previous))
+		}
+	}
+	if len(returns) < 2 {
+		return 0, nil
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	return math.Sqrt(variance), nil
+}
+
+// intervalDuration returns the length of a candle interval.
+func intervalDuration(interval Interval) (time.Duration, error) {
+	switch interval {
+	case Interval5m:
+		return 5 * time.Minute, nil
+	case Interval15m:
+		return 15 * time.Minute, nil
+	case Interval30m:
+		return 30 * time.Minute, nil
+	case Interval1h:
+		return time.Hour, nil
+	case Interval1d:
+		return 24 * time.Hour, nil
+	}
+	return 0, fmt.Errorf("unknown interval %q", interval)
+}
+
+// oppositeSide returns the other side of the book.
+func oppositeSide(side OrderSide) OrderSide {
+	if side == OrderSideBuy {
+		return OrderSideSell
+	}
+	return OrderSideBuy
+}
@@ -0,0 +1,274 @@
+package deltadefi
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestAutoSlippageLeavesRequestUnchanged(t *testing.T) {
+	tests := []struct {
+		name string
+		// bound is the slippage bound set by the caller, nil to calibrate
+		bound     *int
+		orderType OrderType
+		wantBound *int
+	}{
+		{name: "market order is calibrated", orderType: OrderTypeMarket, wantBound: IntPtr(200)},
+		{name: "caller bound is kept", orderType: OrderTypeMarket, bound: IntPtr(50), wantBound: IntPtr(50)},
+		{name: "limit order is not calibrated", orderType: OrderTypeLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{AutoSlippage: &SlippageCalibration{MinBasisPoints: 200}})
+			exchange.depth = GetMarketDepthResponse{
+				Bids: []MarketDepth{{Price: 0.49, Quantity: 100}},
+				Asks: []MarketDepth{{Price: 0.51, Quantity: 100}},
+			}
+			var bounds []*int
+			exchange.beforePlace = func(req *BuildPlaceOrderTransactionRequest) error {
+				bounds = append(bounds, req.MaxSlippageBasisPoint)
+				return nil
+			}
+
+			// The same request is placed twice concurrently
+			order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: tt.orderType, Quantity: 10, MaxSlippageBasisPoint: tt.bound}
+			if tt.orderType == OrderTypeLimit {
+				order.Price = FloatPtr(0.5)
+			}
+			if _, err := d.PostOrders([]*BuildPlaceOrderTransactionRequest{order, order}, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if order.MaxSlippageBasisPoint != tt.bound || order.LimitSlippage != nil {
+				t.Errorf("request modified: bound %v, limit slippage %v", order.MaxSlippageBasisPoint, order.LimitSlippage)
+			}
+			if len(bounds) != 2 {
+				t.Fatalf("%d orders built, want 2", len(bounds))
+			}
+			for _, bound := range bounds {
+				if (bound == nil) != (tt.wantBound == nil) || bound != nil && *bound != *tt.wantBound {
+					t.Errorf("built with bound %v, want %v", bound, tt.wantBound)
+				}
+			}
+		})
+	}
+}
+
+// closes returns candles with the given close prices.
+func closes(prices ...float64) GetAggregatedPriceResponse {
+	candles := make(GetAggregatedPriceResponse, len(prices))
+	for i, price := range prices {
+		candles[i] = Candlestick{Close: price}
+	}
+	return candles
+}
+
+func TestPriceImpact(t *testing.T) {
+	tests := []struct {
+		name            string
+		depth           GetMarketDepthResponse
+		side            OrderSide
+		quantity        float64
+		wantReference   float64
+		wantImpact      float64
+		wantErr         bool
+		wantNotFillable bool
+	}{
+		{
+			name:          "best level from the mid price",
+			depth:         GetMarketDepthResponse{Bids: []MarketDepth{{Price: 0.49, Quantity: 10}}, Asks: []MarketDepth{{Price: 0.51, Quantity: 10}}},
+			side:          OrderSideBuy,
+			quantity:      10,
+			wantReference: 0.5,
+			wantImpact:    200,
+		},
+		{
+			name:          "buy walks the asks",
+			depth:         GetMarketDepthResponse{Bids: []MarketDepth{{Price: 0.49, Quantity: 10}}, Asks: []MarketDepth{{Price: 0.52, Quantity: 10}, {Price: 0.51, Quantity: 10}}},
+			side:          OrderSideBuy,
+			quantity:      15,
+			wantReference: 0.5,
+			wantImpact:    400,
+		},
+		{
+			name:          "sell walks the bids",
+			depth:         GetMarketDepthResponse{Bids: []MarketDepth{{Price: 0.48, Quantity: 10}, {Price: 0.49, Quantity: 10}}, Asks: []MarketDepth{{Price: 0.51, Quantity: 10}}},
+			side:          OrderSideSell,
+			quantity:      15,
+			wantReference: 0.5,
+			wantImpact:    400,
+		},
+		{
+			name:          "one-sided book uses the best price",
+			depth:         GetMarketDepthResponse{Asks: []MarketDepth{{Price: 0.5, Quantity: 10}, {Price: 0.51, Quantity: 10}}},
+			side:          OrderSideBuy,
+			quantity:      20,
+			wantReference: 0.5,
+			wantImpact:    200,
+		},
+		{
+			name:            "insufficient depth",
+			depth:           GetMarketDepthResponse{Asks: []MarketDepth{{Price: 0.5, Quantity: 10}}},
+			side:            OrderSideBuy,
+			quantity:        11,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:            "empty book",
+			side:            OrderSideSell,
+			quantity:        1,
+			wantErr:         true,
+			wantNotFillable: true,
+		},
+		{
+			name:     "non-positive quantity",
+			depth:    GetMarketDepthResponse{Asks: []MarketDepth{{Price: 0.5, Quantity: 10}}},
+			side:     OrderSideBuy,
+			quantity: 0,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate, err := tt.depth.priceImpact(tt.side, tt.quantity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("priceImpact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if errors.Is(err, ErrNotFillable) != tt.wantNotFillable {
+				t.Errorf("priceImpact() error = %v, want ErrNotFillable %v", err, tt.wantNotFillable)
+			}
+			if err != nil {
+				return
+			}
+			if math.Abs(estimate.ReferencePrice-tt.wantReference) > 1e-9 || math.Abs(estimate.ImpactBasisPoints-tt.wantImpact) > 1e-6 {
+				t.Errorf("priceImpact() = %v bp from %v, want %v bp from %v", estimate.ImpactBasisPoints, estimate.ReferencePrice, tt.wantImpact, tt.wantReference)
+			}
+		})
+	}
+}
+
+func TestCandleVolatility(t *testing.T) {
+	step := math.Log(1.1)
+	tests := []struct {
+		name    string
+		candles GetAggregatedPriceResponse
+		want    float64
+	}{
+		// Returns of +step, -step, +step have a sample standard deviation of 2*step/sqrt(3)
+		{name: "alternating closes", candles: closes(1, 1.1, 1, 1.1), want: 2 * step / math.Sqrt(3)},
+		{name: "steady trend", candles: closes(1, 1.1, 1.21, 1.331), want: 0},
+		{name: "non-positive closes are skipped", candles: closes(1, 1.1, 0, 1, 1.1), want: 0},
+		{name: "too few candles", candles: closes(1, 1.1), want: 0},
+		{name: "no candles", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.candles = tt.candles
+
+			got, err := d.candleVolatility(ADAUSDM, Interval5m, 12)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("candleVolatility() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalibrateSlippage(t *testing.T) {
+	// The book alone gives an impact of 200 bp
+	depth := GetMarketDepthResponse{Bids: []MarketDepth{{Price: 0.49, Quantity: 100}}, Asks: []MarketDepth{{Price: 0.51, Quantity: 100}}}
+	// Returns of +1%, -1%, +1% have a standard deviation of about 115 bp
+	volatile := closes(1, 1.01, 1, 1.01)
+	volatility := 2 * math.Log(1.01) / math.Sqrt(3) * 10000
+
+	tests := []struct {
+		name           string
+		candles        GetAggregatedPriceResponse
+		cal            *SlippageCalibration
+		wantBasis      int
+		wantVolatility float64
+		wantCapped     bool
+	}{
+		{name: "impact only", wantBasis: 200},
+		{name: "buffer is added", cal: &SlippageCalibration{BufferBasisPoints: 25}, wantBasis: 225},
+		{
+			name:           "volatility is added",
+			candles:        volatile,
+			cal:            &SlippageCalibration{MaxBasisPoints: 1000},
+			wantBasis:      int(math.Ceil(200 + volatility)),
+			wantVolatility: volatility,
+		},
+		{
+			name:           "volatility multiplier",
+			candles:        volatile,
+			cal:            &SlippageCalibration{VolatilityMultiplier: 2, MaxBasisPoints: 1000},
+			wantBasis:      int(math.Ceil(200 + 2*volatility)),
+			wantVolatility: 2 * volatility,
+		},
+		{name: "raised to the minimum", cal: &SlippageCalibration{MinBasisPoints: 350, MaxBasisPoints: 1000}, wantBasis: 350},
+		{name: "capped at the maximum", cal: &SlippageCalibration{MaxBasisPoints: 150}, wantBasis: 150, wantCapped: true},
+		{name: "capped at the default maximum", cal: &SlippageCalibration{BufferBasisPoints: 500}, wantBasis: DefaultMaxSlippageBasisPoints, wantCapped: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.depth = depth
+			exchange.candles = tt.candles
+
+			estimate, err := d.CalibrateSlippage(&BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeMarket, Quantity: 10}, tt.cal)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if estimate.BasisPoints != tt.wantBasis || estimate.Capped != tt.wantCapped {
+				t.Errorf("BasisPoints = %d (capped %v), want %d (capped %v)", estimate.BasisPoints, estimate.Capped, tt.wantBasis, tt.wantCapped)
+			}
+			if math.Abs(estimate.VolatilityBasisPoints-tt.wantVolatility) > 1e-6 {
+				t.Errorf("VolatilityBasisPoints = %v, want %v", estimate.VolatilityBasisPoints, tt.wantVolatility)
+			}
+		})
+	}
+}
+
+func TestApplyAutoSlippage(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderType     OrderType
+		limitSlippage *bool
+		wantLimit     bool
+		wantErr       bool
+	}{
+		{name: "enables the limit when unset", orderType: OrderTypeMarket, wantLimit: true},
+		{name: "keeps a disabled limit", orderType: OrderTypeMarket, limitSlippage: BoolPtr(false), wantLimit: false},
+		{name: "keeps an enabled limit", orderType: OrderTypeMarket, limitSlippage: BoolPtr(true), wantLimit: true},
+		{name: "rejects limit orders", orderType: OrderTypeLimit, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.depth = GetMarketDepthResponse{Asks: []MarketDepth{{Price: 0.5, Quantity: 100}}}
+			order := &BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideBuy, Type: tt.orderType, Quantity: 10, LimitSlippage: tt.limitSlippage}
+
+			estimate, err := d.ApplyAutoSlippage(order, &SlippageCalibration{MinBasisPoints: 20})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyAutoSlippage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if order.MaxSlippageBasisPoint != nil || order.LimitSlippage != tt.limitSlippage {
+					t.Error("request modified on error")
+				}
+				return
+			}
+			if order.MaxSlippageBasisPoint == nil || *order.MaxSlippageBasisPoint != estimate.BasisPoints {
+				t.Errorf("MaxSlippageBasisPoint = %v, want %d", order.MaxSlippageBasisPoint, estimate.BasisPoints)
+			}
+			if order.LimitSlippage == nil || *order.LimitSlippage != tt.wantLimit {
+				t.Errorf("LimitSlippage = %v, want %v", order.LimitSlippage, tt.wantLimit)
+			}
+		})
+	}
+}
@@ -0,0 +1,570 @@
+package deltadefi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStrategyPriceInterval is how often market prices are polled for strategies
+	DefaultStrategyPriceInterval = 2 * time.Second
+	// DefaultStrategyOrderInterval is how often open orders and fills are polled for strategies
+	DefaultStrategyOrderInterval = 5 * time.Second
+	// DefaultStrategyQueueSize is how many events may wait for a busy strategy
+	DefaultStrategyQueueSize = 256
+)
+
+const (
+	// strategyFillsPageLimit is the number of fills fetched per page.
+	strategyFillsPageLimit = 100
+	// strategyFillsMaxPages bounds how many pages one poll fetches while looking for the last seen fill.
+	strategyFillsMaxPages = 10
+)
+
+// Strategy receives market and account events from a StrategyRuntime. The callbacks of one
+// strategy are never called concurrently. An error returned by a callback is reported through
+// StrategyRuntimeOptions.OnError and does not stop the strategy.
+// Embed BaseStrategy to implement only the callbacks a strategy needs.
+type Strategy interface {
+	// OnStart is called when the runtime starts, before any other callback; an error aborts the start
+	OnStart(sc *StrategyContext) error
+	// OnPrice is called when the market price of a symbol changes
+	OnPrice(sc *StrategyContext, event PriceEvent) error
+	// OnTrade is called for every market trade delivered by StrategyRuntimeOptions.TradeFeed
+	OnTrade(sc *StrategyContext, trade Trade) error
+	// OnDepth is called with every order book snapshot when depth polling is enabled
+	OnDepth(sc *StrategyContext, event DepthEvent) error
+	// OnOrderUpdate is called when an order of the account is first seen open, changes, or completes
+	OnOrderUpdate(sc *StrategyContext, order OrderJSON) error
+	// OnFill is called for every new fill of the account's orders
+	OnFill(sc *StrategyContext, fill OrderFillingRecordJSON) error
+	// OnTimer is called every StrategyRuntimeOptions.TimerInterval
+	OnTimer(sc *StrategyContext, now time.Time) error
+	// OnStop is called once the runtime stops, after all pending events were delivered
+	OnStop(sc *StrategyContext) error
+}
+
+// BaseStrategy implements every Strategy callback as a no-op.
+type BaseStrategy struct{}
+
+func (BaseStrategy) OnStart(*StrategyContext) error                        { return nil }
+func (BaseStrategy) OnPrice(*StrategyContext, PriceEvent) error            { return nil }
+func (BaseStrategy) OnTrade(*StrategyContext, Trade) error                 { return nil }
+func (BaseStrategy) OnDepth(*StrategyContext, DepthEvent) error            { return nil }
+func (BaseStrategy) OnOrderUpdate(*StrategyContext, OrderJSON) error       { return nil }
+func (BaseStrategy) OnFill(*StrategyContext, OrderFillingRecordJSON) error { return nil }
+func (BaseStrategy) OnTimer(*StrategyContext, time.Time) error             { return nil }
+func (BaseStrategy) OnStop(*StrategyContext) error                         { return nil }
+
+// PriceEvent is a market price change.
+type PriceEvent struct {
+	Symbol Symbol
+	Price  float64
+	Time   time.Time
+}
+
+// DepthEvent is an order book snapshot.
+type DepthEvent struct {
+	Symbol Symbol
+	Depth  *GetMarketDepthResponse
+	Time   time.Time
+}
+
+// TradeFeed returns the market trades of a symbol that occurred since its previous call.
+// The API offers no public trades endpoint, so trades come from a caller-supplied source.
+type TradeFeed func(symbol Symbol) ([]Trade, error)
+
+// StrategyContext is passed to every callback of a strategy.
+type StrategyContext struct {
+	// Name is the name the strategy was added with
+	Name string
+	// Client is the client the runtime polls; strategies use it to place and cancel orders
+	Client *DeltaDeFi
+
+	ctx context.Context
+}
+
+// Context returns a context that is cancelled as soon as the runtime starts stopping, before the
+// pending events and OnStop are delivered, so callbacks waiting on it return promptly.
+func (sc *StrategyContext) Context() context.Context {
+	return sc.ctx
+}
+
+// StrategyRuntimeOptions configures a StrategyRuntime.
+type StrategyRuntimeOptions struct {
+	// Symbols are the markets the strategies follow; order and fill events are limited to them
+	Symbols []Symbol
+	// PriceInterval is how often prices (and the trade feed) are polled (defaults to DefaultStrategyPriceInterval)
+	PriceInterval time.Duration
+	// DepthInterval is how often order books are polled (zero disables OnDepth)
+	DepthInterval time.Duration
+	// OrderInterval is how often open orders and fills are polled (defaults to DefaultStrategyOrderInterval)
+	OrderInterval time.Duration
+	// TimerInterval is how often OnTimer is called (zero disables OnTimer)
+	TimerInterval time.Duration
+	// TradeFeed supplies market trades for OnTrade (optional)
+	TradeFeed TradeFeed
+	// QueueSize is how many events may wait for a busy strategy (defaults to DefaultStrategyQueueSize);
+	// polling pauses while a strategy's queue is full
+	QueueSize int
+	// CancelOnStop cancels all open orders of the account with CancelAllOrders once the strategies stopped
+	CancelOnStop bool
+	// OnError is called when polling or a strategy callback fails (optional). The strategy name is
+	// empty for polling errors.
+	OnError func(strategy string, err error)
+}
+
+// strategyEvent delivers one event to a strategy.
+type strategyEvent func(s Strategy, sc *StrategyContext) error
+
+// strategyRunner delivers the events of one strategy in order.
+type strategyRunner struct {
+	strategy Strategy
+	sc       *StrategyContext
+	queue    chan strategyEvent
+}
+
+// StrategyRuntime polls market and account data and delivers it to strategies. Each strategy
+// receives its events in order on its own goroutine, so a slow strategy does not delay the others.
+// A StrategyRuntime is safe for concurrent use.
+type StrategyRuntime struct {
+	d    *DeltaDeFi
+	opts StrategyRuntimeOptions
+
+	runMu   sync.Mutex
+	runners []*strategyRunner
+	done    chan struct{}
+	cancel  context.CancelFunc
+	// stopping is set while Stop tears a run down; idle is signalled when it is cleared
+	stopping bool
+	idle     *sync.Cond
+	pollers  sync.WaitGroup
+	workers  sync.WaitGroup
+}
+
+// NewStrategyRuntime creates a runtime. Add strategies, then call Start or Run.
+//
+// Parameters:
+//   - d: The client used to poll data and passed to the strategies
+//   - opts: Runtime options
+//
+// Returns:
+//   - *StrategyRuntime: The runtime, not yet started
+func NewStrategyRuntime(d *DeltaDeFi, opts StrategyRuntimeOptions) *StrategyRuntime {
+	if opts.PriceInterval <= 0 {
+		opts.PriceInterval = DefaultStrategyPriceInterval
+	}
+	if opts.OrderInterval <= 0 {
+		opts.OrderInterval = DefaultStrategyOrderInterval
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultStrategyQueueSize
+	}
+	r := &StrategyRuntime{d: d, opts: opts}
+	r.idle = sync.NewCond(&r.runMu)
+	return r
+}
+
+// Add registers a strategy. Strategies cannot be added while the runtime runs.
+//
+// Parameters:
+//   - name: A unique name identifying the strategy in errors
+//   - strategy: The strategy
+//
+// Returns:
+//   - error: nil on success, error if the name is empty or taken, or the runtime is running
+func (r *StrategyRuntime) Add(name string, strategy Strategy) error {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.done != nil {
+		return errors.New("cannot add a strategy to a running runtime")
+	}
+	if name == "" {
+		return errors.New("strategy name is required")
+	}
+	for _, runner := range r.runners {
+		if runner.sc.Name == name {
+			return fmt.Errorf("strategy %q already added", name)
+		}
+	}
+	r.runners = append(r.runners, &strategyRunner{
+		strategy: strategy,
+		sc:       &StrategyContext{Name: name, Client: r.d},
+	})
+	return nil
+}
+
+// Start calls OnStart on every strategy and begins polling. If a strategy fails to start, the
+// strategies already started are stopped and the error is returned. Calling Start on a running
+// runtime has no effect; while a Stop is in progress, Start waits for it to finish.
+//
+// Returns:
+//   - error: nil on success, error if no symbol is configured or a strategy fails to start
+func (r *StrategyRuntime) Start() error {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	for r.stopping {
+		r.idle.Wait()
+	}
+	if r.done != nil {
+		return nil
+	}
+	if len(r.opts.Symbols) == 0 {
+		return errors.New("strategy runtime requires at least one symbol")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for i, runner := range r.runners {
+		runner.sc.ctx = ctx
+		if err := r.call(runner, Strategy.OnStart); err != nil {
+			for _, started := range r.runners[:i] {
+				r.report(started.sc.Name, r.call(started, Strategy.OnStop))
+			}
+			cancel()
+			return fmt.Errorf("starting strategy %s: %w", runner.sc.Name, err)
+		}
+	}
+
+	r.done = make(chan struct{})
+	r.cancel = cancel
+	for _, runner := range r.runners {
+		runner.queue = make(chan strategyEvent, r.opts.QueueSize)
+		r.workers.Add(1)
+		go r.work(runner)
+	}
+
+	runners := append([]*strategyRunner(nil), r.runners...)
+	r.startPoller(r.done, r.opts.PriceInterval, true, r.pricePoller(runners))
+	r.startPoller(r.done, r.opts.OrderInterval, true, r.orderPoller(runners))
+	if r.opts.DepthInterval > 0 {
+		r.startPoller(r.done, r.opts.DepthInterval, true, r.depthPoller(runners))
+	}
+	if r.opts.TradeFeed != nil {
+		r.startPoller(r.done, r.opts.PriceInterval, true, r.tradePoller(runners))
+	}
+	if r.opts.TimerInterval > 0 {
+		r.startPoller(r.done, r.opts.TimerInterval, false, func(done chan struct{}) {
+			now := time.Now()
+			r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+				return s.OnTimer(sc, now)
+			})
+		})
+	}
+	return nil
+}
+
+// Stop shuts the runtime down gracefully: polling stops and the strategies' context is
+// cancelled, the strategies receive their pending events and OnStop, and then, when CancelOnStop
+// is set, all open orders are cancelled. The runtime cannot be restarted until this completes.
+// Calling Stop on a stopped runtime has no effect, except waiting for a Stop in progress.
+// Stop waits for the strategies to finish, so it must not be called from a Strategy callback or
+// OnError; to stop from a strategy, cancel the context passed to Run.
+//
+// Returns:
+//   - error: nil on success, error if cancelling the open orders fails
+func (r *StrategyRuntime) Stop() error {
+	r.runMu.Lock()
+	if r.done == nil {
+		for r.stopping {
+			r.idle.Wait()
+		}
+		r.runMu.Unlock()
+		return nil
+	}
+	close(r.done)
+	r.done = nil
+	r.stopping = true
+	runners := append([]*strategyRunner(nil), r.runners...)
+	cancel := r.cancel
+	r.runMu.Unlock()
+
+	defer func() {
+		r.runMu.Lock()
+		r.stopping = false
+		r.idle.Broadcast()
+		r.runMu.Unlock()
+	}()
+
+	// Callbacks blocked on the context return, so the queues can drain
+	cancel()
+	r.pollers.Wait()
+	for _, runner := range runners {
+		close(runner.queue)
+	}
+	r.workers.Wait()
+
+	if r.opts.CancelOnStop {
+		if _, err := r.d.CancelAllOrders(); err != nil {
+			return fmt.Errorf("cancelling open orders: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run starts the runtime, blocks until ctx is done and then stops it.
+//
+// Parameters:
+//   - ctx: Context whose cancellation stops the runtime
+//
+// Returns:
+//   - error: The error of Start or Stop
+func (r *StrategyRuntime) Run(ctx context.Context) error {
+	if err := r.Start(); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	return r.Stop()
+}
+
+// work delivers the events of a strategy until its queue is closed, then calls OnStop.
+func (r *StrategyRuntime) work(runner *strategyRunner) {
+	defer r.workers.Done()
+
+	for event := range runner.queue {
+		r.report(runner.sc.Name, r.call(runner, event))
+	}
+	r.report(runner.sc.Name, r.call(runner, Strategy.OnStop))
+}
+
+// call runs one callback, converting a panic into an error.
+func (r *StrategyRuntime) call(runner *strategyRunner, event func(s Strategy, sc *StrategyContext) error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("strategy panicked: %v", recovered)
+		}
+	}()
+	return event(runner.strategy, runner.sc)
+}
+
+// dispatch queues an event for every strategy. It gives up when done is closed.
+func (r *StrategyRuntime) dispatch(done chan struct{}, runners []*strategyRunner, event strategyEvent) {
+	for _, runner := range runners {
+		select {
+		case runner.queue <- event:
+		case <-done:
+			return
+		}
+	}
+}
+
+// report passes an error to OnError.
+func (r *StrategyRuntime) report(strategy string, err error) {
+	if err != nil && r.opts.OnError != nil {
+		r.opts.OnError(strategy, err)
+	}
+}
+
+// startPoller calls poll every interval until done is closed, optionally once right away.
+func (r *StrategyRuntime) startPoller(done chan struct{}, interval time.Duration, immediate bool, poll func(done chan struct{})) {
+	r.pollers.Add(1)
+	go func() {
+		defer r.pollers.Done()
+
+		if immediate {
+			poll(done)
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				poll(done)
+			}
+		}
+	}()
+}
+
+// pricePoller emits a PriceEvent whenever the price of a symbol changes.
+func (r *StrategyRuntime) pricePoller(runners []*strategyRunner) func(done chan struct{}) {
+	last := make(map[Symbol]float64)
+	return func(done chan struct{}) {
+		for _, symbol := range r.opts.Symbols {
+			res, err := r.d.Market.GetMarketPrice(string(symbol))
+			if err != nil {
+				r.report("", fmt.Errorf("fetching %s price: %w", symbol, err))
+				continue
+			}
+			if previous, ok := last[symbol]; ok && previous == res.Price {
+				continue
+			}
+			last[symbol] = res.Price
+			event := PriceEvent{Symbol: symbol, Price: res.Price, Time: time.Now()}
+			r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+				return s.OnPrice(sc, event)
+			})
+		}
+	}
+}
+
+// depthPoller emits a DepthEvent for every symbol.
+func (r *StrategyRuntime) depthPoller(runners []*strategyRunner) func(done chan struct{}) {
+	return func(done chan struct{}) {
+		for _, symbol := range r.opts.Symbols {
+			res, err := r.d.Market.GetMarketDepth(string(symbol))
+			if err != nil {
+				r.report("", fmt.Errorf("fetching %s depth: %w", symbol, err))
+				continue
+			}
+			event := DepthEvent{Symbol: symbol, Depth: res, Time: time.Now()}
+			r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+				return s.OnDepth(sc, event)
+			})
+		}
+	}
+}
+
+// tradePoller emits the trades delivered by the trade feed.
+func (r *StrategyRuntime) tradePoller(runners []*strategyRunner) func(done chan struct{}) {
+	return func(done chan struct{}) {
+		for _, symbol := range r.opts.Symbols {
+			trades, err := r.opts.TradeFeed(symbol)
+			if err != nil {
+				r.report("", fmt.Errorf("fetching %s trades: %w", symbol, err))
+				continue
+			}
+			for _, trade := range trades {
+				r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+					return s.OnTrade(sc, trade)
+				})
+			}
+		}
+	}
+}
+
+// orderPoller emits order updates by diffing the open orders between polls, and fills by
+// comparing the most recent fills with those seen before. Fills that predate the first poll are
+// not emitted.
+func (r *StrategyRuntime) orderPoller(runners []*strategyRunner) func(done chan struct{}) {
+	symbols := make(map[Symbol]bool, len(r.opts.Symbols))
+	for _, symbol := range r.opts.Symbols {
+		symbols[symbol] = true
+	}
+	var filter Symbol
+	if len(r.opts.Symbols) == 1 {
+		filter = r.opts.Symbols[0]
+	}
+
+	known := make(map[string]OrderJSON)
+	var seenFills map[string]bool
+
+	return func(done chan struct{}) {
+		var updates []OrderJSON
+		if open, err := r.d.fetchOpenOrders(filter); err != nil {
+			r.report("", fmt.Errorf("fetching open orders: %w", err))
+		} else {
+			current := make(map[string]OrderJSON, len(open))
+			for _, order := range open {
+				if !symbols[order.Symbol] {
+					continue
+				}
+				current[order.OrderID] = order
+				previous, ok := known[order.OrderID]
+				if !ok || orderChanged(previous, order) {
+					updates = append(updates, order)
+				}
+			}
+			// Orders no longer open have completed; fetch their final state
+			for id, previous := range known {
+				if _, ok := current[id]; ok {
+					continue
+				}
+				final, err := r.d.fetchOrder(id)
+				if err != nil {
+					r.report("", fmt.Errorf("fetching order %s: %w", id, err))
+					current[id] = previous
+					continue
+				}
+				updates = append(updates, *final)
+			}
+			known = current
+		}
+
+		var fills []OrderFillingRecordJSON
+		if recent, newFills, err := r.fetchNewFills(filter, symbols, seenFills); err != nil {
+			r.report("", err)
+		} else {
+			fills, seenFills = newFills, recent
+		}
+
+		for _, order := range updates {
+			r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+				return s.OnOrderUpdate(sc, order)
+			})
+		}
+		for _, fill := range fills {
+			r.dispatch(done, runners, func(s Strategy, sc *StrategyContext) error {
+				return s.OnFill(sc, fill)
+			})
+		}
+	}
+}
+
+// fetchNewFills returns the fills not in seen, oldest first. Pages of recent fills are fetched
+// until one contains a fill already seen; when seen is nil only the first page is fetched to
+// set the baseline and no fills are returned. If no seen fill turns up within
+// strategyFillsMaxPages, the gap is reported to OnError and the fills fetched are returned.
+//
+// Parameters:
+//   - filter: The symbol to filter by, or empty for all symbols
+//   - symbols: The symbols whose fills are returned
+//   - seen: The execution IDs returned by the previous fetch, or nil on the first fetch
+//
+// Returns:
+//   - map[string]bool: The execution IDs of all fills fetched, to pass as seen next time
+//   - []OrderFillingRecordJSON: The new fills
+//   - error: An error if a page could not be fetched
+func (r *StrategyRuntime) fetchNewFills(filter Symbol, symbols map[Symbol]bool, seen map[string]bool) (map[string]bool, []OrderFillingRecordJSON, error) {
+	recent := make(map[string]bool)
+	var fills []OrderFillingRecordJSON
+	for page := 1; ; page++ {
+		res, err := r.d.Accounts.GetOrderRecords(&GetOrderRecordRequest{
+			Status: OrderRecordStatusTradingHistory,
+			Limit:  strategyFillsPageLimit,
+			Page:   page,
+			Symbol: filter,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("fetching fills: %w", err)
+		}
+		overlap := false
+		for _, data := range res.Data {
+			for _, fill := range data.OrderFillingRecords {
+				if seen[fill.ExecutionID] {
+					overlap = true
+				}
+				// Fills shift to later pages as new ones arrive, so skip repeats
+				if recent[fill.ExecutionID] {
+					continue
+				}
+				recent[fill.ExecutionID] = true
+				if seen != nil && !seen[fill.ExecutionID] && symbols[fill.Symbol] {
+					fills = append(fills, fill)
+				}
+			}
+		}
+		if seen == nil || overlap || page >= res.TotalPage {
+			break
+		}
+		if page == strategyFillsMaxPages {
+			r.report("", fmt.Errorf("more than %d fills since the last poll; older fills were not delivered", strategyFillsPageLimit*strategyFillsMaxPages))
+			break
+		}
+	}
+	sort.SliceStable(fills, func(i, j int) bool { return fills[i].CreatedTime < fills[j].CreatedTime })
+	return recent, fills, nil
+}
+
+// orderChanged reports whether an order progressed between two snapshots.
+func orderChanged(previous, current OrderJSON) bool {
+	return previous.Status != current.Status ||
+		previous.ExecutedQty != current.ExecutedQty ||
+		previous.UpdateTime != current.UpdateTime
+}
@@ -0,0 +1,244 @@
+package deltadefi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingStrategy blocks in OnPrice until its context is cancelled.
+type blockingStrategy struct {
+	BaseStrategy
+	entered    chan struct{}
+	stopCtxErr atomic.Value
+}
+
+func (s *blockingStrategy) OnPrice(sc *StrategyContext, event PriceEvent) error {
+	select {
+	case s.entered <- struct{}{}:
+	default:
+	}
+	<-sc.Context().Done()
+	return nil
+}
+
+func (s *blockingStrategy) OnStop(sc *StrategyContext) error {
+	s.stopCtxErr.Store(sc.Context().Err() != nil)
+	return nil
+}
+
+// slowStopStrategy takes a while to stop and checks that a restart waits for it.
+type slowStopStrategy struct {
+	BaseStrategy
+	stopping    chan struct{}
+	stopped     atomic.Bool
+	startedLate atomic.Bool
+}
+
+func (s *slowStopStrategy) OnStart(sc *StrategyContext) error {
+	if s.stopping != nil && !s.stopped.Load() {
+		s.startedLate.Store(true)
+	}
+	return nil
+}
+
+func (s *slowStopStrategy) OnStop(sc *StrategyContext) error {
+	if s.stopping != nil {
+		close(s.stopping)
+	}
+	time.Sleep(100 * time.Millisecond)
+	s.stopped.Store(true)
+	return nil
+}
+
+// returnsWithin fails the test when fn does not return within the timeout, and reports its error.
+func returnsWithin(t *testing.T, timeout time.Duration, fn func() error) {
+	t.Helper()
+	result := make(chan error, 1)
+	go func() { result <- fn() }()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(timeout):
+		t.Fatal("call did not return")
+	}
+}
+
+func TestStrategyRuntimeStopCancelsContextFirst(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.price = 0.5
+	r := NewStrategyRuntime(d, StrategyRuntimeOptions{Symbols: []Symbol{ADAUSDM}, PriceInterval: time.Hour, OrderInterval: time.Hour})
+	strategy := &blockingStrategy{entered: make(chan struct{}, 1)}
+	if err := r.Add("blocking", strategy); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-strategy.entered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnPrice not called")
+	}
+
+	returnsWithin(t, 2*time.Second, r.Stop)
+	if cancelled, _ := strategy.stopCtxErr.Load().(bool); !cancelled {
+		t.Error("context not cancelled before OnStop")
+	}
+}
+
+func TestStrategyRuntimeRestartWaitsForStop(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.price = 0.5
+	r := NewStrategyRuntime(d, StrategyRuntimeOptions{Symbols: []Symbol{ADAUSDM}, PriceInterval: 10 * time.Millisecond, OrderInterval: 10 * time.Millisecond})
+	strategy := &slowStopStrategy{}
+	if err := r.Add("slow", strategy); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	strategy.stopping = make(chan struct{})
+	stopped := make(chan error, 1)
+	go func() { stopped <- r.Stop() }()
+	<-strategy.stopping
+
+	// Start while the first run is tearing down, then stop the second run
+	returnsWithin(t, 2*time.Second, r.Start)
+	if strategy.startedLate.Load() {
+		t.Error("restarted before the previous run stopped")
+	}
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("first run did not stop")
+	}
+	strategy.stopping = nil
+	returnsWithin(t, 2*time.Second, r.Stop)
+}
+
+func TestStrategyRuntimeConcurrentStartStop(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.price = 0.5
+	r := NewStrategyRuntime(d, StrategyRuntimeOptions{Symbols: []Symbol{ADAUSDM}, PriceInterval: time.Millisecond, OrderInterval: time.Millisecond})
+	if err := r.Add("base", &BaseStrategy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	returnsWithin(t, 10*time.Second, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				for ctx.Err() == nil {
+					if err := r.Start(); err != nil {
+						errs <- err
+						return
+					}
+					if err := r.Stop(); err != nil {
+						errs <- err
+						return
+					}
+				}
+				errs <- nil
+			}()
+		}
+		for i := 0; i < 2; i++ {
+			if err := <-errs; err != nil {
+				return err
+			}
+		}
+		return r.Stop()
+	})
+}
+
+// testFills returns n fills, newest first, with execution IDs counting down from from.
+func testFills(from, n int, symbol Symbol) []OrderFillingRecordJSON {
+	fills := make([]OrderFillingRecordJSON, n)
+	for i := range fills {
+		id := from - i
+		fills[i] = OrderFillingRecordJSON{ExecutionID: fmt.Sprintf("exec-%d", id), Symbol: symbol, CreatedTime: uint64(id)}
+	}
+	return fills
+}
+
+func TestStrategyRuntimeFetchNewFills(t *testing.T) {
+	tests := []struct {
+		name      string
+		fresh     []OrderFillingRecordJSON // fills since the baseline, newest first
+		baseline  int
+		wantFills int
+		wantPages int
+		wantGap   bool
+	}{
+		{name: "no new fills", baseline: 150, wantPages: 1},
+		{name: "new fills on the first page", fresh: testFills(1030, 30, ADAUSDM), baseline: 150, wantFills: 30, wantPages: 1},
+		{name: "new fills across pages", fresh: testFills(1250, 250, ADAUSDM), baseline: 150, wantFills: 250, wantPages: 3},
+		{name: "other symbols are skipped", fresh: testFills(1250, 250, "OTHER"), baseline: 150, wantPages: 3},
+		{name: "empty history", fresh: testFills(1250, 250, ADAUSDM), wantFills: 250, wantPages: 3},
+		{name: "gap beyond the page bound", fresh: testFills(2100, 1100, ADAUSDM), baseline: 150,
+			wantFills: strategyFillsPageLimit * strategyFillsMaxPages, wantPages: strategyFillsMaxPages, wantGap: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			var reported []error
+			r := NewStrategyRuntime(d, StrategyRuntimeOptions{
+				Symbols: []Symbol{ADAUSDM},
+				OnError: func(strategy string, err error) { reported = append(reported, err) },
+			})
+			symbols := map[Symbol]bool{ADAUSDM: true}
+
+			exchange.fills = testFills(1000, tt.baseline, ADAUSDM)
+			seen, fills, err := r.fetchNewFills(ADAUSDM, symbols, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fills) != 0 {
+				t.Fatalf("baseline returned %d fills", len(fills))
+			}
+
+			exchange.mu.Lock()
+			exchange.fills = append(append([]OrderFillingRecordJSON{}, tt.fresh...), exchange.fills...)
+			exchange.mu.Unlock()
+			before := exchange.count("GET", "/accounts/order-records")
+			_, fills, err = r.fetchNewFills(ADAUSDM, symbols, seen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fills) != tt.wantFills {
+				t.Errorf("got %d fills, want %d", len(fills), tt.wantFills)
+			}
+			for i := 1; i < len(fills); i++ {
+				if fills[i].CreatedTime < fills[i-1].CreatedTime {
+					t.Fatal("fills not oldest first")
+				}
+			}
+			if pages := exchange.count("GET", "/accounts/order-records") - before; pages != tt.wantPages {
+				t.Errorf("fetched %d pages, want %d", pages, tt.wantPages)
+			}
+			if gap := len(reported) > 0; gap != tt.wantGap {
+				t.Errorf("gap reported = %v (%v), want %v", gap, reported, tt.wantGap)
+			}
+		})
+	}
+}
+
+func TestStrategyRuntimeFetchNewFillsError(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	exchange.authorize = func(string) int { return http.StatusServiceUnavailable }
+	r := NewStrategyRuntime(d, StrategyRuntimeOptions{Symbols: []Symbol{ADAUSDM}})
+	if _, _, err := r.fetchNewFills(ADAUSDM, map[Symbol]bool{ADAUSDM: true}, map[string]bool{}); err == nil {
+		t.Error("expected an error")
+	}
+}
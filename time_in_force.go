@@ -0,0 +1,426 @@
+package deltadefi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeInForceCheckInterval is how often expired good-till-time orders are looked for
+	DefaultTimeInForceCheckInterval = time.Second
+	// DefaultTimeInForceSettleTimeout bounds how long IOC and FOK orders may take to leave the pending states
+	DefaultTimeInForceSettleTimeout = 10 * time.Second
+)
+
+// ErrNotFillable is returned when a depth check shows the order book cannot fill an order as requested.
+var ErrNotFillable = errors.New("order cannot be filled from the current order book")
+
+// TimeInForce represents how long an order stays on the book.
+type TimeInForce string
+
+const (
+	// TimeInForceGTC keeps the order until it is filled or cancelled (the exchange default)
+	TimeInForceGTC TimeInForce = "GTC"
+	// TimeInForceGTT cancels the order at a deadline
+	TimeInForceGTT TimeInForce = "GTT"
+	// TimeInForceIOC fills what is immediately available and cancels the rest
+	TimeInForceIOC TimeInForce = "IOC"
+	// TimeInForceFOK only places the order if the book can fill it completely, and cancels any residual
+	TimeInForceFOK TimeInForce = "FOK"
+)
+
+// GTTEntry is a scheduled cancellation of a good-till-time order.
+type GTTEntry struct {
+	OrderID  string `json:"order_id"`
+	Symbol   Symbol `json:"symbol"`
+	ExpireAt int64  `json:"expire_at"`
+	// Attempts counts failed cancellations
+	Attempts int `json:"attempts,omitempty"`
+	// Error describes the last failed cancellation
+	Error string `json:"error,omitempty"`
+}
+
+// GTTStore persists scheduled cancellations across restarts.
+type GTTStore interface {
+	// Load returns all persisted entries
+	Load() ([]GTTEntry, error)
+	// Save replaces the persisted entries
+	Save(entries []GTTEntry) error
+}
+
+// FileGTTStore persists scheduled cancellations in a JSON file, rewritten atomically on every change.
+type FileGTTStore struct {
+	path string
+}
+
+// NewFileGTTStore creates a store backed by the given JSON file.
+func NewFileGTTStore(path string) *FileGTTStore {
+	return &FileGTTStore{path: path}
+}
+
+// Load reads the entries from the file. A missing file yields no entries.
+func (s *FileGTTStore) Load() ([]GTTEntry, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []GTTEntry
+	err = json.Unmarshal(content, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GTT store %s: %w", s.path, err)
+	}
+	return entries, nil
+}
+
+// Save writes the entries to the file.
+func (s *FileGTTStore) Save(entries []GTTEntry) error {
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, content, 0o600)
+}
+
+// TimeInForceOptions configures a TimeInForceScheduler.
+type TimeInForceOptions struct {
+	// CheckInterval is how often expired GTT orders are looked for (defaults to DefaultTimeInForceCheckInterval)
+	CheckInterval time.Duration
+	// SettleTimeout bounds the wait for IOC/FOK orders to be processed (defaults to DefaultTimeInForceSettleTimeout)
+	SettleTimeout time.Duration
+	// Store persists GTT cancellations across restarts (optional)
+	Store GTTStore
+	// OnExpire is called after a GTT order was cancelled or failed to cancel at its deadline (optional)
+	OnExpire func(entry GTTEntry, err error)
+}
+
+// TimeInForceResult is the outcome of an order placed with a time in force.
+type TimeInForceResult struct {
+	// Order is the submission result
+	Order *SubmitPlaceOrderTransactionResponse
+	// Final is the last observed state of an IOC/FOK order, after any residual was cancelled
+	Final *OrderJSON
+	// Filled is the executed quantity of an IOC/FOK order
+	Filled float64
+	// ResidualCancelled reports whether an unfilled IOC/FOK remainder was cancelled
+	ResidualCancelled bool
+}
+
+// TimeInForceScheduler emulates time-in-force instructions the exchange does not offer.
+// Good-till-time orders are cancelled at their deadline by a background loop whose schedule
+// is persisted, so deadlines survive restarts. Immediate-or-cancel and fill-or-kill orders are
+// checked against the order book before placing, and any remainder is cancelled right after
+// the order is processed. Because the depth check and the order are not atomic, a FOK order can
+// still fill partially if the book changes in between.
+// A TimeInForceScheduler is safe for concurrent use.
+type TimeInForceScheduler struct {
+	d    *DeltaDeFi
+	opts TimeInForceOptions
+
+	mu      sync.Mutex
+	entries map[string]*GTTEntry
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewTimeInForceScheduler creates a scheduler and loads the persisted GTT cancellations.
+//
+// Parameters:
+//   - d: The client used to place and cancel orders
+//   - opts: Scheduler options
+//
+// Returns:
+//   - *TimeInForceScheduler: The scheduler, not yet started
+//   - error: nil on success, error if the persisted entries cannot be loaded
+func NewTimeInForceScheduler(d *DeltaDeFi, opts TimeInForceOptions) (*TimeInForceScheduler, error) {
+	if opts.CheckInterval <= 0 {
+		opts.CheckInterval = DefaultTimeInForceCheckInterval
+	}
+	if opts.SettleTimeout <= 0 {
+		opts.SettleTimeout = DefaultTimeInForceSettleTimeout
+	}
+
+	s := &TimeInForceScheduler{
+		d:       d,
+		opts:    opts,
+		entries: make(map[string]*GTTEntry),
+	}
+	if opts.Store != nil {
+		entries, err := opts.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for i := range entries {
+			entry := entries[i]
+			s.entries[entry.OrderID] = &entry
+		}
+	}
+	return s, nil
+}
+
+// PostOrder places an order with the given time in force.
+// The operation wallet must be loaded before calling this method.
+//
+// Parameters:
+//   - data: Order details including symbol, side, type, quantity, and optional price
+//   - tif: The time in force
+//   - expireAt: The deadline of a GTT order (ignored otherwise)
+//
+// Returns:
+//   - *TimeInForceResult: The placed order and, for IOC/FOK, its final state
+//   - error: ErrNotFillable if the depth check rejects an IOC/FOK order, other error on failure
+func (s *TimeInForceScheduler) PostOrder(data *BuildPlaceOrderTransactionRequest, tif TimeInForce, expireAt time.Time) (*TimeInForceResult, error) {
+	switch tif {
+	case TimeInForceGTC, "":
+		res, err := s.d.PostOrder(data)
+		if err != nil {
+			return nil, err
+		}
+		return &TimeInForceResult{Order: res}, nil
+	case TimeInForceGTT:
+		return s.postGTT(data, expireAt)
+	case TimeInForceIOC, TimeInForceFOK:
+		return s.postImmediate(data, tif)
+	}
+	return nil, fmt.Errorf("unknown time in force %q", tif)
+}
+
+// Pending returns the scheduled GTT cancellations sorted by deadline.
+func (s *TimeInForceScheduler) Pending() []GTTEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]GTTEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, *entry)
+	}
+	sortGTTEntries(entries)
+	return entries
+}
+
+// Unschedule removes the scheduled cancellation of a GTT order, e.g. after cancelling it manually.
+//
+// Parameters:
+//   - orderId: The order ID
+//
+// Returns:
+//   - error: nil on success, error if the schedule cannot be persisted
+func (s *TimeInForceScheduler) Unschedule(orderId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[orderId]
+	if !ok {
+		return nil
+	}
+	delete(s.entries, orderId)
+	if err := s.persist(); err != nil {
+		s.entries[orderId] = entry
+		return err
+	}
+	return nil
+}
+
+// Start begins cancelling expired GTT orders in a background goroutine, including deadlines
+// that passed while the process was down. Calling Start on a running scheduler has no effect.
+func (s *TimeInForceScheduler) Start() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.done != nil {
+		return
+	}
+	s.done = make(chan struct{})
+
+	s.wg.Add(1)
+	go s.run(s.done)
+}
+
+// Stop stops the background loop. Scheduled cancellations stay persisted.
+func (s *TimeInForceScheduler) Stop() {
+	s.runMu.Lock()
+	if s.done == nil {
+		s.runMu.Unlock()
+		return
+	}
+	close(s.done)
+	s.done = nil
+	s.runMu.Unlock()
+
+	s.wg.Wait()
+}
+
+// postGTT places an order and schedules its cancellation.
+func (s *TimeInForceScheduler) postGTT(data *BuildPlaceOrderTransactionRequest, expireAt time.Time) (*TimeInForceResult, error) {
+	if !expireAt.After(time.Now()) {
+		return nil, fmt.Errorf("GTT deadline must be in the future")
+	}
+
+	res, err := s.d.PostOrder(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[res.Order.OrderID] = &GTTEntry{
+		OrderID:  res.Order.OrderID,
+		Symbol:   data.Symbol,
+		ExpireAt: expireAt.Unix(),
+	}
+	if err := s.persist(); err != nil {
+		return &TimeInForceResult{Order: res}, fmt.Errorf("order placed but GTT deadline not persisted: %w", err)
+	}
+	return &TimeInForceResult{Order: res}, nil
+}
+
+// postImmediate places an IOC or FOK order after a depth check and cancels any residual.
+func (s *TimeInForceScheduler) postImmediate(data *BuildPlaceOrderTransactionRequest, tif TimeInForce) (*TimeInForceResult, error) {
+	depth, err := s.d.Market.GetMarketDepth(string(data.Symbol))
+	if err != nil {
+		return nil, fmt.Errorf("fetching market depth: %w", err)
+	}
+	available := depth.AvailableQuantity(data.Side, data.Price)
+	if available <= quantityEpsilon || tif == TimeInForceFOK && available+quantityEpsilon < data.Quantity {
+		return nil, fmt.Errorf("%w: %g available, %g requested", ErrNotFillable, available, data.Quantity)
+	}
+
+	res, err := s.d.PostOrder(data)
+	if err != nil {
+		return nil, err
+	}
+	result := &TimeInForceResult{Order: res}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.SettleTimeout)
+	defer cancel()
+	settled := func(order *OrderJSON) bool { return !order.OrderStatus().IsPending() }
+	order, waitErr := s.d.WaitForOrder(ctx, res.Order.OrderID, settled)
+
+	// Without a snapshot the order may still be resting, so the cancellation is attempted anyway
+	if order == nil || !order.OrderStatus().IsTerminal() {
+		_, cancelErr := s.d.CancelOrder(res.Order.OrderID)
+		latest, fetchErr := s.d.fetchOrder(res.Order.OrderID)
+		switch {
+		case cancelErr == nil:
+			result.ResidualCancelled = true
+		case fetchErr == nil && latest.OrderStatus().IsTerminal():
+			// The order finished before the cancellation reached it
+			result.ResidualCancelled = latest.OrderStatus() == OrderStatusCancelled
+		default:
+			if waitErr != nil {
+				return result, fmt.Errorf("cancelling %s residual after failed wait (%v): %w", tif, waitErr, cancelErr)
+			}
+			return result, fmt.Errorf("cancelling %s residual: %w", tif, cancelErr)
+		}
+		if fetchErr != nil {
+			if order == nil {
+				return result, fmt.Errorf("%s residual cancelled but final state unknown: %w", tif, fetchErr)
+			}
+		} else {
+			order = latest
+		}
+	}
+
+	result.Final = order
+	filled, err := order.ExecutedQuantity()
+	result.Filled = filled
+	return result, err
+}
+
+// run cancels expired GTT orders until done is closed.
+func (s *TimeInForceScheduler) run(done chan struct{}) {
+	defer s.wg.Done()
+
+	s.cancelExpired()
+	ticker := time.NewTicker(s.opts.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.cancelExpired()
+		}
+	}
+}
+
+// cancelExpired cancels every GTT order whose deadline passed. Failed cancellations are retried
+// on the next check unless the order already reached a terminal status or the server does not know it.
+func (s *TimeInForceScheduler) cancelExpired() {
+	now := time.Now().Unix()
+
+	s.mu.Lock()
+	var expired []GTTEntry
+	for _, entry := range s.entries {
+		if entry.ExpireAt <= now {
+			expired = append(expired, *entry)
+		}
+	}
+	s.mu.Unlock()
+	sortGTTEntries(expired)
+
+	for _, entry := range expired {
+		_, err := s.d.CancelOrder(entry.OrderID)
+		if err != nil {
+			order, fetchErr := s.d.fetchOrder(entry.OrderID)
+			if errors.Is(fetchErr, ErrOrderNotFound) || fetchErr == nil && order.OrderStatus().IsTerminal() {
+				err = nil
+			}
+		}
+
+		s.mu.Lock()
+		if err != nil {
+			if current, ok := s.entries[entry.OrderID]; ok {
+				current.Attempts++
+				current.Error = err.Error()
+				entry = *current
+			}
+		} else {
+			delete(s.entries, entry.OrderID)
+		}
+		persistErr := s.persist()
+		s.mu.Unlock()
+
+		if err == nil && persistErr != nil {
+			err = fmt.Errorf("order cancelled but GTT schedule not persisted: %w", persistErr)
+		}
+		if s.opts.OnExpire != nil {
+			s.opts.OnExpire(entry, err)
+		}
+	}
+}
+
+// persist saves all entries to the store. The caller must hold s.mu.
+func (s *TimeInForceScheduler) persist() error {
+	if s.opts.Store == nil {
+		return nil
+	}
+	entries := make([]GTTEntry, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, *entry)
+	}
+	sortGTTEntries(entries)
+	return s.opts.Store.Save(entries)
+}
+
+// sortGTTEntries orders entries by deadline, then order ID.
+func sortGTTEntries(entries []GTTEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ExpireAt != entries[j].ExpireAt {
+			return entries[i].ExpireAt < entries[j].ExpireAt
+		}
+		return entries[i].OrderID < entries[j].OrderID
+	})
+}
@@ -0,0 +1,211 @@
+package deltadefi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeInForceImmediate(t *testing.T) {
+	tests := []struct {
+		name string
+		tif  TimeInForce
+		// lookup replaces the order lookup hook; it runs with the exchange locked
+		lookup       func(exchange *mockExchange, id string) int
+		beforeCancel func(order *OrderJSON) error
+		wantResidual bool
+		wantFilled   float64
+		wantCancel   int
+		wantFinal    bool
+		wantErr      bool
+	}{
+		{
+			name:         "unfilled residual is cancelled",
+			tif:          TimeInForceIOC,
+			wantResidual: true,
+			wantCancel:   1,
+			wantFinal:    true,
+		},
+		{
+			name: "filled order is kept",
+			tif:  TimeInForceFOK,
+			lookup: func(exchange *mockExchange, id string) int {
+				exchange.orders[id].Status, exchange.orders[id].ExecutedQty = "closed", "10"
+				return http.StatusOK
+			},
+			wantFilled: 10,
+			wantFinal:  true,
+		},
+		{
+			name:         "cancel attempted without a snapshot",
+			tif:          TimeInForceIOC,
+			lookup:       func(*mockExchange, string) int { return http.StatusBadGateway },
+			wantResidual: true,
+			wantCancel:   1,
+			wantErr:      true,
+		},
+		{
+			name:         "failed cancel without a snapshot",
+			tif:          TimeInForceIOC,
+			lookup:       func(*mockExchange, string) int { return http.StatusBadGateway },
+			beforeCancel: func(*OrderJSON) error { return errors.New("rejected") },
+			wantErr:      true,
+		},
+		{
+			name: "filled while cancelling",
+			tif:  TimeInForceIOC,
+			beforeCancel: func(order *OrderJSON) error {
+				order.Status, order.ExecutedQty = "closed", "10"
+				return nil
+			},
+			wantFilled: 10,
+			wantFinal:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.depth = GetMarketDepthResponse{Asks: []MarketDepth{{Price: 0.5, Quantity: 20}}}
+			exchange.beforeCancel = tt.beforeCancel
+			if tt.lookup != nil {
+				exchange.lookup = func(id string) int { return tt.lookup(exchange, id) }
+			}
+			s, err := NewTimeInForceScheduler(d, TimeInForceOptions{SettleTimeout: 200 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := s.PostOrder(&BuildPlaceOrderTransactionRequest{
+				Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5),
+			}, tt.tif, time.Time{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PostOrder() error = %v, want error %v", err, tt.wantErr)
+			}
+			if result == nil || result.Order == nil {
+				t.Fatal("no placed order reported")
+			}
+			if result.ResidualCancelled != tt.wantResidual {
+				t.Errorf("ResidualCancelled = %v, want %v", result.ResidualCancelled, tt.wantResidual)
+			}
+			if result.Filled != tt.wantFilled {
+				t.Errorf("Filled = %v, want %v", result.Filled, tt.wantFilled)
+			}
+			if (result.Final != nil) != tt.wantFinal {
+				t.Errorf("Final = %+v, want final state %v", result.Final, tt.wantFinal)
+			}
+			if n := int(exchange.cancelled.Load()); n != tt.wantCancel {
+				t.Errorf("%d cancellations, want %d", n, tt.wantCancel)
+			}
+		})
+	}
+}
+
+func TestTimeInForceNotFillable(t *testing.T) {
+	tests := []struct {
+		name string
+		tif  TimeInForce
+		asks []MarketDepth
+	}{
+		{name: "IOC on an empty book", tif: TimeInForceIOC},
+		{name: "IOC beyond the limit price", tif: TimeInForceIOC, asks: []MarketDepth{{Price: 0.6, Quantity: 20}}},
+		{name: "FOK larger than the book", tif: TimeInForceFOK, asks: []MarketDepth{{Price: 0.5, Quantity: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			exchange.depth = GetMarketDepthResponse{Asks: tt.asks}
+			s, err := NewTimeInForceScheduler(d, TimeInForceOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = s.PostOrder(&BuildPlaceOrderTransactionRequest{
+				Symbol: ADAUSDM, Side: OrderSideBuy, Type: OrderTypeLimit, Quantity: 10, Price: FloatPtr(0.5),
+			}, tt.tif, time.Time{})
+			if !errors.Is(err, ErrNotFillable) {
+				t.Errorf("PostOrder() error = %v, want ErrNotFillable", err)
+			}
+			if n := exchange.placed.Load(); n != 0 {
+				t.Errorf("%d orders placed, want none", n)
+			}
+		})
+	}
+}
+
+func TestTimeInForceCancelExpired(t *testing.T) {
+	tests := []struct {
+		name string
+		// order is the exchange state of the expired order, nil if the server does not know it
+		order        *OrderJSON
+		beforeCancel func(order *OrderJSON) error
+		lookupStatus int
+		wantExpires  int
+		wantErr      bool
+		wantPending  bool
+	}{
+		{
+			name:        "open order is cancelled",
+			order:       &OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			wantExpires: 1,
+		},
+		{
+			name:        "filled order is settled",
+			order:       &OrderJSON{Status: "closed", OrigQty: "10", ExecutedQty: "10"},
+			wantExpires: 1,
+		},
+		{
+			name:        "unknown order is settled",
+			wantExpires: 1,
+		},
+		{
+			name:         "failed cancellation is retried",
+			order:        &OrderJSON{Status: "open", OrigQty: "10", ExecutedQty: "0"},
+			beforeCancel: func(*OrderJSON) error { return errors.New("rejected") },
+			lookupStatus: http.StatusBadGateway,
+			wantExpires:  2,
+			wantErr:      true,
+			wantPending:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			if tt.order != nil {
+				tt.order.OrderID = "gtt"
+				exchange.addOrder(*tt.order)
+			}
+			exchange.beforeCancel = tt.beforeCancel
+			if tt.lookupStatus != 0 {
+				exchange.lookup = func(string) int { return tt.lookupStatus }
+			}
+
+			var expires []error
+			s, err := NewTimeInForceScheduler(d, TimeInForceOptions{
+				OnExpire: func(entry GTTEntry, err error) { expires = append(expires, err) },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.entries["gtt"] = &GTTEntry{OrderID: "gtt", Symbol: ADAUSDM, ExpireAt: time.Now().Add(-time.Minute).Unix()}
+
+			// A settled entry is removed, so the second check does not report it again
+			s.cancelExpired()
+			s.cancelExpired()
+
+			if len(expires) != tt.wantExpires {
+				t.Fatalf("OnExpire called %d times, want %d", len(expires), tt.wantExpires)
+			}
+			if (expires[0] != nil) != tt.wantErr {
+				t.Errorf("OnExpire error = %v, wantErr %v", expires[0], tt.wantErr)
+			}
+			pending := s.Pending()
+			if (len(pending) > 0) != tt.wantPending {
+				t.Errorf("Pending() = %+v, want pending %v", pending, tt.wantPending)
+			}
+			if tt.wantPending && pending[0].Attempts != 2 {
+				t.Errorf("Attempts = %d, want 2", pending[0].Attempts)
+			}
+		})
+	}
+}
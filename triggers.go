@@ -0,0 +1,707 @@
+package deltadefi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTriggerPollInterval is how often the trigger engine polls market prices when no interval is configured.
+const DefaultTriggerPollInterval = 2 * time.Second
+
+// triggerMaxRetryInterval caps the backoff between attempts to place the order of a fired trigger
+const triggerMaxRetryInterval = time.Minute
+
+// ErrTriggerNotFound is returned when no trigger exists for an ID.
+var ErrTriggerNotFound = errors.New("trigger not found")
+
+// TriggerType represents the kind of client-side conditional order.
+type TriggerType string
+
+const (
+	// TriggerTypeStop fires when the price moves against the position: a sell fires at or below
+	// TriggerPrice, a buy at or above it
+	TriggerTypeStop TriggerType = "stop"
+	// TriggerTypeTakeProfit fires when the price moves in favour of the position: a sell fires at or
+	// above TriggerPrice, a buy at or below it
+	TriggerTypeTakeProfit TriggerType = "take_profit"
+	// TriggerTypeTrailingStop follows the best price seen and fires when the price retraces by
+	// TrailingBasisPoints from it
+	TriggerTypeTrailingStop TriggerType = "trailing_stop"
+)
+
+// TriggerState represents the lifecycle of a trigger.
+type TriggerState string
+
+const (
+	TriggerStatePending   TriggerState = "pending"
+	TriggerStateFiring    TriggerState = "firing"
+	TriggerStateFired     TriggerState = "fired"
+	TriggerStateFailed    TriggerState = "failed"
+	TriggerStateCancelled TriggerState = "cancelled"
+)
+
+// Trigger is a conditional order held by the SDK and placed with PostOrder when its condition is met.
+type Trigger struct {
+	// ID identifies the trigger; generated by TriggerEngine.Add when empty
+	ID string `json:"id"`
+	// Type is the trigger condition
+	Type TriggerType `json:"type"`
+	// TriggerPrice is the activation price of stop and take-profit triggers
+	TriggerPrice float64 `json:"trigger_price,omitempty"`
+	// TrailingBasisPoints is the retracement from the best price that fires a trailing stop
+	TrailingBasisPoints int `json:"trailing_basis_points,omitempty"`
+	// ReferencePrice is the best price seen by a trailing stop (highest for sells, lowest for buys)
+	ReferencePrice float64 `json:"reference_price,omitempty"`
+	// Order is placed when the trigger fires; its Symbol and Side define what is watched
+	Order BuildPlaceOrderTransactionRequest `json:"order"`
+	// State is the lifecycle state of the trigger
+	State TriggerState `json:"state"`
+	// OrderID is the ID of the order placed when the trigger fired
+	OrderID string `json:"order_id,omitempty"`
+	// Error describes why firing failed, or why the last attempt of a trigger still firing failed
+	Error string `json:"error,omitempty"`
+	// CreatedAt is the Unix time the trigger was added
+	CreatedAt int64 `json:"created_at"`
+	// FiredAt is the Unix time the trigger fired
+	FiredAt int64 `json:"fired_at,omitempty"`
+}
+
+// ClientOrderID returns the client order ID used when the trigger places its order.
+// Firing goes through PostOrderWithClientID, so a trigger never places more than one order.
+func (t *Trigger) ClientOrderID() string {
+	return "trigger-" + t.ID
+}
+
+// validate checks that the trigger is complete.
+func (t *Trigger) validate() error {
+	if t.Order.Symbol == "" {
+		return fmt.Errorf("trigger order symbol is required")
+	}
+	if t.Order.Side != OrderSideBuy && t.Order.Side != OrderSideSell {
+		return fmt.Errorf("trigger order side must be buy or sell")
+	}
+	if t.Order.Quantity <= 0 {
+		return fmt.Errorf("trigger order quantity must be positive")
+	}
+	switch t.Type {
+	case TriggerTypeStop, TriggerTypeTakeProfit:
+		if t.TriggerPrice <= 0 {
+			return fmt.Errorf("trigger price must be positive")
+		}
+	case TriggerTypeTrailingStop:
+		if t.TrailingBasisPoints <= 0 || t.TrailingBasisPoints >= 10000 {
+			return fmt.Errorf("trailing basis points must be between 1 and 9999")
+		}
+	default:
+		return fmt.Errorf("unknown trigger type %q", t.Type)
+	}
+	return nil
+}
+
+// observe updates the trailing reference price and reports whether the trigger should fire at price.
+func (t *Trigger) observe(price float64) bool {
+	sell := t.Order.Side == OrderSideSell
+	switch t.Type {
+	case TriggerTypeStop:
+		if sell {
+			return price <= t.TriggerPrice
+		}
+		return price >= t.TriggerPrice
+	case TriggerTypeTakeProfit:
+		if sell {
+			return price >= t.TriggerPrice
+		}
+		return price <= t.TriggerPrice
+	case TriggerTypeTrailingStop:
+		offset := float64(t.TrailingBasisPoints) / 10000
+		if sell {
+			if price > t.ReferencePrice {
+				t.ReferencePrice = price
+			}
+			return price <= t.ReferencePrice*(1-offset)
+		}
+		if t.ReferencePrice == 0 || price < t.ReferencePrice {
+			t.ReferencePrice = price
+		}
+		return price >= t.ReferencePrice*(1+offset)
+	}
+	return false
+}
+
+// TriggerStore persists triggers across restarts.
+type TriggerStore interface {
+	// Load returns all persisted triggers
+	Load() ([]Trigger, error)
+	// Save replaces the persisted triggers
+	Save(triggers []Trigger) error
+}
+
+// FileTriggerStore persists triggers in a JSON file, rewritten atomically on every change.
+type FileTriggerStore struct {
+	path string
+}
+
+// NewFileTriggerStore creates a store backed by the given JSON file.
+func NewFileTriggerStore(path string) *FileTriggerStore {
+	return &FileTriggerStore{path: path}
+}
+
+// Load reads the triggers from the file. A missing file yields no triggers.
+func (s *FileTriggerStore) Load() ([]Trigger, error) {
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var triggers []Trigger
+	err = json.Unmarshal(content, &triggers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trigger store %s: %w", s.path, err)
+	}
+	return triggers, nil
+}
+
+// Save writes the triggers to the file.
+func (s *FileTriggerStore) Save(triggers []Trigger) error {
+	content, err := json.MarshalIndent(triggers, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, content, 0o600)
+}
+
+// PriceSource provides the current price of a symbol.
+type PriceSource interface {
+	Price(symbol Symbol) (float64, error)
+}
+
+// MarketPriceSource is a PriceSource backed by MarketClient.GetMarketPrice.
+type MarketPriceSource struct {
+	Market *MarketClient
+}
+
+// Price returns the current market price of the symbol.
+func (s *MarketPriceSource) Price(symbol Symbol) (float64, error) {
+	res, err := s.Market.GetMarketPrice(string(symbol))
+	if err != nil {
+		return 0, err
+	}
+	return res.Price, nil
+}
+
+// TriggerEngineOptions configures a TriggerEngine.
+type TriggerEngineOptions struct {
+	// PollInterval is how often prices are polled (defaults to DefaultTriggerPollInterval)
+	PollInterval time.Duration
+	// PriceSource provides prices when polling (defaults to the market price endpoint)
+	PriceSource PriceSource
+	// Store persists triggers across restarts (optional)
+	Store TriggerStore
+	// OnFire is called after a trigger fired or failed to fire (optional)
+	OnFire func(trigger Trigger, res *SubmitPlaceOrderTransactionResponse, err error)
+	// OnError is called when polling a price fails or an attempt to place a trigger order will be
+	// retried (optional)
+	OnError func(err error)
+}
+
+// TriggerEngine watches market prices and places orders when stop, take-profit or trailing-stop
+// conditions are met. Prices are polled from a PriceSource while the engine runs, and can also
+// be pushed with OnPrice, e.g. from a stream.
+//
+// Triggers are persisted before every state change, and an order is placed through
+// PostOrderWithClientID with the trigger's client order ID, so a trigger fires at most once even
+// across restarts. Use a persistent ClientOrderStore on the client for that guarantee to hold
+// when the process crashes while firing.
+//
+// A trigger whose condition was met stays firing until its order is placed: failed attempts are
+// retried with backoff while the engine runs, and resumed by the next Start otherwise. A trigger
+// fails only when the order is invalid, breaches a risk limit or is blocked by the dead man's switch.
+// A TriggerEngine is safe for concurrent use.
+type TriggerEngine struct {
+	d    *DeltaDeFi
+	opts TriggerEngineOptions
+
+	mu       sync.Mutex
+	triggers map[string]*Trigger
+	// firing guards triggers whose order is being placed
+	firing map[string]bool
+	// beforeFire holds the hooks of triggers added by addWithHook
+	beforeFire map[string]func(trigger Trigger) (float64, error)
+
+	runMu sync.Mutex
+	done  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewTriggerEngine creates a trigger engine and loads the persisted triggers.
+// Triggers that were firing when the process stopped are fired again, which is safe because
+// placement is idempotent per trigger.
+//
+// Parameters:
+//   - d: The client used to place orders
+//   - opts: Engine options
+//
+// Returns:
+//   - *TriggerEngine: The engine, not yet started
+//   - error: nil on success, error if the persisted triggers cannot be loaded
+func NewTriggerEngine(d *DeltaDeFi, opts TriggerEngineOptions) (*TriggerEngine, error) {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultTriggerPollInterval
+	}
+	if opts.PriceSource == nil {
+		opts.PriceSource = &MarketPriceSource{Market: d.Market}
+	}
+
+	e := &TriggerEngine{
+		d:          d,
+		opts:       opts,
+		triggers:   make(map[string]*Trigger),
+		firing:     make(map[string]bool),
+		beforeFire: make(map[string]func(trigger Trigger) (float64, error)),
+	}
+
+	if opts.Store != nil {
+		triggers, err := opts.Store.Load()
+		if err != nil {
+			return nil, err
+		}
+		for i := range triggers {
+			trigger := triggers[i]
+			e.triggers[trigger.ID] = &trigger
+		}
+	}
+	return e, nil
+}
+
+// Add registers a new pending trigger.
+//
+// Parameters:
+//   - trigger: The trigger; ID is generated when empty
+//
+// Returns:
+//   - Trigger: The registered trigger
+//   - error: nil on success, error if the trigger is invalid or cannot be persisted
+func (e *TriggerEngine) Add(trigger Trigger) (Trigger, error) {
+	return e.addWithHook(trigger, nil)
+}
+
+// addWithHook registers a new pending trigger like Add. When the trigger fires, beforeFire, if
+// non-nil, runs before the order is placed and returns the quantity to place; zero cancels the
+// trigger without placing anything. A failing beforeFire is retried like a failed placement.
+// Hooks are not persisted, so a trigger resumed by a new engine fires without its hook.
+func (e *TriggerEngine) addWithHook(trigger Trigger, beforeFire func(trigger Trigger) (float64, error)) (Trigger, error) {
+	if err := trigger.validate(); err != nil {
+		return Trigger{}, err
+	}
+	if trigger.ID == "" {
+		id, err := newRandomID()
+		if err != nil {
+			return Trigger{}, err
+		}
+		trigger.ID = id
+	}
+	trigger.State = TriggerStatePending
+	trigger.OrderID = ""
+	trigger.Error = ""
+	trigger.CreatedAt = time.Now().Unix()
+	trigger.FiredAt = 0
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.triggers[trigger.ID]; exists {
+		return Trigger{}, fmt.Errorf("trigger %s already exists", trigger.ID)
+	}
+	e.triggers[trigger.ID] = &trigger
+	if err := e.persist(); err != nil {
+		delete(e.triggers, trigger.ID)
+		return Trigger{}, err
+	}
+	if beforeFire != nil {
+		e.beforeFire[trigger.ID] = beforeFire
+	}
+	return trigger, nil
+}
+
+// Cancel cancels a pending trigger.
+//
+// Parameters:
+//   - id: The trigger ID
+//
+// Returns:
+//   - error: ErrTriggerNotFound if unknown, error if the trigger is no longer pending
+func (e *TriggerEngine) Cancel(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	trigger, ok := e.triggers[id]
+	if !ok {
+		return ErrTriggerNotFound
+	}
+	if trigger.State != TriggerStatePending || e.firing[id] {
+		return fmt.Errorf("trigger %s is %s and cannot be cancelled", id, trigger.State)
+	}
+	trigger.State = TriggerStateCancelled
+	delete(e.beforeFire, id)
+	return e.persist()
+}
+
+// Trigger returns a trigger by ID.
+//
+// Parameters:
+//   - id: The trigger ID
+//
+// Returns:
+//   - Trigger: The trigger
+//   - error: ErrTriggerNotFound if unknown
+func (e *TriggerEngine) Trigger(id string) (Trigger, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	trigger, ok := e.triggers[id]
+	if !ok {
+		return Trigger{}, ErrTriggerNotFound
+	}
+	return *trigger, nil
+}
+
+// Triggers returns all triggers sorted by creation time.
+func (e *TriggerEngine) Triggers() []Trigger {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	triggers := make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		triggers = append(triggers, *trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool {
+		if triggers[i].CreatedAt != triggers[j].CreatedAt {
+			return triggers[i].CreatedAt < triggers[j].CreatedAt
+		}
+		return triggers[i].ID < triggers[j].ID
+	})
+	return triggers
+}
+
+// Start begins polling prices in a background goroutine and resumes triggers that were firing
+// when the process stopped. Calling Start on a running engine has no effect.
+func (e *TriggerEngine) Start() {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	if e.done != nil {
+		return
+	}
+	e.done = make(chan struct{})
+
+	e.mu.Lock()
+	var resume []string
+	for id, trigger := range e.triggers {
+		if trigger.State == TriggerStateFiring && !e.firing[id] {
+			e.firing[id] = true
+			resume = append(resume, id)
+		}
+	}
+	e.mu.Unlock()
+	for _, id := range resume {
+		e.wg.Add(1)
+		go e.fire(id, e.done)
+	}
+
+	e.wg.Add(1)
+	go e.poll(e.done)
+}
+
+// Stop stops polling and waits for triggers being fired to complete.
+func (e *TriggerEngine) Stop() {
+	e.runMu.Lock()
+	if e.done == nil {
+		e.runMu.Unlock()
+		return
+	}
+	close(e.done)
+	e.done = nil
+	e.runMu.Unlock()
+
+	e.wg.Wait()
+}
+
+// OnPrice evaluates the pending triggers of a symbol against a price, firing those whose
+// condition is met. Use it to feed prices from a stream; polling calls it as well.
+//
+// Parameters:
+//   - symbol: The trading pair
+//   - price: The current price
+func (e *TriggerEngine) OnPrice(symbol Symbol, price float64) {
+	if price <= 0 {
+		return
+	}
+
+	e.mu.Lock()
+	var (
+		fire       []string
+		persistErr error
+	)
+	changed := false
+	for id, trigger := range e.triggers {
+		if trigger.State != TriggerStatePending || trigger.Order.Symbol != symbol || e.firing[id] {
+			continue
+		}
+		reference := trigger.ReferencePrice
+		if trigger.observe(price) {
+			trigger.State = TriggerStateFiring
+			e.firing[id] = true
+			fire = append(fire, id)
+			changed = true
+		} else if trigger.ReferencePrice != reference {
+			changed = true
+		}
+	}
+	if changed {
+		if persistErr = e.persist(); persistErr != nil {
+			// Without persisting the firing state a restart could fire again, so back off
+			for _, id := range fire {
+				e.triggers[id].State = TriggerStatePending
+				delete(e.firing, id)
+			}
+			fire = nil
+		}
+	}
+	e.mu.Unlock()
+
+	if persistErr != nil {
+		e.reportError(fmt.Errorf("persisting triggers: %w", persistErr))
+	}
+
+	if len(fire) == 0 {
+		return
+	}
+	done := e.running()
+	for _, id := range fire {
+		e.wg.Add(1)
+		go e.fire(id, done)
+	}
+}
+
+// running returns the done channel of the current run, nil when the engine is stopped.
+func (e *TriggerEngine) running() chan struct{} {
+	e.runMu.Lock()
+	defer e.runMu.Unlock()
+	return e.done
+}
+
+// poll fetches the price of every watched symbol until done is closed.
+func (e *TriggerEngine) poll(done chan struct{}) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			for _, symbol := range e.watchedSymbols() {
+				price, err := e.opts.PriceSource.Price(symbol)
+				if err != nil {
+					e.reportError(fmt.Errorf("fetching %s price: %w", symbol, err))
+					continue
+				}
+				e.OnPrice(symbol, price)
+			}
+		}
+	}
+}
+
+// watchedSymbols returns the symbols that have pending triggers.
+func (e *TriggerEngine) watchedSymbols() []Symbol {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	seen := make(map[Symbol]bool)
+	var symbols []Symbol
+	for _, trigger := range e.triggers {
+		if trigger.State == TriggerStatePending && !seen[trigger.Order.Symbol] {
+			seen[trigger.Order.Symbol] = true
+			symbols = append(symbols, trigger.Order.Symbol)
+		}
+	}
+	return symbols
+}
+
+// fire places the order of a trigger that is in the firing state. Attempts that may succeed later
+// are retried with backoff until done is closed; the trigger is then left firing for the next Start.
+// A nil done leaves the trigger firing after the first failed attempt.
+func (e *TriggerEngine) fire(id string, done chan struct{}) {
+	defer e.wg.Done()
+
+	e.mu.Lock()
+	trigger := *e.triggers[id]
+	beforeFire := e.beforeFire[id]
+	e.mu.Unlock()
+
+	interval := e.opts.PollInterval
+	for {
+		var err error
+		if beforeFire != nil {
+			var quantity float64
+			if quantity, err = beforeFire(trigger); err == nil {
+				beforeFire = nil
+				if quantity <= quantityEpsilon {
+					e.cancelFiring(id)
+					return
+				}
+				trigger.Order.Quantity = quantity
+				err = e.setQuantity(id, quantity)
+			}
+		}
+		if err == nil {
+			var res *SubmitPlaceOrderTransactionResponse
+			res, err = e.d.PostOrderWithClientID(trigger.ClientOrderID(), &trigger.Order)
+			if err == nil || permanentFireError(err) {
+				e.finishFire(id, res, err)
+				return
+			}
+		}
+		e.reportError(fmt.Errorf("placing order of trigger %s: %w", id, err))
+
+		e.mu.Lock()
+		e.triggers[id].Error = err.Error()
+		if done == nil {
+			delete(e.firing, id)
+		}
+		persistErr := e.persist()
+		e.mu.Unlock()
+		if persistErr != nil {
+			e.reportError(fmt.Errorf("persisting triggers: %w", persistErr))
+		}
+		if done == nil {
+			return
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-done:
+			timer.Stop()
+			e.mu.Lock()
+			delete(e.firing, id)
+			e.mu.Unlock()
+			return
+		case <-timer.C:
+		}
+		interval *= 2
+		if interval > triggerMaxRetryInterval {
+			interval = triggerMaxRetryInterval
+		}
+	}
+}
+
+// permanentFireError reports whether placing a trigger order failed for a reason a retry cannot fix:
+// the server rejected the order as invalid, it breaches a risk limit, placement is blocked by the
+// dead man's switch, or the client is in dry-run mode.
+func permanentFireError(err error) bool {
+	var (
+		riskErr   *RiskError
+		dryRunErr *DryRunError
+		apiErr    *APIError
+	)
+	switch {
+	case errors.As(err, &riskErr), errors.As(err, &dryRunErr), errors.Is(err, ErrOrdersBlocked):
+		return true
+	case errors.As(err, &apiErr):
+		return apiErr.StatusCode == http.StatusBadRequest || apiErr.StatusCode == http.StatusUnprocessableEntity
+	}
+	return false
+}
+
+// finishFire records the final outcome of firing a trigger and reports it.
+func (e *TriggerEngine) finishFire(id string, res *SubmitPlaceOrderTransactionResponse, err error) {
+	e.mu.Lock()
+	current := e.triggers[id]
+	current.FiredAt = time.Now().Unix()
+	if err != nil {
+		current.State = TriggerStateFailed
+		current.Error = err.Error()
+	} else {
+		current.State = TriggerStateFired
+		current.OrderID = res.Order.OrderID
+		current.Error = ""
+	}
+	delete(e.firing, id)
+	delete(e.beforeFire, id)
+	persistErr := e.persist()
+	result := *current
+	e.mu.Unlock()
+
+	if persistErr != nil {
+		e.reportError(fmt.Errorf("persisting triggers: %w", persistErr))
+	}
+	if e.opts.OnFire != nil {
+		e.opts.OnFire(result, res, err)
+	}
+}
+
+// setQuantity records the quantity a firing trigger places, as returned by its beforeFire hook.
+func (e *TriggerEngine) setQuantity(id string, quantity float64) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.triggers[id].Order.Quantity = quantity
+	delete(e.beforeFire, id)
+	return e.persist()
+}
+
+// cancelFiring cancels a firing trigger whose beforeFire hook left nothing to place.
+func (e *TriggerEngine) cancelFiring(id string) {
+	e.mu.Lock()
+	current := e.triggers[id]
+	current.State = TriggerStateCancelled
+	delete(e.firing, id)
+	delete(e.beforeFire, id)
+	persistErr := e.persist()
+	e.mu.Unlock()
+
+	if persistErr != nil {
+		e.reportError(fmt.Errorf("persisting triggers: %w", persistErr))
+	}
+}
+
+// persist saves all triggers to the store. The caller must hold e.mu.
+func (e *TriggerEngine) persist() error {
+	if e.opts.Store == nil {
+		return nil
+	}
+	triggers := make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		triggers = append(triggers, *trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool { return triggers[i].ID < triggers[j].ID })
+	return e.opts.Store.Save(triggers)
+}
+
+// reportError passes err to the OnError callback, if configured.
+func (e *TriggerEngine) reportError(err error) {
+	if e.opts.OnError != nil {
+		e.opts.OnError(err)
+	}
+}
+
+// newRandomID returns a random 128-bit hex identifier.
+func newRandomID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
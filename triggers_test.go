@@ -0,0 +1,260 @@
+package deltadefi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTriggerObserve(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger Trigger
+		prices  []float64
+		// wantFire is the index of the first price that fires, -1 for none
+		wantFire      int
+		wantReference float64
+	}{
+		{
+			name:     "sell stop fires at or below the trigger price",
+			trigger:  Trigger{Type: TriggerTypeStop, TriggerPrice: 0.5, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideSell}},
+			prices:   []float64{0.6, 0.51, 0.5},
+			wantFire: 2,
+		},
+		{
+			name:     "buy stop fires at or above the trigger price",
+			trigger:  Trigger{Type: TriggerTypeStop, TriggerPrice: 0.5, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideBuy}},
+			prices:   []float64{0.4, 0.49, 0.52},
+			wantFire: 2,
+		},
+		{
+			name:     "sell take-profit fires at or above the trigger price",
+			trigger:  Trigger{Type: TriggerTypeTakeProfit, TriggerPrice: 0.5, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideSell}},
+			prices:   []float64{0.4, 0.5},
+			wantFire: 1,
+		},
+		{
+			name:     "buy take-profit fires at or below the trigger price",
+			trigger:  Trigger{Type: TriggerTypeTakeProfit, TriggerPrice: 0.5, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideBuy}},
+			prices:   []float64{0.6, 0.51},
+			wantFire: -1,
+		},
+		{
+			name:          "trailing sell follows the high",
+			trigger:       Trigger{Type: TriggerTypeTrailingStop, TrailingBasisPoints: 200, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideSell}},
+			prices:        []float64{1, 1.2, 1.19, 1.177, 1.176},
+			wantFire:      4,
+			wantReference: 1.2,
+		},
+		{
+			name:          "trailing buy follows the low",
+			trigger:       Trigger{Type: TriggerTypeTrailingStop, TrailingBasisPoints: 200, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideBuy}},
+			prices:        []float64{1, 0.8, 0.81, 0.817},
+			wantFire:      3,
+			wantReference: 0.8,
+		},
+		{
+			name:          "trailing stop does not fire without a retracement",
+			trigger:       Trigger{Type: TriggerTypeTrailingStop, TrailingBasisPoints: 200, Order: BuildPlaceOrderTransactionRequest{Side: OrderSideSell}},
+			prices:        []float64{1, 1.1, 1.2},
+			wantFire:      -1,
+			wantReference: 1.2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trigger := tt.trigger
+			fired := -1
+			for i, price := range tt.prices {
+				if trigger.observe(price) {
+					fired = i
+					break
+				}
+			}
+			if fired != tt.wantFire {
+				t.Errorf("fired at price %d, want %d", fired, tt.wantFire)
+			}
+			if trigger.ReferencePrice != tt.wantReference {
+				t.Errorf("ReferencePrice = %v, want %v", trigger.ReferencePrice, tt.wantReference)
+			}
+		})
+	}
+}
+
+// testStopTrigger sells 10 ADA at market when the price drops to 0.5.
+func testStopTrigger() Trigger {
+	return Trigger{
+		Type:         TriggerTypeStop,
+		TriggerPrice: 0.5,
+		Order:        BuildPlaceOrderTransactionRequest{Symbol: ADAUSDM, Side: OrderSideSell, Type: OrderTypeMarket, Quantity: 10},
+	}
+}
+
+// failingTransport fails the first requests to a path with 503 Service Unavailable.
+type failingTransport struct {
+	next     http.RoundTripper
+	path     string
+	failures atomic.Int32
+}
+
+func (f *failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if strings.HasSuffix(req.URL.Path, f.path) && f.failures.Add(-1) >= 0 {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader("unavailable")),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil
+	}
+	return f.next.RoundTrip(req)
+}
+
+// triggerRecorder collects the callbacks of a TriggerEngine.
+type triggerRecorder struct {
+	mu    sync.Mutex
+	fired []Trigger
+	errs  []error
+}
+
+func (r *triggerRecorder) options(opts TriggerEngineOptions) TriggerEngineOptions {
+	opts.OnFire = func(trigger Trigger, res *SubmitPlaceOrderTransactionResponse, err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.fired = append(r.fired, trigger)
+	}
+	opts.OnError = func(err error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.errs = append(r.errs, err)
+	}
+	return opts
+}
+
+func (r *triggerRecorder) counts() (fired, errs int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.fired), len(r.errs)
+}
+
+func TestTriggerEngineFire(t *testing.T) {
+	tests := []struct {
+		name string
+		// buildFailures fail the first order builds with 503
+		buildFailures int32
+		rejectOrder   bool
+		wantState     TriggerState
+		wantErrs      int
+	}{
+		{name: "fired", wantState: TriggerStateFired},
+		{name: "retryable failures are retried", buildFailures: 2, wantState: TriggerStateFired, wantErrs: 2},
+		{name: "rejected order fails", rejectOrder: true, wantState: TriggerStateFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, exchange := newMockClient(t, ApiConfig{})
+			transport := &failingTransport{next: http.DefaultTransport, path: "/order/build"}
+			transport.failures.Store(tt.buildFailures)
+			d.client.HTTPClient.Transport = transport
+			if tt.rejectOrder {
+				exchange.beforePlace = func(*BuildPlaceOrderTransactionRequest) error { return errors.New("invalid quantity") }
+			}
+
+			recorder := &triggerRecorder{}
+			// The mock reports no market price, so only pushed prices fire
+			e, err := NewTriggerEngine(d, recorder.options(TriggerEngineOptions{PollInterval: 10 * time.Millisecond}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e.Start()
+			defer e.Stop()
+			trigger, err := e.Add(testStopTrigger())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			e.OnPrice(ADAUSDM, 0.6)
+			e.OnPrice(ADAUSDM, 0.5)
+			waitFor(t, func() bool { fired, _ := recorder.counts(); return fired == 1 })
+
+			got, err := e.Trigger(trigger.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.State != tt.wantState {
+				t.Errorf("State = %s, want %s (error %q)", got.State, tt.wantState, got.Error)
+			}
+			if _, errs := recorder.counts(); errs != tt.wantErrs {
+				t.Errorf("reported %d errors, want %d", errs, tt.wantErrs)
+			}
+			wantPlaced := int64(0)
+			if tt.wantState == TriggerStateFired {
+				wantPlaced = 1
+				if got.OrderID == "" || exchange.order(got.OrderID).OrderID != got.OrderID {
+					t.Errorf("fired trigger has no placed order (OrderID %q)", got.OrderID)
+				}
+			}
+			if placed := exchange.placed.Load(); placed != wantPlaced {
+				t.Errorf("placed %d orders, want %d", placed, wantPlaced)
+			}
+		})
+	}
+}
+
+func TestTriggerEngineRestart(t *testing.T) {
+	d, exchange := newMockClient(t, ApiConfig{})
+	transport := &failingTransport{next: http.DefaultTransport, path: "/order/build"}
+	transport.failures.Store(1 << 20)
+	d.client.HTTPClient.Transport = transport
+	store := NewFileTriggerStore(filepath.Join(t.TempDir(), "triggers.json"))
+
+	recorder := &triggerRecorder{}
+	e, err := NewTriggerEngine(d, recorder.options(TriggerEngineOptions{PollInterval: 10 * time.Millisecond, Store: store}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Start()
+	trailing := testStopTrigger()
+	trailing.Type, trailing.TriggerPrice, trailing.TrailingBasisPoints = TriggerTypeTrailingStop, 0, 2000
+	if trailing, err = e.Add(trailing); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := e.Add(testStopTrigger())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The stop fires but cannot be placed while the exchange is unavailable
+	e.OnPrice(ADAUSDM, 0.55)
+	e.OnPrice(ADAUSDM, 0.5)
+	waitFor(t, func() bool { _, errs := recorder.counts(); return errs > 0 })
+	e.Stop()
+
+	reloaded, err := NewTriggerEngine(d, recorder.options(TriggerEngineOptions{PollInterval: 10 * time.Millisecond, Store: store}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reloaded.Trigger(trailing.ID); got.ReferencePrice != 0.55 || got.State != TriggerStatePending {
+		t.Errorf("reloaded trailing stop = %s at %v, want pending at 0.55", got.State, got.ReferencePrice)
+	}
+	if got, _ := reloaded.Trigger(stop.ID); got.State != TriggerStateFiring || got.Error == "" {
+		t.Errorf("reloaded stop = %s (error %q), want firing with the last error", got.State, got.Error)
+	}
+
+	// Starting again resumes the firing stop once the exchange is back
+	transport.failures.Store(0)
+	reloaded.Start()
+	defer reloaded.Stop()
+	waitFor(t, func() bool {
+		got, _ := reloaded.Trigger(stop.ID)
+		return got.State == TriggerStateFired
+	})
+	if placed := exchange.placed.Load(); placed != 1 {
+		t.Errorf("placed %d orders, want 1", placed)
+	}
+}
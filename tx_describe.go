@@ -0,0 +1,447 @@
+package deltadefi
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// TransactionInput references an output spent by a transaction.
+type TransactionInput struct {
+	TxHash string `json:"tx_hash"`
+	Index  uint64 `json:"index"`
+}
+
+// TransactionAsset is a native asset amount. Unit is the policy ID followed by the hex asset name.
+// Quantity is negative for burned assets.
+type TransactionAsset struct {
+	Unit     string `json:"unit"`
+	Quantity int64  `json:"quantity"`
+}
+
+// TransactionOutput is an output created by a transaction.
+type TransactionOutput struct {
+	// Address is the raw address bytes, hex-encoded
+	Address  string             `json:"address"`
+	Lovelace uint64             `json:"lovelace"`
+	Assets   []TransactionAsset `json:"assets,omitempty"`
+	HasDatum bool               `json:"has_datum,omitempty"`
+}
+
+// TransactionSummary describes the content of a Cardano transaction.
+type TransactionSummary struct {
+	// TxID is the transaction hash (blake2b-256 of the body)
+	TxID string `json:"tx_id"`
+	// Size is the serialized size in bytes
+	Size            int                 `json:"size"`
+	Fee             uint64              `json:"fee"`
+	Inputs          []TransactionInput  `json:"inputs"`
+	Outputs         []TransactionOutput `json:"outputs"`
+	Mint            []TransactionAsset  `json:"mint,omitempty"`
+	TTL             *uint64             `json:"ttl,omitempty"`
+	ValidityStart   *uint64             `json:"validity_start,omitempty"`
+	RequiredSigners []string            `json:"required_signers,omitempty"`
+}
+
+// DescribeTransaction decodes an unsigned or signed transaction into a summary of its inputs,
+// outputs, fee and validity interval, without signing or submitting it.
+//
+// Parameters:
+//   - txHex: The transaction CBOR, hex-encoded
+//
+// Returns:
+//   - *TransactionSummary: The decoded summary
+//   - error: nil on success, error if the transaction cannot be decoded
+func DescribeTransaction(txHex string) (*TransactionSummary, error) {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction hex: %w", err)
+	}
+
+	r := &cborReader{data: raw}
+	major, _, _, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+	if major != cborArray {
+		return nil, fmt.Errorf("transaction is not a CBOR array")
+	}
+
+	start := r.pos
+	body, err := r.item()
+	if err != nil {
+		return nil, fmt.Errorf("decoding transaction body: %w", err)
+	}
+	bodyMap, ok := body.(cborMap)
+	if !ok {
+		return nil, fmt.Errorf("transaction body is not a CBOR map")
+	}
+	txID := blake2b.Sum256(raw[start:r.pos])
+
+	summary := &TransactionSummary{
+		TxID: hex.EncodeToString(txID[:]),
+		Size: len(raw),
+	}
+	for _, field := range bodyMap {
+		key, ok := field.key.(uint64)
+		if !ok {
+			continue
+		}
+		switch key {
+		case 0:
+			summary.Inputs, err = describeInputs(field.value)
+		case 1:
+			summary.Outputs, err = describeOutputs(field.value)
+		case 2:
+			summary.Fee, err = cborUint(field.value)
+		case 3:
+			var ttl uint64
+			ttl, err = cborUint(field.value)
+			summary.TTL = &ttl
+		case 8:
+			var start uint64
+			start, err = cborUint(field.value)
+			summary.ValidityStart = &start
+		case 9:
+			summary.Mint, err = describeMultiAsset(field.value)
+		case 14:
+			summary.RequiredSigners, err = describeByteStrings(field.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding transaction body field %d: %w", key, err)
+		}
+	}
+	return summary, nil
+}
+
+// describeInputs decodes a list or set of inputs.
+func describeInputs(value any) ([]TransactionInput, error) {
+	items, err := cborList(value)
+	if err != nil {
+		return nil, err
+	}
+	inputs := make([]TransactionInput, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]any)
+		if !ok || len(pair) != 2 {
+			return nil, fmt.Errorf("malformed input")
+		}
+		hash, ok := pair[0].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("malformed input hash")
+		}
+		index, err := cborUint(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, TransactionInput{TxHash: hex.EncodeToString(hash), Index: index})
+	}
+	return inputs, nil
+}
+
+// describeOutputs decodes legacy (array) and post-Alonzo (map) outputs.
+func describeOutputs(value any) ([]TransactionOutput, error) {
+	items, err := cborList(value)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]TransactionOutput, 0, len(items))
+	for _, item := range items {
+		var address, amount any
+		var output TransactionOutput
+		switch out := item.(type) {
+		case []any:
+			if len(out) < 2 {
+				return nil, fmt.Errorf("malformed output")
+			}
+			address, amount = out[0], out[1]
+			output.HasDatum = len(out) > 2
+		case cborMap:
+			for _, field := range out {
+				switch field.key {
+				case uint64(0):
+					address = field.value
+				case uint64(1):
+					amount = field.value
+				case uint64(2):
+					output.HasDatum = true
+				}
+			}
+		default:
+			return nil, fmt.Errorf("malformed output")
+		}
+
+		addressBytes, ok := address.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("malformed output address")
+		}
+		output.Address = hex.EncodeToString(addressBytes)
+
+		switch v := amount.(type) {
+		case uint64:
+			output.Lovelace = v
+		case []any:
+			if len(v) != 2 {
+				return nil, fmt.Errorf("malformed output value")
+			}
+			if output.Lovelace, err = cborUint(v[0]); err != nil {
+				return nil, err
+			}
+			if output.Assets, err = describeMultiAsset(v[1]); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("malformed output value")
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// describeMultiAsset flattens a policy ID -> asset name -> quantity map.
+func describeMultiAsset(value any) ([]TransactionAsset, error) {
+	policies, ok := value.(cborMap)
+	if !ok {
+		return nil, fmt.Errorf("malformed multi-asset")
+	}
+	var assets []TransactionAsset
+	for _, policy := range policies {
+		policyID, ok := policy.key.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("malformed policy ID")
+		}
+		names, ok := policy.value.(cborMap)
+		if !ok {
+			return nil, fmt.Errorf("malformed multi-asset")
+		}
+		for _, name := range names {
+			assetName, ok := name.key.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("malformed asset name")
+			}
+			var quantity int64
+			switch q := name.value.(type) {
+			case uint64:
+				if q > math.MaxInt64 {
+					return nil, fmt.Errorf("asset quantity out of range")
+				}
+				quantity = int64(q)
+			case int64:
+				quantity = q
+			default:
+				return nil, fmt.Errorf("malformed asset quantity")
+			}
+			assets = append(assets, TransactionAsset{
+				Unit:     hex.EncodeToString(policyID) + hex.EncodeToString(assetName),
+				Quantity: quantity,
+			})
+		}
+	}
+	return assets, nil
+}
+
+// describeByteStrings decodes a list or set of byte strings into hex.
+func describeByteStrings(value any) ([]string, error) {
+	items, err := cborList(value)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		b, ok := item.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("malformed byte string")
+		}
+		values = append(values, hex.EncodeToString(b))
+	}
+	return values, nil
+}
+
+// cborList unwraps an array, or a set encoded as tag 258 around an array.
+func cborList(value any) ([]any, error) {
+	if tag, ok := value.(cborTag); ok && tag.number == 258 {
+		value = tag.value
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected a CBOR array")
+	}
+	return items, nil
+}
+
+// cborUint asserts an unsigned integer.
+func cborUint(value any) (uint64, error) {
+	v, ok := value.(uint64)
+	if !ok {
+		return 0, fmt.Errorf("expected an unsigned integer")
+	}
+	return v, nil
+}
+
+// CBOR major types used by transactions.
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMapType  = 5
+	cborTagType  = 6
+	cborSimple   = 7
+)
+
+// errCBORBreak marks the end of an indefinite-length item.
+var errCBORBreak = errors.New("cbor break")
+
+// cborPair is a key/value entry of a CBOR map. Maps keep their encoding order.
+type cborPair struct {
+	key   any
+	value any
+}
+
+// cborMap is a decoded CBOR map.
+type cborMap []cborPair
+
+// cborTag is a decoded tagged item.
+type cborTag struct {
+	number uint64
+	value  any
+}
+
+// cborReader is a minimal CBOR decoder covering the items found in Cardano transactions.
+type cborReader struct {
+	data []byte
+	pos  int
+}
+
+// head reads an item header.
+func (r *cborReader) head() (major byte, arg uint64, indefinite bool, err error) {
+	if r.pos >= len(r.data) {
+		return 0, 0, false, fmt.Errorf("unexpected end of CBOR data")
+	}
+	initial := r.data[r.pos]
+	r.pos++
+	major = initial >> 5
+	info := initial & 0x1f
+
+	switch {
+	case info < 24:
+		return major, uint64(info), false, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if r.pos+size > len(r.data) {
+			return 0, 0, false, fmt.Errorf("unexpected end of CBOR data")
+		}
+		for _, b := range r.data[r.pos : r.pos+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		r.pos += size
+		return major, arg, false, nil
+	case info == 31:
+		return major, 0, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("invalid CBOR header 0x%02x", initial)
+}
+
+// item decodes the next item.
+func (r *cborReader) item() (any, error) {
+	major, arg, indefinite, err := r.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		return arg, nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("negative integer out of range")
+		}
+		return -1 - int64(arg), nil
+	case cborBytes, cborText:
+		var content []byte
+		if indefinite {
+			for {
+				chunk, err := r.item()
+				if errors.Is(err, errCBORBreak) {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				switch c := chunk.(type) {
+				case []byte:
+					content = append(content, c...)
+				case string:
+					content = append(content, c...)
+				default:
+					return nil, fmt.Errorf("malformed indefinite-length string")
+				}
+			}
+		} else {
+			if arg > uint64(len(r.data)-r.pos) {
+				return nil, fmt.Errorf("unexpected end of CBOR data")
+			}
+			content = r.data[r.pos : r.pos+int(arg)]
+			r.pos += int(arg)
+		}
+		if major == cborText {
+			return string(content), nil
+		}
+		return content, nil
+	case cborArray:
+		var items []any
+		for i := uint64(0); indefinite || i < arg; i++ {
+			item, err := r.item()
+			if indefinite && errors.Is(err, errCBORBreak) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMapType:
+		var entries cborMap
+		for i := uint64(0); indefinite || i < arg; i++ {
+			key, err := r.item()
+			if indefinite && errors.Is(err, errCBORBreak) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			value, err := r.item()
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, cborPair{key: key, value: value})
+		}
+		return entries, nil
+	case cborTagType:
+		value, err := r.item()
+		if err != nil {
+			return nil, err
+		}
+		return cborTag{number: arg, value: value}, nil
+	case cborSimple:
+		if indefinite {
+			return nil, errCBORBreak
+		}
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		// Floats and other simple values carry no information used here
+		return arg, nil
+	}
+	return nil, fmt.Errorf("unsupported CBOR major type %d", major)
+}
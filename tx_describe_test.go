@@ -0,0 +1,213 @@
+package deltadefi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// cborHead encodes an item header with a definite argument.
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		return []byte{major<<5 | 25, byte(arg >> 8), byte(arg)}
+	case arg <= 0xffffffff:
+		return []byte{major<<5 | 26, byte(arg >> 24), byte(arg >> 16), byte(arg >> 8), byte(arg)}
+	}
+	head := []byte{major<<5 | 27}
+	for shift := 56; shift >= 0; shift -= 8 {
+		head = append(head, byte(arg>>shift))
+	}
+	return head
+}
+
+// cborBytesItem encodes a byte string.
+func cborBytesItem(b []byte) []byte {
+	return append(cborHead(cborBytes, uint64(len(b))), b...)
+}
+
+// cborJoin concatenates encoded items.
+func cborJoin(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// testDescribeBody returns a Conway transaction body with set-encoded inputs and required signers,
+// a map output with a datum and multi-assets, a legacy output, mint, TTL and validity start.
+func testDescribeBody() []byte {
+	hash := bytes.Repeat([]byte{0x11}, 32)
+	policy := bytes.Repeat([]byte{0x22}, 28)
+	signer := bytes.Repeat([]byte{0x33}, 28)
+	return cborJoin(
+		cborHead(cborMapType, 7),
+		// inputs: 258([[hash, 1]])
+		cborHead(cborUnsigned, 0), cborHead(cborTagType, 258), cborHead(cborArray, 1),
+		cborHead(cborArray, 2), cborBytesItem(hash), cborHead(cborUnsigned, 1),
+		// outputs
+		cborHead(cborUnsigned, 1), cborHead(cborArray, 2),
+		// {0: address, 1: [2000000, {policy: {"tok": 5}}], 2: [0, h'00']}
+		cborHead(cborMapType, 3),
+		cborHead(cborUnsigned, 0), cborBytesItem([]byte{0x61, 0x01}),
+		cborHead(cborUnsigned, 1), cborHead(cborArray, 2), cborHead(cborUnsigned, 2000000),
+		cborHead(cborMapType, 1), cborBytesItem(policy), cborHead(cborMapType, 1), cborBytesItem([]byte("tok")), cborHead(cborUnsigned, 5),
+		cborHead(cborUnsigned, 2), cborHead(cborArray, 2), cborHead(cborUnsigned, 0), cborBytesItem([]byte{0}),
+		// [address, 1500000]
+		cborHead(cborArray, 2), cborBytesItem([]byte{0x61, 0x02}), cborHead(cborUnsigned, 1500000),
+		// fee, TTL, validity start
+		cborHead(cborUnsigned, 2), cborHead(cborUnsigned, 170000),
+		cborHead(cborUnsigned, 3), cborHead(cborUnsigned, 5000000000),
+		cborHead(cborUnsigned, 8), cborHead(cborUnsigned, 100),
+		// mint: {policy: {"tok": -5}}
+		cborHead(cborUnsigned, 9), cborHead(cborMapType, 1), cborBytesItem(policy),
+		cborHead(cborMapType, 1), cborBytesItem([]byte("tok")), cborHead(cborNegative, 4),
+		// required signers: 258([signer])
+		cborHead(cborUnsigned, 14), cborHead(cborTagType, 258), cborHead(cborArray, 1), cborBytesItem(signer),
+	)
+}
+
+// testDescribeIndefiniteBody returns a body using indefinite-length maps, arrays and byte strings.
+func testDescribeIndefiniteBody() []byte {
+	hash := bytes.Repeat([]byte{0x11}, 32)
+	return cborJoin(
+		[]byte{0xbf},
+		cborHead(cborUnsigned, 0), []byte{0x9f}, cborHead(cborArray, 2), cborBytesItem(hash), cborHead(cborUnsigned, 3), []byte{0xff},
+		cborHead(cborUnsigned, 1), []byte{0x9f},
+		// [address in two chunks, 1000000]
+		cborHead(cborArray, 2), []byte{0x5f}, cborBytesItem([]byte{0x61}), cborBytesItem([]byte{0x03}), []byte{0xff},
+		cborHead(cborUnsigned, 1000000),
+		[]byte{0xff},
+		cborHead(cborUnsigned, 2), cborHead(cborUnsigned, 42),
+		[]byte{0xff},
+	)
+}
+
+// testDescribeTx wraps a body into a transaction with an empty witness set and no metadata.
+func testDescribeTx(body []byte) []byte {
+	return cborJoin(cborHead(cborArray, 4), body, []byte{0xa0, 0xf5, 0xf6})
+}
+
+func TestDescribeTransaction(t *testing.T) {
+	policyUnit := hex.EncodeToString(bytes.Repeat([]byte{0x22}, 28)) + hex.EncodeToString([]byte("tok"))
+	tests := []struct {
+		name  string
+		body  []byte
+		check func(t *testing.T, s *TransactionSummary)
+	}{
+		{
+			name: "conway sets and map outputs",
+			body: testDescribeBody(),
+			check: func(t *testing.T, s *TransactionSummary) {
+				if len(s.Inputs) != 1 || s.Inputs[0].Index != 1 || s.Inputs[0].TxHash != hex.EncodeToString(bytes.Repeat([]byte{0x11}, 32)) {
+					t.Errorf("Inputs = %+v", s.Inputs)
+				}
+				if len(s.Outputs) != 2 {
+					t.Fatalf("Outputs = %+v", s.Outputs)
+				}
+				first, second := s.Outputs[0], s.Outputs[1]
+				if first.Address != "6101" || first.Lovelace != 2000000 || !first.HasDatum {
+					t.Errorf("map output = %+v", first)
+				}
+				if len(first.Assets) != 1 || first.Assets[0].Unit != policyUnit || first.Assets[0].Quantity != 5 {
+					t.Errorf("map output assets = %+v", first.Assets)
+				}
+				if second.Address != "6102" || second.Lovelace != 1500000 || second.HasDatum || len(second.Assets) != 0 {
+					t.Errorf("legacy output = %+v", second)
+				}
+				if s.Fee != 170000 || s.TTL == nil || *s.TTL != 5000000000 || s.ValidityStart == nil || *s.ValidityStart != 100 {
+					t.Errorf("fee %d, TTL %v, validity start %v", s.Fee, s.TTL, s.ValidityStart)
+				}
+				if len(s.Mint) != 1 || s.Mint[0].Unit != policyUnit || s.Mint[0].Quantity != -5 {
+					t.Errorf("Mint = %+v", s.Mint)
+				}
+				if len(s.RequiredSigners) != 1 || s.RequiredSigners[0] != hex.EncodeToString(bytes.Repeat([]byte{0x33}, 28)) {
+					t.Errorf("RequiredSigners = %v", s.RequiredSigners)
+				}
+			},
+		},
+		{
+			name: "indefinite lengths",
+			body: testDescribeIndefiniteBody(),
+			check: func(t *testing.T, s *TransactionSummary) {
+				if len(s.Inputs) != 1 || s.Inputs[0].Index != 3 {
+					t.Errorf("Inputs = %+v", s.Inputs)
+				}
+				if len(s.Outputs) != 1 || s.Outputs[0].Address != "6103" || s.Outputs[0].Lovelace != 1000000 {
+					t.Errorf("Outputs = %+v", s.Outputs)
+				}
+				if s.Fee != 42 || s.TTL != nil {
+					t.Errorf("fee %d, TTL %v", s.Fee, s.TTL)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := testDescribeTx(tt.body)
+			summary, err := DescribeTransaction(hex.EncodeToString(tx))
+			if err != nil {
+				t.Fatal(err)
+			}
+			txID := blake2b.Sum256(tt.body)
+			if summary.TxID != hex.EncodeToString(txID[:]) {
+				t.Errorf("TxID = %s, want the hash of the body", summary.TxID)
+			}
+			if summary.Size != len(tx) {
+				t.Errorf("Size = %d, want %d", summary.Size, len(tx))
+			}
+			tt.check(t, summary)
+		})
+	}
+}
+
+func TestDescribeTransactionRejectsMalformedInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		txHex string
+	}{
+		{name: "invalid hex", txHex: "zz"},
+		{name: "empty", txHex: ""},
+		{name: "not an array", txHex: "a0"},
+		{name: "body is not a map", txHex: "8480a0f5f6"},
+		{name: "reserved header", txHex: "84bc"},
+		{name: "length beyond the data", txHex: hex.EncodeToString(cborJoin(cborHead(cborArray, 4), cborHead(cborMapType, 1), cborHead(cborUnsigned, 0), cborHead(cborBytes, 1<<40)))},
+		{name: "malformed input", txHex: hex.EncodeToString(testDescribeTx(cborJoin(cborHead(cborMapType, 1), cborHead(cborUnsigned, 0), cborHead(cborArray, 1), cborHead(cborUnsigned, 1))))},
+		{name: "negative fee", txHex: hex.EncodeToString(testDescribeTx(cborJoin(cborHead(cborMapType, 1), cborHead(cborUnsigned, 2), cborHead(cborNegative, 0))))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DescribeTransaction(tt.txHex); err == nil {
+				t.Error("DescribeTransaction() succeeded, want an error")
+			}
+		})
+	}
+
+	// Every truncation of a valid transaction inside its body must fail cleanly
+	for _, body := range [][]byte{testDescribeBody(), testDescribeIndefiniteBody()} {
+		tx := testDescribeTx(body)
+		for n := 1; n <= len(body); n++ {
+			if _, err := DescribeTransaction(hex.EncodeToString(tx[:n])); err == nil {
+				t.Errorf("truncation to %d of %d bytes decoded without error", n, len(tx))
+			}
+		}
+	}
+}
+
+func TestDescribeBuiltTransaction(t *testing.T) {
+	txHex, bodyHex := testTransaction(7)
+	summary, err := DescribeTransaction(txHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Inputs) != 1 || summary.Inputs[0].Index != 7 || summary.Fee != 10 || len(summary.Outputs) != 0 {
+		t.Errorf("summary = %+v", summary)
+	}
+	body, _ := hex.DecodeString(bodyHex)
+	if txID := blake2b.Sum256(body); summary.TxID != hex.EncodeToString(txID[:]) {
+		t.Errorf("TxID = %s", summary.TxID)
+	}
+}